@@ -3,21 +3,28 @@ package main
 import (
 	_ "context"
 	"database/sql"
+	"errors"
+	"fmt"
 	_ "github.com/go-sql-driver/mysql"
 	_ "github.com/google/uuid"
 	_ "github.com/joho/godotenv"
 	_ "google.golang.org/api/option"
 	"log"
 	"net/http"
+	"os"
 
+	"workout/internal/config"
 	"workout/internal/handlers"
 	_ "workout/internal/handlers"
+	"workout/internal/mailer"
 	_ "workout/internal/models"
+	"workout/internal/oidc"
+	"workout/internal/printing"
 	"workout/internal/repositories"
 	_ "workout/internal/repositories"
 	"workout/internal/services"
 	_ "workout/internal/services"
-	_ "workout/utils"
+	"workout/utils"
 )
 
 type application struct {
@@ -26,7 +33,13 @@ type application struct {
 	infoLog          *log.Logger
 	userHandler      *handlers.UserHandler
 	userRepo         *repositories.UserRepository
+	sessionRepo      *repositories.SessionRepository
+	revokedRepo      *repositories.RevokedTokenRepository
+	tokenManager     *utils.Manager
 	programHandler   *handlers.ProgramHandler
+	programVersionHandler *handlers.ProgramVersionHandler
+	programDocumentHandler *handlers.ProgramDocumentHandler
+	printHandler     *handlers.PrintHandler
 	programRepo      *repositories.ProgramRepository
 	dayHandler       *handlers.DayHandler
 	dayRepo          *repositories.DayRepository
@@ -35,50 +48,117 @@ type application struct {
 	foodHandler      *handlers.FoodHandler
 	foodRepo         *repositories.FoodRepository
 	inviteHandler    *handlers.InviteHandler
+	inviteService    *services.InviteService
 	inviteRepo       *repositories.InviteRepository
 	analyticsHandler *handlers.AnalyticsHandler
+	jwksHandler      *handlers.JWKSHandler
+	twoFactorHandler *handlers.TwoFactorHandler
+	apiTokenHandler  *handlers.APITokenHandler
+	apiTokenService  *services.APITokenService
+	oidcHandler      *handlers.OIDCHandler
+	adminHandler     *handlers.AdminHandler
+	trainerApplicationHandler *handlers.TrainerApplicationHandler
+	accountHandler   *handlers.AccountHandler
+	accountService   *services.AccountService
+	auditHandler     *handlers.AuditHandler
+	auditService     *services.AuditService
+	ipResolver       *utils.IPResolver
 	analyticsRepo    *repositories.AnalyticsRepository
 
 }
 
-func initializeApp(db *sql.DB, errorLog, infoLog *log.Logger) *application {
+func initializeApp(db *sql.DB, cfg config.Config, errorLog, infoLog *log.Logger) *application {
 	// Repositories
 	userRepo := repositories.UserRepository{DB: db}
+	sessionRepo := repositories.SessionRepository{DB: db}
+	revokedRepo := repositories.RevokedTokenRepository{DB: db}
+	resetRepo := repositories.PasswordResetRepository{DB: db}
+	emailRepo := repositories.EmailChangeRepository{DB: db}
+	twoFactorRepo := repositories.TwoFactorRepository{DB: db}
+	loginAttemptRepo := repositories.LoginAttemptRepository{DB: db}
+	apiTokenRepo := repositories.APITokenRepository{DB: db}
+	identityRepo := repositories.IdentityRepository{DB: db}
 	programRepo := repositories.ProgramRepository{DB: db}
 	dayRepo := repositories.DayRepository{DB: db}
+	versionRepo := repositories.ProgramVersionRepository{DB: db}
 	exerciseRepo := repositories.ExerciseRepository{DB: db}
 	foodRepo := repositories.FoodRepository{DB: db}
 	inviteRepo := repositories.InviteRepository{DB: db}
+	applicationRepo := repositories.TrainerApplicationRepository{DB: db}
+	accountRepo := repositories.AccountRepository{DB: db}
+	auditRepo := repositories.AuditRepository{DB: db}
+	brandingRepo := repositories.BrandingRepository{DB: db}
 
 	analyticsRepo := repositories.AnalyticsRepository{DB: db}
 
 
+	mail := newMailer(cfg)
+
+	tokenManager, err := newTokenManager(cfg)
+	if err != nil {
+		errorLog.Fatalf("Failed to configure JWT signing keys: %v", err)
+	}
+	ipResolver, err := utils.NewIPResolver(cfg.Server.TrustedProxies)
+	if err != nil {
+		errorLog.Fatalf("Failed to configure trusted proxies: %v", err)
+	}
+
 	// Services
-	userService := &services.UserService{UserRepo: &userRepo}
-	programService := &services.ProgramService{Repo: &programRepo}
-	dayService := &services.DayService{Repo: &dayRepo}
-	exerciseService := &services.ExerciseService{Repo: &exerciseRepo}
-	foodService := &services.FoodService{Repo: &foodRepo}
-	inviteService := &services.InviteService{Repo: &inviteRepo, UserRepo: &userRepo}
+	auditService := &services.AuditService{Repo: &auditRepo}
+	twoFactorService := &services.TwoFactorService{Repo: &twoFactorRepo, UserRepo: &userRepo}
+	lockoutService := &services.LockoutService{Repo: &loginAttemptRepo}
+	apiTokenService := &services.APITokenService{Repo: &apiTokenRepo, UserRepo: &userRepo}
+	authorizer := &services.Authorizer{ProgramRepo: &programRepo, DayRepo: &dayRepo, ExerciseRepo: &exerciseRepo, FoodRepo: &foodRepo, UserRepo: &userRepo, VersionRepo: &versionRepo}
+	inviteService := &services.InviteService{Repo: &inviteRepo, UserRepo: &userRepo, Auth: authorizer, Mailer: mail, Audit: auditService}
+	userService := &services.UserService{UserRepo: &userRepo, SessionRepo: &sessionRepo, RevokedRepo: &revokedRepo, ResetRepo: &resetRepo, EmailRepo: &emailRepo, APITokenRepo: &apiTokenRepo, Mailer: mail, TokenManager: tokenManager, TwoFactor: twoFactorService, Lockout: lockoutService, Auth: authorizer, Audit: auditService, Invites: inviteService}
+	programService := &services.ProgramService{Repo: &programRepo, Auth: authorizer}
+	dayService := &services.DayService{Repo: &dayRepo, Auth: authorizer}
+	programVersionService := &services.ProgramVersionService{Repo: &versionRepo, DayRepo: &dayRepo, Auth: authorizer}
+	programDocumentService := &services.ProgramDocumentService{Repo: &programRepo, DayRepo: &dayRepo, Auth: authorizer}
+	printService := &services.PrintService{DayRepo: &dayRepo, UserRepo: &userRepo, BrandingRepo: &brandingRepo, Auth: authorizer, Images: printing.NewImageFetcher()}
+	exerciseService := &services.ExerciseService{Repo: &exerciseRepo, Auth: authorizer}
+	foodService := &services.FoodService{Repo: &foodRepo, Auth: authorizer}
+	oidcService := &services.OIDCService{Providers: newOIDCProviders(cfg), Repo: &identityRepo, UserRepo: &userRepo, Users: userService}
 
 	analyticsService := &services.AnalyticsService{Repo: &analyticsRepo}
+	adminService := &services.AdminService{UserRepo: &userRepo, ProgramRepo: &programRepo, Users: userService, Audit: auditService}
+	applicationService := &services.TrainerApplicationService{Repo: &applicationRepo, UserRepo: &userRepo, Mailer: mail, Audit: auditService, Dir: privateUploadsDir(cfg)}
+	accountService := &services.AccountService{Repo: &accountRepo, UserRepo: &userRepo, ProgramRepo: &programRepo, SessionRepo: &sessionRepo,
+		APITokenRepo: &apiTokenRepo, ApplicationRepo: &applicationRepo, Users: userService, Mailer: mail, UploadsDir: privateUploadsDir(cfg)}
 
 
 	// Handlers
 	userHandler := &handlers.UserHandler{Service: userService}
 	programHandler := &handlers.ProgramHandler{Service: programService}
+	programVersionHandler := &handlers.ProgramVersionHandler{Service: programVersionService}
+	programDocumentHandler := &handlers.ProgramDocumentHandler{Service: programDocumentService}
+	printHandler := &handlers.PrintHandler{Service: printService}
 	dayHandler := &handlers.DayHandler{Service: dayService}
 	exerciseHandler := &handlers.ExerciseHandler{Service: exerciseService}
 	foodHandler := &handlers.FoodHandler{Service: foodService}
 	inviteHandler := &handlers.InviteHandler{Service: inviteService}
 	analyticsHandler := &handlers.AnalyticsHandler{Service: analyticsService}
+	jwksHandler := &handlers.JWKSHandler{Manager: tokenManager}
+	twoFactorHandler := &handlers.TwoFactorHandler{Service: twoFactorService}
+	apiTokenHandler := &handlers.APITokenHandler{Service: apiTokenService}
+	oidcHandler := &handlers.OIDCHandler{Service: oidcService}
+	adminHandler := &handlers.AdminHandler{Service: adminService}
+	trainerApplicationHandler := &handlers.TrainerApplicationHandler{Service: applicationService}
+	accountHandler := &handlers.AccountHandler{Service: accountService}
+	auditHandler := &handlers.AuditHandler{Service: auditService}
 
 	return &application{
 		errorLog:         errorLog,
 		infoLog:          infoLog,
 		userHandler:      userHandler,
 		programHandler:   programHandler,
+		programVersionHandler: programVersionHandler,
+		programDocumentHandler: programDocumentHandler,
+		printHandler:     printHandler,
 		userRepo:         &userRepo,
+		sessionRepo:      &sessionRepo,
+		revokedRepo:      &revokedRepo,
+		tokenManager:     tokenManager,
 		programRepo:      &programRepo,
 		dayRepo:          &dayRepo,
 		dayHandler:       dayHandler,
@@ -88,9 +168,89 @@ func initializeApp(db *sql.DB, errorLog, infoLog *log.Logger) *application {
 		foodHandler:      foodHandler,
 		inviteRepo:       &inviteRepo,
 		inviteHandler:    inviteHandler,
+		inviteService:    inviteService,
 		analyticsRepo:    &analyticsRepo,
 		analyticsHandler: analyticsHandler,
+		jwksHandler:      jwksHandler,
+		twoFactorHandler: twoFactorHandler,
+		apiTokenHandler:  apiTokenHandler,
+		apiTokenService:  apiTokenService,
+		oidcHandler:      oidcHandler,
+		adminHandler:     adminHandler,
+		trainerApplicationHandler: trainerApplicationHandler,
+		accountHandler:   accountHandler,
+		accountService:   accountService,
+		auditHandler:     auditHandler,
+		auditService:     auditService,
+		ipResolver:       ipResolver,
+	}
+}
+
+// minHS256SecretLen is the shortest HS256 secret accepted, 256 bits.
+const minHS256SecretLen = 32
+
+// newTokenManager builds the JWT key ring. No key ships with the repository,
+// so it refuses to start unless keys come from JWT_KEYS or the deployment's
+// config file.
+func newTokenManager(cfg config.Config) (*utils.Manager, error) {
+	if len(cfg.JWT.Keys) == 0 {
+		return nil, errors.New("no signing keys configured, set JWT_KEYS or jwt.keys")
+	}
+	keys := make([]utils.KeyConfig, 0, len(cfg.JWT.Keys))
+	for _, k := range cfg.JWT.Keys {
+		if (k.Algorithm == "" || k.Algorithm == "HS256") && len(k.Secret) < minHS256SecretLen {
+			return nil, fmt.Errorf("signing key %q: HS256 secret must be at least %d bytes", k.ID, minHS256SecretLen)
+		}
+		kc := utils.KeyConfig{ID: k.ID, Algorithm: k.Algorithm, Secret: k.Secret}
+		if k.PrivateKeyFile != "" {
+			pem, err := os.ReadFile(k.PrivateKeyFile)
+			if err != nil {
+				return nil, err
+			}
+			kc.PrivateKeyPEM = pem
+		}
+		if k.PublicKeyFile != "" {
+			pem, err := os.ReadFile(k.PublicKeyFile)
+			if err != nil {
+				return nil, err
+			}
+			kc.PublicKeyPEM = pem
+		}
+		keys = append(keys, kc)
+	}
+	return utils.NewKeyRingManager(cfg.JWT.ActiveKeyID, keys)
+}
+
+func newOIDCProviders(cfg config.Config) map[string]*oidc.Provider {
+	providers := make(map[string]*oidc.Provider, len(cfg.OIDC.Providers))
+	for _, p := range cfg.OIDC.Providers {
+		providers[p.Name] = oidc.NewProvider(p)
+	}
+	return providers
+}
+
+func privateUploadsDir(cfg config.Config) string {
+	if cfg.Storage.PrivateDir == "" {
+		return "private_uploads"
+	}
+	return cfg.Storage.PrivateDir
+}
+
+func newMailer(cfg config.Config) mailer.Mailer {
+	if cfg.Mail.Driver == "smtp" {
+		return &mailer.SMTPMailer{
+			Host:     cfg.Mail.SMTPHost,
+			Port:     cfg.Mail.SMTPPort,
+			Username: cfg.Mail.Username,
+			Password: cfg.Mail.Password,
+			From:     cfg.Mail.From,
+		}
+	}
+	dir := cfg.Mail.OutboxDir
+	if dir == "" {
+		dir = "outbox"
 	}
+	return &mailer.OutboxMailer{Dir: dir, From: cfg.Mail.From}
 }
 
 func openDB(dsn string) (*sql.DB, error) {
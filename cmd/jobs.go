@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// jobInterval is how often background jobs run.
+const jobInterval = time.Hour
+
+// runJobs starts the periodic background jobs. They stop when ctx is cancelled.
+func (app *application) runJobs(ctx context.Context) {
+	// expire program access and notify trainers
+	go app.runEvery(ctx, "access expiry", jobInterval, app.inviteService.ExpireAccess)
+	// anonymize accounts whose deletion grace period has ended
+	go app.runEvery(ctx, "account purge", jobInterval, app.accountService.PurgeDue)
+}
+
+// runEvery calls job immediately and then on every tick until ctx is
+// cancelled. job returns the number of records it processed.
+func (app *application) runEvery(ctx context.Context, name string, interval time.Duration, job func(context.Context) (int, error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		n, err := job(ctx)
+		if err != nil {
+			app.errorLog.Printf("%s: %v", name, err)
+		} else if n > 0 {
+			app.infoLog.Printf("%s: %d records processed", name, n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	_ "database/sql"
 	"flag"
 	_ "github.com/jackc/pgx/v5/stdlib"
@@ -39,7 +40,11 @@ func main() {
 	}
 	defer db.Close()
 
-	app := initializeApp(db, errorLog, infoLog)
+	app := initializeApp(db, cfg, errorLog, infoLog)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	app.runJobs(ctx)
 
 	fs := http.FileServer(http.Dir("./uploads"))
 	http.Handle("/static/", http.StripPrefix("/static/", fs))
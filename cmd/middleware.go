@@ -2,14 +2,18 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"github.com/dgrijalva/jwt-go"
 	"log"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
 	"workout/internal/models"
+	"workout/internal/repositories"
+	"workout/internal/services"
+
+	"github.com/google/uuid"
 )
 
 func secureHeaders(next http.Handler) http.Handler {
@@ -27,6 +31,28 @@ func makeResponseJSON(next http.Handler) http.Handler {
 	})
 }
 
+// requestContext присваивает запросу ID (или берёт X-Request-ID клиента) и
+// сохраняет его вместе с IP в контексте для журнала аудита. X-Forwarded-For
+// учитывается только от доверенных прокси
+func (app *application) requestContext(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// цепочка standardMiddleware применяется и к mux, и к маршрутам
+		if _, ok := r.Context().Value("request_id").(string); ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		requestID := r.Header.Get("X-Request-ID")
+		if requestID == "" || len(requestID) > 64 {
+			requestID = uuid.New().String()
+		}
+		w.Header().Set("X-Request-ID", requestID)
+
+		ctx := context.WithValue(r.Context(), "request_id", requestID)
+		ctx = context.WithValue(ctx, "client_ip", app.ipResolver.ClientIP(r))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
 func (app *application) logRequest(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		app.infoLog.Printf("%s - %s %s %s", r.RemoteAddr, r.Proto, r.Method, r.URL.RequestURI())
@@ -47,6 +73,17 @@ func (app *application) recoverPanic(next http.Handler) http.Handler {
 }
 
 func (app *application) JWTMiddleware(next http.Handler, requiredRole string) http.Handler {
+	return app.authenticate(next, requiredRole, "")
+}
+
+// JWTMiddlewareWithScope допускает также персональные API токены с нужным scope
+func (app *application) JWTMiddlewareWithScope(requiredRole, scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return app.authenticate(next, requiredRole, scope)
+	}
+}
+
+func (app *application) authenticate(next http.Handler, requiredRole, scope string) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Получаем access token из заголовка
 		tokenString := r.Header.Get("Authorization")
@@ -58,88 +95,167 @@ func (app *application) JWTMiddleware(next http.Handler, requiredRole string) ht
 		// Удаляем префикс "Bearer "
 		tokenString = strings.TrimPrefix(tokenString, "Bearer ")
 
+		// Персональные API токены принимаются только на маршрутах со scope
+		if strings.HasPrefix(tokenString, services.APITokenPrefix) {
+			app.authenticateAPIToken(next, w, r, tokenString, requiredRole, scope)
+			return
+		}
+
 		// Проверяем access token
 		claims := &models.Claims{}
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				log.Println("Unexpected signing method:", token.Header["alg"])
-				return nil, http.ErrNoLocation
-			}
-			return []byte("asdadsadadaadsasd"), nil
-		})
+		token, err := app.tokenManager.ParseWithClaims(tokenString, claims)
+		if err != nil {
+			log.Println("Access token rejected:", err)
+		}
 
-		fmt.Println("middleware claims:", token)
-		// Если access token недействителен, проверяем refresh token
+		// Обновление токенов выполняется только через POST /auth/refresh
 		if err != nil || !token.Valid {
-			refreshToken := r.Header.Get("Refresh-Token")
-			if refreshToken == "" {
-				http.Error(w, "Refresh token missing", http.StatusUnauthorized)
-				return
-			}
+			http.Error(w, "Invalid or expired access token", http.StatusUnauthorized)
+			return
+		}
 
-			// Получаем refresh token из базы данных
-			session, err := app.userRepo.GetSession(r.Context(), strconv.Itoa(int(claims.UserID)))
+		// Токены с purpose (например, вызов 2FA при входе) не являются access token
+		if claims.Purpose != "" {
+			http.Error(w, "Invalid access token", http.StatusUnauthorized)
+			return
+		}
+
+		// Проверяем, не был ли токен отозван (logout)
+		if claims.Id != "" {
+			revoked, err := app.revokedRepo.IsRevoked(r.Context(), claims.Id)
 			if err != nil {
-				log.Printf("Failed to fetch session for user %v: %v", claims.UserID, err)
-				http.Error(w, "Invalid session", http.StatusUnauthorized)
+				app.serverError(w, err)
 				return
 			}
-
-			// Проверяем совпадение токена из базы и его срок действия
-			if session.RefreshToken != refreshToken || session.ExpiresAt.Before(time.Now()) {
-				http.Error(w, "Invalid or expired refresh token", http.StatusUnauthorized)
+			if revoked {
+				http.Error(w, "Token has been revoked", http.StatusUnauthorized)
 				return
 			}
+		}
 
-			// Создаем новый access token, если refresh token действителен
-			newAccessToken, err := generateAccessToken(int(claims.UserID), claims.Role)
-			if err != nil {
-				log.Printf("Error generating new access token: %v", err)
-				http.Error(w, "Error generating new access token", http.StatusInternalServerError)
+		// Роль и блокировка берутся из базы, чтобы изменения админа действовали сразу
+		role, suspended, err := app.userRepo.GetAccountStatus(r.Context(), int(claims.UserID))
+		if err != nil {
+			if errors.Is(err, repositories.ErrUserNotFound) {
+				http.Error(w, "Invalid access token", http.StatusUnauthorized)
 				return
 			}
-			fmt.Println(newAccessToken)
-			// Устанавливаем новый access token в заголовке ответа
-			w.Header().Set("Authorization", "Bearer "+newAccessToken)
-			log.Printf("New access token issued for user: %v", claims.UserID)
-
-			claims = &models.Claims{UserID: claims.UserID, Role: claims.Role} // Обновляем данные пользователя для проверки ролей
+			app.serverError(w, err)
+			return
 		}
-
-		// Проверка ролей
-		if requiredRole == "admin" && claims.Role != "admin" {
-			http.Error(w, "Forbidden: only admins allowed", http.StatusForbidden)
+		if suspended {
+			http.Error(w, "Account is suspended", http.StatusForbidden)
 			return
 		}
-		if requiredRole == "client" && claims.Role != "client" && claims.Role != "admin" {
-			http.Error(w, "Forbidden: only clients or admins allowed", http.StatusForbidden)
+
+		// Под чужим пользователем нельзя попасть в админские маршруты
+		if claims.ImpersonatorID != 0 && role == models.RoleAdmin {
+			http.Error(w, "Forbidden: admins cannot be impersonated", http.StatusForbidden)
 			return
 		}
 
-		if requiredRole == "trainer" && claims.Role != "trainer" && claims.Role != "admin" {
-			http.Error(w, "Forbidden: only trainers or admins allowed", http.StatusForbidden)
+		if !checkRole(w, requiredRole, role) {
 			return
 		}
 
 		ctx := context.WithValue(r.Context(), "user_id", int(claims.UserID))
-		ctx = context.WithValue(ctx, "role", claims.Role)
+		ctx = context.WithValue(ctx, "role", role)
+		ctx = context.WithValue(ctx, "session_id", claims.SessionID)
+		ctx = context.WithValue(ctx, "jti", claims.Id)
+		ctx = context.WithValue(ctx, "token_expires", time.Unix(claims.ExpiresAt, 0))
+
+		if claims.ImpersonatorID != 0 {
+			impersonatorID := int(claims.ImpersonatorID)
+			// Токен действует, только пока выдавший его админ остаётся админом
+			adminRole, adminSuspended, err := app.userRepo.GetAccountStatus(r.Context(), impersonatorID)
+			if err != nil && !errors.Is(err, repositories.ErrUserNotFound) {
+				app.serverError(w, err)
+				return
+			}
+			if err != nil || adminRole != models.RoleAdmin || adminSuspended {
+				http.Error(w, "Invalid access token", http.StatusUnauthorized)
+				return
+			}
+			ctx = context.WithValue(ctx, "impersonator_id", impersonatorID)
+			w.Header().Set("X-Impersonated-By", strconv.Itoa(impersonatorID))
+			app.auditService.RecordActor(ctx, impersonatorID, models.AuditImpersonatedCall, models.AuditTargetUser, int(claims.UserID),
+				map[string]interface{}{"method": r.Method, "path": r.URL.Path})
+		}
 
 
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
-// Функция для генерации нового access token
-func generateAccessToken(userID int, role string) (string, error) {
-	claims := &models.Claims{
-		UserID: uint(userID),
-		Role:   role,
-		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: time.Now().Add(20 * time.Hour).Unix(), // Устанавливаем срок годности access token
-			IssuedAt:  time.Now().Unix(),
-		},
+func (app *application) authenticateAPIToken(next http.Handler, w http.ResponseWriter, r *http.Request, tokenString, requiredRole, scope string) {
+	if scope == "" {
+		http.Error(w, "API tokens are not accepted here", http.StatusUnauthorized)
+		return
+	}
+
+	user, apiToken, err := app.apiTokenService.Authenticate(r.Context(), tokenString)
+	if err != nil {
+		if errors.Is(err, models.ErrInvalidAPIToken) {
+			http.Error(w, "Invalid or expired API token", http.StatusUnauthorized)
+			return
+		}
+		app.serverError(w, err)
+		return
+	}
+	if !apiToken.HasScope(scope) {
+		http.Error(w, "Forbidden: token lacks scope "+scope, http.StatusForbidden)
+		return
+	}
+
+	if user.SuspendedAt != nil {
+		http.Error(w, "Account is suspended", http.StatusForbidden)
+		return
+	}
+	// Пока не сменён пароль, токены могут быть в чужих руках
+	if user.PasswordResetRequired {
+		http.Error(w, models.ErrPasswordResetRequired.Error(), http.StatusForbidden)
+		return
+	}
+
+	// Роль берётся у владельца токена на момент запроса
+	if !checkRole(w, requiredRole, user.Role) {
+		return
+	}
+
+	ctx := context.WithValue(r.Context(), "user_id", user.ID)
+	ctx = context.WithValue(ctx, "role", user.Role)
+	ctx = context.WithValue(ctx, "api_token_id", apiToken.ID)
+	ctx = context.WithValue(ctx, "scopes", apiToken.Scopes)
+
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// denyImpersonation закрывает чувствительные действия (пароль, 2FA, токены,
+// удаление аккаунта) для админа, действующего от имени пользователя
+func denyImpersonation(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.Context().Value("impersonator_id").(int); ok {
+			http.Error(w, models.ErrImpersonationForbidden.Error(), http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Проверка ролей
+func checkRole(w http.ResponseWriter, requiredRole, role string) bool {
+	if requiredRole == "admin" && role != "admin" {
+		http.Error(w, "Forbidden: only admins allowed", http.StatusForbidden)
+		return false
+	}
+	if requiredRole == "client" && role != "client" && role != "admin" {
+		http.Error(w, "Forbidden: only clients or admins allowed", http.StatusForbidden)
+		return false
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte("asdadsadadaadsasd"))
+	if requiredRole == "trainer" && role != "trainer" && role != "admin" {
+		http.Error(w, "Forbidden: only trainers or admins allowed", http.StatusForbidden)
+		return false
+	}
+	return true
 }
@@ -4,6 +4,7 @@ import (
 	"github.com/bmizerany/pat"
 	"github.com/justinas/alice"
 	"net/http"
+	"workout/internal/models"
 	// httpSwagger "github.com/swaggo/http-swagger"
 	// _ "naimuBack/docs"
 )
@@ -15,67 +16,138 @@ func (app *application) JWTMiddlewareWithRole(requiredRole string) func(http.Han
 }
 
 func (app *application) routes() http.Handler {
-	standardMiddleware := alice.New(app.recoverPanic, app.logRequest, secureHeaders, makeResponseJSON)
+	standardMiddleware := alice.New(app.recoverPanic, app.requestContext, app.logRequest, secureHeaders, makeResponseJSON)
 	//authMiddleware := standardMiddleware.Append(app.JWTMiddlewareWithRole("user"))
 	adminAuthMiddleware := standardMiddleware.Append(app.JWTMiddlewareWithRole("admin"))
 	trainerAuthMiddleware := standardMiddleware.Append(app.JWTMiddlewareWithRole("trainer"))
 	clientAuthMiddleware := standardMiddleware.Append(app.JWTMiddlewareWithRole("client"))
 	authMiddleware := standardMiddleware.Append(app.JWTMiddlewareWithRole(""))
+	// Sensitive account actions are closed to admins impersonating a user
+	selfAuthMiddleware := authMiddleware.Append(denyImpersonation)
+	selfTrainerAuthMiddleware := trainerAuthMiddleware.Append(denyImpersonation)
+	selfClientAuthMiddleware := clientAuthMiddleware.Append(denyImpersonation)
+
+	// Trainer routes that also accept personal access tokens with the given scope
+	programsRead := standardMiddleware.Append(app.JWTMiddlewareWithScope("trainer", models.ScopeProgramsRead))
+	programsWrite := standardMiddleware.Append(app.JWTMiddlewareWithScope("trainer", models.ScopeProgramsWrite))
+	clientsRead := standardMiddleware.Append(app.JWTMiddlewareWithScope("trainer", models.ScopeClientsRead))
+	clientsWrite := standardMiddleware.Append(app.JWTMiddlewareWithScope("trainer", models.ScopeClientsWrite))
+	analyticsRead := standardMiddleware.Append(app.JWTMiddlewareWithScope("trainer", models.ScopeAnalyticsRead))
+	// Shared with enrolled clients; services check ownership or active access
+	programsView := standardMiddleware.Append(app.JWTMiddlewareWithScope("", models.ScopeProgramsRead))
+	clientsView := standardMiddleware.Append(app.JWTMiddlewareWithScope("", models.ScopeClientsRead))
 
 	mux := pat.New()
 
+	mux.Get("/.well-known/jwks.json", standardMiddleware.ThenFunc(app.jwksHandler.JWKS))
+
 	// Users
 	mux.Post("/user", adminAuthMiddleware.ThenFunc(app.userHandler.CreateUser))
+	mux.Post("/user/:id/unlock", adminAuthMiddleware.ThenFunc(app.userHandler.UnlockUser))
+	mux.Get("/admin/users", adminAuthMiddleware.ThenFunc(app.adminHandler.Users))
+	mux.Get("/admin/users/:id", adminAuthMiddleware.ThenFunc(app.adminHandler.User))
+	mux.Put("/admin/users/:id/role", adminAuthMiddleware.ThenFunc(app.adminHandler.ChangeRole))
+	mux.Post("/admin/users/:id/suspend", adminAuthMiddleware.ThenFunc(app.adminHandler.Suspend))
+	mux.Post("/admin/users/:id/reactivate", adminAuthMiddleware.ThenFunc(app.adminHandler.Reactivate))
+	mux.Post("/admin/users/:id/password_reset", adminAuthMiddleware.ThenFunc(app.adminHandler.ForcePasswordReset))
+	mux.Post("/admin/users/:id/impersonate", adminAuthMiddleware.ThenFunc(app.adminHandler.Impersonate))
+	mux.Get("/admin/audit", adminAuthMiddleware.ThenFunc(app.auditHandler.Events))
+	mux.Get("/admin/trainer_applications", adminAuthMiddleware.ThenFunc(app.trainerApplicationHandler.Queue))
+	mux.Get("/admin/trainer_applications/:id", adminAuthMiddleware.ThenFunc(app.trainerApplicationHandler.Application))
+	mux.Get("/admin/trainer_applications/:id/files/:file_id", adminAuthMiddleware.ThenFunc(app.trainerApplicationHandler.File))
+	mux.Post("/admin/trainer_applications/:id/approve", adminAuthMiddleware.ThenFunc(app.trainerApplicationHandler.Approve))
+	mux.Post("/admin/trainer_applications/:id/reject", adminAuthMiddleware.ThenFunc(app.trainerApplicationHandler.Reject))
+	mux.Post("/user/verification_code", standardMiddleware.ThenFunc(app.userHandler.SendVerificationCode))
 	mux.Post("/user/sign_up", standardMiddleware.ThenFunc(app.userHandler.SignUp))
 	mux.Post("/user/sign_in", standardMiddleware.ThenFunc(app.userHandler.SignIn))
-	mux.Post("/user/upgrade", clientAuthMiddleware.ThenFunc(app.userHandler.UpgradeToTrainer))
-	mux.Put("/user/profile", authMiddleware.ThenFunc(app.userHandler.UpdateProfile))
+	mux.Post("/user/sign_in/2fa", standardMiddleware.ThenFunc(app.userHandler.CompleteSignIn))
+	mux.Post("/user/password/forgot", standardMiddleware.ThenFunc(app.userHandler.RequestPasswordReset))
+	mux.Post("/user/password/reset", standardMiddleware.ThenFunc(app.userHandler.ResetPassword))
+	mux.Post("/user/email/confirm", standardMiddleware.ThenFunc(app.userHandler.ConfirmEmailChange))
+	mux.Post("/user/email/revert", standardMiddleware.ThenFunc(app.userHandler.RevertEmailChange))
+	mux.Post("/auth/refresh", standardMiddleware.ThenFunc(app.userHandler.Refresh))
+	mux.Get("/auth/oidc/:provider", standardMiddleware.ThenFunc(app.oidcHandler.Start))
+	mux.Get("/auth/oidc/:provider/callback", standardMiddleware.ThenFunc(app.oidcHandler.Callback))
+	mux.Post("/user/upgrade", selfClientAuthMiddleware.ThenFunc(app.trainerApplicationHandler.Submit))
+	mux.Get("/user/upgrade", clientAuthMiddleware.ThenFunc(app.trainerApplicationHandler.MyApplications))
+	mux.Put("/user/profile", selfAuthMiddleware.ThenFunc(app.userHandler.UpdateProfile))
+	mux.Post("/user/logout", authMiddleware.ThenFunc(app.userHandler.Logout))
+	mux.Post("/user/logout/all", selfAuthMiddleware.ThenFunc(app.userHandler.LogoutEverywhere))
+	mux.Post("/user/2fa/enroll", selfTrainerAuthMiddleware.ThenFunc(app.twoFactorHandler.Enroll))
+	mux.Post("/user/2fa/confirm", selfTrainerAuthMiddleware.ThenFunc(app.twoFactorHandler.Confirm))
+	mux.Post("/user/2fa/disable", selfTrainerAuthMiddleware.ThenFunc(app.twoFactorHandler.Disable))
+	mux.Post("/user/2fa/recovery_codes", selfTrainerAuthMiddleware.ThenFunc(app.twoFactorHandler.RecoveryCodes))
+	mux.Get("/user/sessions", authMiddleware.ThenFunc(app.userHandler.Sessions))
+	mux.Del("/user/sessions/:id", selfAuthMiddleware.ThenFunc(app.userHandler.RevokeSession))
+	mux.Post("/user/api_tokens", selfTrainerAuthMiddleware.ThenFunc(app.apiTokenHandler.CreateToken))
+	mux.Get("/user/api_tokens", trainerAuthMiddleware.ThenFunc(app.apiTokenHandler.Tokens))
+	mux.Del("/user/api_tokens/:id", selfTrainerAuthMiddleware.ThenFunc(app.apiTokenHandler.RevokeToken))
+	mux.Get("/user/account/export", selfAuthMiddleware.ThenFunc(app.accountHandler.Export))
+	mux.Post("/user/account/deletion", selfAuthMiddleware.ThenFunc(app.accountHandler.RequestDeletion))
+	mux.Del("/user/account/deletion", selfAuthMiddleware.ThenFunc(app.accountHandler.CancelDeletion))
 
 	// Programs
-	mux.Post("/program", trainerAuthMiddleware.ThenFunc(app.programHandler.CreateProgram))
-	mux.Get("/programs", trainerAuthMiddleware.ThenFunc(app.programHandler.ProgramsByTrainer))
-	mux.Get("/program/:id", trainerAuthMiddleware.ThenFunc(app.programHandler.GetProgram))
-	mux.Put("/program/:id", trainerAuthMiddleware.ThenFunc(app.programHandler.UpdateProgram))
-	mux.Del("/program/:id", trainerAuthMiddleware.ThenFunc(app.programHandler.DeleteProgram))
+	mux.Post("/program", programsWrite.ThenFunc(app.programHandler.CreateProgram))
+	mux.Post("/program/import", programsWrite.ThenFunc(app.programDocumentHandler.Import))
+	mux.Get("/programs", programsRead.ThenFunc(app.programHandler.ProgramsByTrainer))
+	mux.Get("/program/:id", programsView.ThenFunc(app.programHandler.GetProgram))
+	mux.Put("/program/:id", programsWrite.ThenFunc(app.programHandler.UpdateProgram))
+	mux.Del("/program/:id", programsWrite.ThenFunc(app.programHandler.DeleteProgram))
+	mux.Post("/program/:id/clone", programsWrite.ThenFunc(app.programHandler.CloneProgram))
+	mux.Get("/program/:id/export", programsRead.ThenFunc(app.programDocumentHandler.Export))
+	mux.Get("/program/:id/print", programsView.ThenFunc(app.printHandler.Program))
+	mux.Put("/program/:id/catalog", programsWrite.ThenFunc(app.programHandler.ListInCatalog))
+	mux.Del("/program/:id/catalog", programsWrite.ThenFunc(app.programHandler.RemoveFromCatalog))
+	mux.Post("/program/:id/versions", programsWrite.ThenFunc(app.programVersionHandler.Publish))
+	mux.Get("/program/:id/versions", programsRead.ThenFunc(app.programVersionHandler.Versions))
+	mux.Get("/program/:id/versions/diff", programsRead.ThenFunc(app.programVersionHandler.Diff))
+	mux.Get("/program/:id/versions/:version", programsRead.ThenFunc(app.programVersionHandler.Version))
+	mux.Post("/program/:id/versions/:version/migrate", clientsWrite.ThenFunc(app.programVersionHandler.MigrateClients))
+
+	// Public catalog, no authentication
+	mux.Get("/catalog", standardMiddleware.ThenFunc(app.programHandler.Catalog))
+	mux.Get("/catalog/:slug", standardMiddleware.ThenFunc(app.programHandler.CatalogProgram))
 
 	// Clients
 	//mux.Get("/clients", trainerAuthMiddleware.ThenFunc(app.userHandler.GetAllClients))
-	mux.Get("/program/:program_id/clients", trainerAuthMiddleware.ThenFunc(app.userHandler.GetClientsByProgramID))
-	mux.Del("/program/:program_id/client/:client_id", trainerAuthMiddleware.ThenFunc(app.userHandler.DeleteClientFromProgram))
-	mux.Get("/client/:client_id/programs", trainerAuthMiddleware.ThenFunc(app.userHandler.GetProgramsByClientID))
+	mux.Get("/program/:program_id/clients", clientsRead.ThenFunc(app.userHandler.GetClientsByProgramID))
+	mux.Del("/program/:program_id/client/:client_id", clientsWrite.ThenFunc(app.userHandler.DeleteClientFromProgram))
+	mux.Get("/client/:client_id/programs", clientsView.ThenFunc(app.userHandler.GetProgramsByClientID))
 
 	// Exercises and Food
-	mux.Post("/exercise", trainerAuthMiddleware.ThenFunc(app.exerciseHandler.CreateExercise))
-	mux.Put("/exercise/:id", trainerAuthMiddleware.ThenFunc(app.exerciseHandler.UpdateExercise))
-	mux.Del("/exercise/:id", trainerAuthMiddleware.ThenFunc(app.exerciseHandler.DeleteExercise))
-	mux.Post("/food", trainerAuthMiddleware.ThenFunc(app.foodHandler.CreateFood))
-	mux.Put("/food/:id", trainerAuthMiddleware.ThenFunc(app.foodHandler.UpdateFood))
-	mux.Del("/food/:id", trainerAuthMiddleware.ThenFunc(app.foodHandler.DeleteFood))
+	mux.Post("/exercise", programsWrite.ThenFunc(app.exerciseHandler.CreateExercise))
+	mux.Put("/exercise/:id", programsWrite.ThenFunc(app.exerciseHandler.UpdateExercise))
+	mux.Del("/exercise/:id", programsWrite.ThenFunc(app.exerciseHandler.DeleteExercise))
+	mux.Post("/food", programsWrite.ThenFunc(app.foodHandler.CreateFood))
+	mux.Put("/food/:id", programsWrite.ThenFunc(app.foodHandler.UpdateFood))
+	mux.Del("/food/:id", programsWrite.ThenFunc(app.foodHandler.DeleteFood))
 
 	// Days
-	mux.Get("/program/:program_id/days", trainerAuthMiddleware.ThenFunc(app.dayHandler.DaysByProgram))
-	mux.Get("/program/:program_id/day/:day", trainerAuthMiddleware.ThenFunc(app.dayHandler.DayDetails))
-	mux.Post("/program/day/complete", standardMiddleware.ThenFunc(app.dayHandler.CompleteDay))
-	mux.Post("/program/day/food", standardMiddleware.ThenFunc(app.dayHandler.CompleteFood))
-	mux.Post("/program/day/exercise", standardMiddleware.ThenFunc(app.dayHandler.CompleteExercise))
-	mux.Get("/program/day/progress", standardMiddleware.ThenFunc(app.dayHandler.ProgressStatus))
-	mux.Get("/program/:program_id/progress", standardMiddleware.ThenFunc(app.dayHandler.ProgramProgress))
-
-	mux.Post("/program/day", trainerAuthMiddleware.ThenFunc(app.dayHandler.CreateDay))
-	mux.Put("/program/day/:id", trainerAuthMiddleware.ThenFunc(app.dayHandler.UpdateDay))
-	mux.Del("/program/day/:id", trainerAuthMiddleware.ThenFunc(app.dayHandler.DeleteDay))
+	mux.Get("/program/:program_id/days", programsView.ThenFunc(app.dayHandler.DaysByProgram))
+	mux.Get("/program/:program_id/day/:day", programsView.ThenFunc(app.dayHandler.DayDetails))
+	mux.Post("/program/day/complete", clientAuthMiddleware.ThenFunc(app.dayHandler.CompleteDay))
+	mux.Post("/program/day/food", clientAuthMiddleware.ThenFunc(app.dayHandler.CompleteFood))
+	mux.Post("/program/day/exercise", clientAuthMiddleware.ThenFunc(app.dayHandler.CompleteExercise))
+	mux.Get("/program/day/progress", authMiddleware.ThenFunc(app.dayHandler.ProgressStatus))
+	mux.Get("/program/:program_id/progress", authMiddleware.ThenFunc(app.dayHandler.ProgramProgress))
+
+	mux.Post("/program/day", programsWrite.ThenFunc(app.dayHandler.CreateDay))
+	mux.Put("/program/day/:id", programsWrite.ThenFunc(app.dayHandler.UpdateDay))
+	mux.Del("/program/day/:id", programsWrite.ThenFunc(app.dayHandler.DeleteDay))
 
 	// Invites
-	mux.Post("/program/invite", trainerAuthMiddleware.ThenFunc(app.inviteHandler.InviteClient))
+	mux.Post("/program/invite", clientsWrite.ThenFunc(app.inviteHandler.InviteClient))
 	mux.Post("/program/invite/accept", clientAuthMiddleware.ThenFunc(app.inviteHandler.AcceptInvite))
 	mux.Get("/program/invite/program", standardMiddleware.ThenFunc(app.inviteHandler.ProgramFromInvite))
-	mux.Put("/program/:program_id/client/:client_id/access", trainerAuthMiddleware.ThenFunc(app.inviteHandler.UpdateAccess))
+	mux.Put("/program/:program_id/client/:client_id/access", clientsWrite.ThenFunc(app.inviteHandler.UpdateAccess))
 
 	// Analytics
-	mux.Get("/trainer/analytics", trainerAuthMiddleware.ThenFunc(app.analyticsHandler.TrainerAnalytics))
+	mux.Get("/trainer/analytics", analyticsRead.ThenFunc(app.analyticsHandler.TrainerAnalytics))
+	mux.Get("/trainer/branding", programsRead.ThenFunc(app.printHandler.Branding))
+	mux.Put("/trainer/branding", programsWrite.ThenFunc(app.printHandler.UpdateBranding))
 	mux.Get("/program/invite/program", standardMiddleware.ThenFunc(app.inviteHandler.ProgramFromInvite))
-	mux.Put("/program/:program_id/client/:client_id/access", trainerAuthMiddleware.ThenFunc(app.inviteHandler.UpdateAccess))
-	mux.Put("/program/day/:id", trainerAuthMiddleware.ThenFunc(app.dayHandler.UpdateDay))
+	mux.Put("/program/:program_id/client/:client_id/access", clientsWrite.ThenFunc(app.inviteHandler.UpdateAccess))
+	mux.Put("/program/day/:id", programsWrite.ThenFunc(app.dayHandler.UpdateDay))
 
 	// mux.Get("/swagger/", httpSwagger.WrapHandler)
 
@@ -0,0 +1,5 @@
+ALTER TABLE users
+    ADD COLUMN refresh_token VARCHAR(255),
+    ADD COLUMN expires_at    DATETIME;
+
+DROP TABLE IF EXISTS sessions;
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS sessions
+(
+    id                 INT AUTO_INCREMENT PRIMARY KEY,
+    user_id            INT          NOT NULL,
+    refresh_token_hash CHAR(64)     NOT NULL UNIQUE,
+    user_agent         VARCHAR(512),
+    ip_address         VARCHAR(64),
+    expires_at         DATETIME     NOT NULL,
+    created_at         TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    last_used_at       DATETIME,
+    INDEX sessions_user_idx (user_id),
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+ALTER TABLE users
+    DROP COLUMN refresh_token,
+    DROP COLUMN expires_at;
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS used_refresh_tokens
+(
+    token_hash CHAR(64) PRIMARY KEY,
+    session_id INT      NOT NULL,
+    used_at    DATETIME NOT NULL,
+    FOREIGN KEY (session_id) REFERENCES sessions (id) ON DELETE CASCADE
+);
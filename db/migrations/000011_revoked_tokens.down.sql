@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS revoked_tokens;
+
+ALTER TABLE sessions
+    DROP COLUMN access_jti;
@@ -0,0 +1,11 @@
+ALTER TABLE sessions
+    ADD COLUMN access_jti VARCHAR(64);
+
+CREATE TABLE IF NOT EXISTS revoked_tokens
+(
+    jti        VARCHAR(64) PRIMARY KEY,
+    user_id    INT      NOT NULL,
+    expires_at DATETIME NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX revoked_tokens_expires_idx (expires_at)
+);
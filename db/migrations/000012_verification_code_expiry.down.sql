@@ -0,0 +1,4 @@
+ALTER TABLE verification_codes
+    DROP INDEX verification_codes_email_idx,
+    DROP COLUMN attempts,
+    DROP COLUMN expires_at;
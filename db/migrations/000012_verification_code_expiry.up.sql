@@ -0,0 +1,4 @@
+ALTER TABLE verification_codes
+    ADD COLUMN expires_at DATETIME,
+    ADD COLUMN attempts   INT NOT NULL DEFAULT 0,
+    ADD INDEX verification_codes_email_idx (email);
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS password_resets
+(
+    id         INT AUTO_INCREMENT PRIMARY KEY,
+    user_id    INT      NOT NULL,
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    expires_at DATETIME NOT NULL,
+    used_at    DATETIME,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS user_totp
+(
+    user_id        INT PRIMARY KEY,
+    secret         VARCHAR(64) NOT NULL,
+    last_used_step BIGINT      NOT NULL DEFAULT 0,
+    enabled_at     DATETIME,
+    created_at     TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS totp_recovery_codes
+(
+    id        INT AUTO_INCREMENT PRIMARY KEY,
+    user_id   INT      NOT NULL,
+    code_hash CHAR(64) NOT NULL,
+    used_at   DATETIME,
+    UNIQUE KEY recovery_code_unique (user_id, code_hash),
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS login_attempts
+(
+    scope           VARCHAR(16)  NOT NULL,
+    subject         VARCHAR(255) NOT NULL,
+    failures        INT          NOT NULL DEFAULT 0,
+    last_failure_at DATETIME     NOT NULL,
+    PRIMARY KEY (scope, subject)
+);
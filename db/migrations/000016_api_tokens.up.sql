@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS api_tokens
+(
+    id           INT AUTO_INCREMENT PRIMARY KEY,
+    user_id      INT          NOT NULL,
+    name         VARCHAR(255) NOT NULL,
+    token_hash   CHAR(64)     NOT NULL UNIQUE,
+    token_prefix VARCHAR(16)  NOT NULL,
+    scopes       VARCHAR(512) NOT NULL,
+    expires_at   DATETIME,
+    last_used_at DATETIME,
+    revoked_at   DATETIME,
+    created_at   TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX api_tokens_user_idx (user_id),
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
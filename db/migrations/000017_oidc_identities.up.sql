@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS user_identities
+(
+    id         INT AUTO_INCREMENT PRIMARY KEY,
+    user_id    INT          NOT NULL,
+    provider   VARCHAR(64)  NOT NULL,
+    subject    VARCHAR(255) NOT NULL,
+    email      VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY user_identities_provider_subject (provider, subject),
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS oidc_login_states
+(
+    state_hash    CHAR(64)     NOT NULL PRIMARY KEY,
+    provider      VARCHAR(64)  NOT NULL,
+    code_verifier VARCHAR(128) NOT NULL,
+    nonce         VARCHAR(64)  NOT NULL,
+    invite_token  VARCHAR(255),
+    expires_at    DATETIME     NOT NULL,
+    created_at    TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,4 @@
+ALTER TABLE food DROP FOREIGN KEY food_trainer_fk;
+ALTER TABLE food DROP COLUMN trainer_id;
+ALTER TABLE exercises DROP FOREIGN KEY exercises_trainer_fk;
+ALTER TABLE exercises DROP COLUMN trainer_id;
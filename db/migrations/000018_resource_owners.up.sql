@@ -0,0 +1,16 @@
+ALTER TABLE exercises ADD COLUMN trainer_id INT NULL AFTER id,
+    ADD CONSTRAINT exercises_trainer_fk FOREIGN KEY (trainer_id) REFERENCES users (id) ON DELETE SET NULL;
+ALTER TABLE food ADD COLUMN trainer_id INT NULL AFTER id,
+    ADD CONSTRAINT food_trainer_fk FOREIGN KEY (trainer_id) REFERENCES users (id) ON DELETE SET NULL;
+
+-- existing rows belong to the trainer whose program uses them
+UPDATE exercises e
+    JOIN days d ON d.exercises_id = e.id
+    JOIN workout_programs wp ON wp.id = d.work_out_program_id
+SET e.trainer_id = wp.trainer_id
+WHERE e.trainer_id IS NULL;
+UPDATE food f
+    JOIN days d ON d.food_id = f.id
+    JOIN workout_programs wp ON wp.id = d.work_out_program_id
+SET f.trainer_id = wp.trainer_id
+WHERE f.trainer_id IS NULL;
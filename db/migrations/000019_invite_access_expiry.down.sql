@@ -0,0 +1,2 @@
+DROP INDEX idx_program_invites_access_expires ON program_invites;
+ALTER TABLE program_invites DROP COLUMN expired_at;
@@ -0,0 +1,2 @@
+ALTER TABLE program_invites ADD COLUMN expired_at DATETIME NULL AFTER access_expires;
+CREATE INDEX idx_program_invites_access_expires ON program_invites (access_expires);
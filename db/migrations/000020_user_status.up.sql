@@ -0,0 +1,3 @@
+ALTER TABLE users
+    ADD COLUMN suspended_at DATETIME NULL AFTER role,
+    ADD COLUMN password_reset_required BOOLEAN NOT NULL DEFAULT FALSE AFTER suspended_at;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS trainer_application_files;
+DROP TABLE IF EXISTS trainer_applications;
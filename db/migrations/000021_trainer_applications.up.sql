@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS trainer_applications
+(
+    id            INT AUTO_INCREMENT PRIMARY KEY,
+    user_id       INT          NOT NULL,
+    credentials   TEXT         NOT NULL,
+    bio           TEXT         NOT NULL,
+    status        VARCHAR(20)  NOT NULL DEFAULT 'pending',
+    reviewer_id   INT          NULL,
+    review_reason TEXT         NULL,
+    reviewed_at   DATETIME     NULL,
+    created_at    TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at    TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    INDEX idx_trainer_applications_status (status, created_at),
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    FOREIGN KEY (reviewer_id) REFERENCES users (id) ON DELETE SET NULL
+);
+
+CREATE TABLE IF NOT EXISTS trainer_application_files
+(
+    id             INT AUTO_INCREMENT PRIMARY KEY,
+    application_id INT          NOT NULL,
+    file_name      VARCHAR(255) NOT NULL,
+    content_type   VARCHAR(100) NOT NULL,
+    size           BIGINT       NOT NULL,
+    path           VARCHAR(255) NOT NULL,
+    created_at     TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (application_id) REFERENCES trainer_applications (id) ON DELETE CASCADE
+);
@@ -0,0 +1,4 @@
+ALTER TABLE users
+    DROP INDEX idx_users_deletion_scheduled_at,
+    DROP COLUMN deleted_at,
+    DROP COLUMN deletion_scheduled_at;
@@ -0,0 +1,4 @@
+ALTER TABLE users
+    ADD COLUMN deletion_scheduled_at DATETIME NULL AFTER password_reset_required,
+    ADD COLUMN deleted_at DATETIME NULL AFTER deletion_scheduled_at,
+    ADD INDEX idx_users_deletion_scheduled_at (deletion_scheduled_at);
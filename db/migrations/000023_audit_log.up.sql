@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS audit_events
+(
+    id          BIGINT AUTO_INCREMENT PRIMARY KEY,
+    event       VARCHAR(64)  NOT NULL,
+    actor_id    INT          NULL,
+    target_type VARCHAR(32)  NULL,
+    target_id   INT          NULL,
+    ip_address  VARCHAR(64)  NOT NULL DEFAULT '',
+    request_id  VARCHAR(64)  NOT NULL DEFAULT '',
+    details     TEXT         NULL,
+    created_at  DATETIME(3)  NOT NULL DEFAULT CURRENT_TIMESTAMP(3),
+    INDEX audit_events_event_idx (event, created_at),
+    INDEX audit_events_actor_idx (actor_id, created_at),
+    INDEX audit_events_target_idx (target_type, target_id, created_at),
+    INDEX audit_events_created_idx (created_at)
+);
+
+-- the audit log is append-only
+CREATE TRIGGER audit_events_no_update BEFORE UPDATE ON audit_events
+    FOR EACH ROW SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'audit_events is append-only';
+CREATE TRIGGER audit_events_no_delete BEFORE DELETE ON audit_events
+    FOR EACH ROW SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'audit_events is append-only';
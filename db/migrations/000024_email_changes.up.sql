@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS email_changes
+(
+    id                 INT AUTO_INCREMENT PRIMARY KEY,
+    user_id            INT          NOT NULL,
+    old_email          VARCHAR(255) NOT NULL,
+    new_email          VARCHAR(255) NOT NULL,
+    confirm_token_hash CHAR(64)     NOT NULL UNIQUE,
+    revert_token_hash  CHAR(64)     NOT NULL UNIQUE,
+    expires_at         DATETIME     NOT NULL,
+    revert_expires_at  DATETIME     NOT NULL,
+    confirmed_at       DATETIME,
+    cancelled_at       DATETIME,
+    reverted_at        DATETIME,
+    created_at         TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    INDEX idx_email_changes_user (user_id)
+);
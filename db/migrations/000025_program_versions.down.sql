@@ -0,0 +1,14 @@
+-- Snapshot days that no progress refers to are dropped; the rest are kept
+-- as plain days.
+DELETE d FROM days d
+    LEFT JOIN progress p ON p.day_id = d.id
+WHERE d.program_version_id IS NOT NULL AND p.id IS NULL;
+
+ALTER TABLE program_invites
+    DROP FOREIGN KEY program_invites_version_fk,
+    DROP COLUMN version_id;
+ALTER TABLE days
+    DROP FOREIGN KEY days_program_version_fk,
+    DROP COLUMN program_version_id;
+
+DROP TABLE IF EXISTS program_versions;
@@ -0,0 +1,43 @@
+-- Published, immutable snapshots of a program. Rows of days with
+-- program_version_id set belong to a snapshot; days without it are the
+-- trainer's draft.
+CREATE TABLE IF NOT EXISTS program_versions
+(
+    id           INT AUTO_INCREMENT PRIMARY KEY,
+    program_id   INT          NOT NULL,
+    version      INT          NOT NULL,
+    name         VARCHAR(255) NOT NULL,
+    description  TEXT,
+    days         INT          NOT NULL,
+    published_by INT          NULL,
+    published_at DATETIME     NOT NULL,
+    UNIQUE KEY program_version_unique (program_id, version),
+    FOREIGN KEY (program_id) REFERENCES workout_programs (id),
+    FOREIGN KEY (published_by) REFERENCES users (id) ON DELETE SET NULL
+);
+
+ALTER TABLE days
+    ADD COLUMN program_version_id INT NULL AFTER work_out_program_id,
+    ADD CONSTRAINT days_program_version_fk FOREIGN KEY (program_version_id) REFERENCES program_versions (id);
+ALTER TABLE program_invites
+    ADD COLUMN version_id INT NULL AFTER program_id,
+    ADD CONSTRAINT program_invites_version_fk FOREIGN KEY (version_id) REFERENCES program_versions (id);
+
+-- Existing programs become version 1. Their current days turn into the
+-- snapshot, so progress keeps pointing at them, and a draft copy is made.
+INSERT INTO program_versions (program_id, version, name, description, days, published_by, published_at)
+SELECT id, 1, name, description, days, trainer_id, NOW() FROM workout_programs;
+
+UPDATE days d
+    JOIN program_versions v ON v.program_id = d.work_out_program_id
+SET d.program_version_id = v.id;
+
+INSERT INTO days (work_out_program_id, day_number, exercises_id, food_id, note, created_at, updated_at)
+SELECT work_out_program_id, day_number, exercises_id, food_id, note, created_at, updated_at
+FROM days
+WHERE program_version_id IS NOT NULL;
+
+UPDATE program_invites pi
+    JOIN program_versions v ON v.program_id = pi.program_id
+SET pi.version_id = v.id
+WHERE pi.accepted_at IS NOT NULL;
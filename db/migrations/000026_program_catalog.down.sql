@@ -0,0 +1,9 @@
+ALTER TABLE workout_programs
+    DROP INDEX idx_workout_programs_visibility,
+    DROP INDEX idx_workout_programs_slug,
+    DROP COLUMN catalog_listed_at,
+    DROP COLUMN preview_days,
+    DROP COLUMN summary,
+    DROP COLUMN cover_image_url,
+    DROP COLUMN slug,
+    DROP COLUMN visibility;
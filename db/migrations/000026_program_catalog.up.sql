@@ -0,0 +1,9 @@
+ALTER TABLE workout_programs
+    ADD COLUMN visibility         VARCHAR(20)  NOT NULL DEFAULT 'private' AFTER description,
+    ADD COLUMN slug               VARCHAR(100) NULL AFTER visibility,
+    ADD COLUMN cover_image_url    TEXT NULL AFTER slug,
+    ADD COLUMN summary            TEXT NULL AFTER cover_image_url,
+    ADD COLUMN preview_days       INT          NOT NULL DEFAULT 0 AFTER summary,
+    ADD COLUMN catalog_listed_at  DATETIME NULL AFTER preview_days,
+    ADD UNIQUE INDEX idx_workout_programs_slug (slug),
+    ADD INDEX idx_workout_programs_visibility (visibility, catalog_listed_at);
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS trainer_branding;
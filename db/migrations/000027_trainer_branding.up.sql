@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS trainer_branding
+(
+    trainer_id INT PRIMARY KEY,
+    brand_name VARCHAR(255) NOT NULL DEFAULT '',
+    color      CHAR(7)      NOT NULL DEFAULT '',
+    logo_url   TEXT NULL,
+    footer     VARCHAR(500) NOT NULL DEFAULT '',
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    CONSTRAINT trainer_branding_trainer_fk FOREIGN KEY (trainer_id) REFERENCES users (id) ON DELETE CASCADE
+);
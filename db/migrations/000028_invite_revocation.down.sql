@@ -0,0 +1 @@
+ALTER TABLE program_invites DROP COLUMN revoked_at;
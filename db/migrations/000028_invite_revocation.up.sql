@@ -0,0 +1 @@
+ALTER TABLE program_invites ADD COLUMN revoked_at DATETIME NULL AFTER expired_at;
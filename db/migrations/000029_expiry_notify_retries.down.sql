@@ -0,0 +1,2 @@
+ALTER TABLE program_invites DROP COLUMN expiry_notify_after;
+ALTER TABLE program_invites DROP COLUMN expiry_notify_attempts;
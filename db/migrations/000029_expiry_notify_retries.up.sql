@@ -0,0 +1,2 @@
+ALTER TABLE program_invites ADD COLUMN expiry_notify_attempts INT NOT NULL DEFAULT 0 AFTER expired_at;
+ALTER TABLE program_invites ADD COLUMN expiry_notify_after DATETIME NULL AFTER expiry_notify_attempts;
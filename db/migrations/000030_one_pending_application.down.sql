@@ -0,0 +1,3 @@
+ALTER TABLE trainer_applications
+    DROP INDEX uq_trainer_applications_pending,
+    DROP COLUMN pending_user_id;
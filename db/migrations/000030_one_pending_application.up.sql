@@ -0,0 +1,3 @@
+ALTER TABLE trainer_applications
+    ADD COLUMN pending_user_id INT AS (IF(status = 'pending', user_id, NULL)) STORED,
+    ADD UNIQUE INDEX uq_trainer_applications_pending (pending_user_id);
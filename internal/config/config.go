@@ -3,18 +3,64 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"strings"
 
 	"gopkg.in/yaml.v2"
 )
 
+// SigningKey is a named key used to sign and verify JWTs. HS256 keys use
+// Secret, RS256 and EdDSA keys are read from PEM files.
+type SigningKey struct {
+	ID             string `yaml:"id"`
+	Algorithm      string `yaml:"algorithm"`
+	Secret         string `yaml:"secret"`
+	PrivateKeyFile string `yaml:"private_key_file"`
+	PublicKeyFile  string `yaml:"public_key_file"`
+}
+
+// OIDCProvider configures an OpenID Connect identity provider used for
+// social login. Endpoints are discovered from Issuer.
+type OIDCProvider struct {
+	Name         string   `yaml:"name"`
+	Issuer       string   `yaml:"issuer"`
+	ClientID     string   `yaml:"client_id"`
+	ClientSecret string   `yaml:"client_secret"`
+	RedirectURL  string   `yaml:"redirect_url"`
+	Scopes       []string `yaml:"scopes"`
+}
+
 type Config struct {
 	Server struct {
 		Address string `yaml:"address"`
+		// TrustedProxies are the IPs or CIDR ranges whose X-Forwarded-For
+		// header is believed
+		TrustedProxies []string `yaml:"trusted_proxies"`
 	} `yaml:"server"`
 	Database struct {
 		Driver string `yaml:"driver"`
 		URL    string `yaml:"url"`
 	} `yaml:"database"`
+	Mail struct {
+		Driver    string `yaml:"driver"`
+		From      string `yaml:"from"`
+		SMTPHost  string `yaml:"smtp_host"`
+		SMTPPort  int    `yaml:"smtp_port"`
+		Username  string `yaml:"username"`
+		Password  string `yaml:"password"`
+		OutboxDir string `yaml:"outbox_dir"`
+	} `yaml:"mail"`
+	JWT struct {
+		ActiveKeyID string       `yaml:"active_key_id"`
+		Keys        []SigningKey `yaml:"keys"`
+	} `yaml:"jwt"`
+	OIDC struct {
+		Providers []OIDCProvider `yaml:"providers"`
+	} `yaml:"oidc"`
+	Storage struct {
+		// PrivateDir holds uploads that must not be served publicly
+		PrivateDir string `yaml:"private_dir"`
+	} `yaml:"storage"`
 }
 
 func LoadConfig() Config {
@@ -43,5 +89,48 @@ func LoadConfig() Config {
 	if v := os.Getenv("SERVER_ADDRESS"); v != "" {
 		cfg.Server.Address = v
 	}
+	// TRUSTED_PROXIES is a comma separated list of IPs or CIDR ranges
+	if v := os.Getenv("TRUSTED_PROXIES"); v != "" {
+		cfg.Server.TrustedProxies = strings.Split(v, ",")
+	}
+
+	if v := os.Getenv("MAIL_DRIVER"); v != "" {
+		cfg.Mail.Driver = v
+	}
+	if v := os.Getenv("MAIL_FROM"); v != "" {
+		cfg.Mail.From = v
+	}
+	if v := os.Getenv("SMTP_HOST"); v != "" {
+		cfg.Mail.SMTPHost = v
+	}
+	if v := os.Getenv("SMTP_PORT"); v != "" {
+		if port, err := strconv.Atoi(v); err == nil {
+			cfg.Mail.SMTPPort = port
+		}
+	}
+	if v := os.Getenv("SMTP_USERNAME"); v != "" {
+		cfg.Mail.Username = v
+	}
+	if v := os.Getenv("SMTP_PASSWORD"); v != "" {
+		cfg.Mail.Password = v
+	}
+
+	// JWT_KEYS has the form "id1:secret1,id2:secret2"
+	if v := os.Getenv("JWT_KEYS"); v != "" {
+		cfg.JWT.Keys = nil
+		for _, pair := range strings.Split(v, ",") {
+			id, secret, ok := strings.Cut(strings.TrimSpace(pair), ":")
+			if !ok {
+				log.Fatalf("Invalid JWT_KEYS entry %q", pair)
+			}
+			cfg.JWT.Keys = append(cfg.JWT.Keys, SigningKey{ID: id, Secret: secret})
+		}
+	}
+	if v := os.Getenv("JWT_ACTIVE_KEY_ID"); v != "" {
+		cfg.JWT.ActiveKeyID = v
+	}
+	if v := os.Getenv("PRIVATE_UPLOADS_DIR"); v != "" {
+		cfg.Storage.PrivateDir = v
+	}
 	return cfg
 }
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"workout/internal/models"
+	"workout/internal/repositories"
+	"workout/internal/services"
+)
+
+// AccountHandler serves personal data export and account deletion.
+type AccountHandler struct {
+	Service *services.AccountService
+}
+
+// Export downloads a ZIP archive with all data of the authenticated user.
+func (h *AccountHandler) Export(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(int)
+	if !ok {
+		http.Error(w, "user id missing", http.StatusUnauthorized)
+		return
+	}
+
+	// build the archive first so that failures still get a proper status
+	var buf bytes.Buffer
+	if err := h.Service.Export(r.Context(), userID, &buf); err != nil {
+		if errors.Is(err, repositories.ErrUserNotFound) {
+			http.Error(w, "user not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("Export error: %v", err)
+		http.Error(w, "Ошибка сервера", http.StatusInternalServerError)
+		return
+	}
+
+	name := fmt.Sprintf("workout-export-%d-%s.zip", userID, time.Now().Format("20060102"))
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	buf.WriteTo(w)
+}
+
+// RequestDeletion schedules deletion of the authenticated user's account.
+func (h *AccountHandler) RequestDeletion(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(int)
+	if !ok {
+		http.Error(w, "user id missing", http.StatusUnauthorized)
+		return
+	}
+	var req models.DeleteAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	deletion, err := h.Service.RequestDeletion(r.Context(), userID, req.Password)
+	if err != nil {
+		if errors.Is(err, models.ErrInvalidCredentials) {
+			http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(deletion)
+}
+
+// CancelDeletion keeps an account scheduled for deletion.
+func (h *AccountHandler) CancelDeletion(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(int)
+	if !ok {
+		http.Error(w, "user id missing", http.StatusUnauthorized)
+		return
+	}
+	if err := h.Service.CancelDeletion(r.Context(), userID); err != nil {
+		if errors.Is(err, models.ErrDeletionNotScheduled) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"workout/internal/models"
+	"workout/internal/repositories"
+	"workout/internal/services"
+)
+
+// AdminHandler exposes user management endpoints for administrators.
+type AdminHandler struct {
+	Service *services.AdminService
+}
+
+// Users lists users. Supports q (name, email or phone), role,
+// status=active|suspended, page and per_page.
+func (h *AdminHandler) Users(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter := models.UserFilter{Query: q.Get("q"), Role: q.Get("role")}
+	filter.Page, _ = strconv.Atoi(q.Get("page"))
+	filter.PerPage, _ = strconv.Atoi(q.Get("per_page"))
+	switch q.Get("status") {
+	case "":
+	case "active":
+		suspended := false
+		filter.Suspended = &suspended
+	case "suspended":
+		suspended := true
+		filter.Suspended = &suspended
+	default:
+		http.Error(w, "status must be active or suspended", http.StatusBadRequest)
+		return
+	}
+
+	list, err := h.Service.ListUsers(r.Context(), filter)
+	if err != nil {
+		if errors.Is(err, models.ErrInvalidRole) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+// User returns a user with their programs and enrollments.
+func (h *AdminHandler) User(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(r.URL.Query().Get(":id"))
+	if id == 0 {
+		http.Error(w, "id required", http.StatusBadRequest)
+		return
+	}
+	details, err := h.Service.UserDetails(r.Context(), id)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(details)
+}
+
+// ChangeRole sets the role of a user.
+func (h *AdminHandler) ChangeRole(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(r.URL.Query().Get(":id"))
+	if id == 0 {
+		http.Error(w, "id required", http.StatusBadRequest)
+		return
+	}
+	var req models.ChangeRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	user, err := h.Service.ChangeRole(r.Context(), actorFromContext(r), id, req.Role)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
+
+// Suspend blocks a user account and signs it out everywhere.
+func (h *AdminHandler) Suspend(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(r.URL.Query().Get(":id"))
+	if id == 0 {
+		http.Error(w, "id required", http.StatusBadRequest)
+		return
+	}
+	if err := h.Service.Suspend(r.Context(), actorFromContext(r), id); err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Reactivate lifts the suspension of a user account.
+func (h *AdminHandler) Reactivate(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(r.URL.Query().Get(":id"))
+	if id == 0 {
+		http.Error(w, "id required", http.StatusBadRequest)
+		return
+	}
+	if err := h.Service.Reactivate(r.Context(), actorFromContext(r), id); err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ForcePasswordReset requires the user to reset their password before
+// signing in again and emails them a reset token.
+func (h *AdminHandler) ForcePasswordReset(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(r.URL.Query().Get(":id"))
+	if id == 0 {
+		http.Error(w, "id required", http.StatusBadRequest)
+		return
+	}
+	if err := h.Service.ForcePasswordReset(r.Context(), actorFromContext(r), id); err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Impersonate issues a short-lived token to view the app as the user.
+func (h *AdminHandler) Impersonate(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(r.URL.Query().Get(":id"))
+	if id == 0 {
+		http.Error(w, "id required", http.StatusBadRequest)
+		return
+	}
+	var req models.ImpersonateRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+	token, err := h.Service.Impersonate(r.Context(), actorFromContext(r), id, req.Reason)
+	if err != nil {
+		writeAdminError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(token)
+}
+
+func writeAdminError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, repositories.ErrUserNotFound):
+		http.Error(w, "user not found", http.StatusNotFound)
+	case errors.Is(err, models.ErrInvalidRole), errors.Is(err, models.ErrCannotAlterSelf),
+		errors.Is(err, models.ErrCannotImpersonate):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"workout/internal/models"
+	"workout/internal/services"
+)
+
+// APITokenHandler manages personal access tokens of the signed in user.
+type APITokenHandler struct {
+	Service *services.APITokenService
+}
+
+// CreateToken issues a new token. The raw token is only shown in this response.
+func (h *APITokenHandler) CreateToken(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(int)
+	if !ok {
+		http.Error(w, "user id missing", http.StatusUnauthorized)
+		return
+	}
+	var req models.CreateAPITokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	token, err := h.Service.CreateToken(r.Context(), userID, req)
+	if err != nil {
+		if errors.Is(err, models.ErrInvalidScope) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(token)
+}
+
+// Tokens lists the tokens of the user without their secret values.
+func (h *APITokenHandler) Tokens(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(int)
+	if !ok {
+		http.Error(w, "user id missing", http.StatusUnauthorized)
+		return
+	}
+
+	tokens, err := h.Service.TokensByUser(r.Context(), userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tokens)
+}
+
+// RevokeToken revokes one of the user's tokens.
+func (h *APITokenHandler) RevokeToken(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(int)
+	if !ok {
+		http.Error(w, "user id missing", http.StatusUnauthorized)
+		return
+	}
+	id, _ := strconv.Atoi(r.URL.Query().Get(":id"))
+	if id == 0 {
+		http.Error(w, "id required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Service.RevokeToken(r.Context(), userID, id); err != nil {
+		if errors.Is(err, models.ErrAPITokenNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
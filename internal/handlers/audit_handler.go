@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"workout/internal/models"
+	"workout/internal/services"
+)
+
+// AuditHandler lets admins query the security audit log.
+type AuditHandler struct {
+	Service *services.AuditService
+}
+
+// Events lists audit events. Supports event, actor_id, target_type,
+// target_id, ip, request_id, from and to (RFC 3339), page and per_page.
+func (h *AuditHandler) Events(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter := models.AuditFilter{
+		Event:      q.Get("event"),
+		TargetType: q.Get("target_type"),
+		IPAddress:  q.Get("ip"),
+		RequestID:  q.Get("request_id"),
+	}
+	filter.ActorID, _ = strconv.Atoi(q.Get("actor_id"))
+	filter.TargetID, _ = strconv.Atoi(q.Get("target_id"))
+	filter.Page, _ = strconv.Atoi(q.Get("page"))
+	filter.PerPage, _ = strconv.Atoi(q.Get("per_page"))
+	for _, p := range []struct {
+		name string
+		dst  **time.Time
+	}{{"from", &filter.From}, {"to", &filter.To}} {
+		v := q.Get(p.name)
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			http.Error(w, p.name+" must be an RFC 3339 timestamp", http.StatusBadRequest)
+			return
+		}
+		*p.dst = &t
+	}
+
+	list, err := h.Service.Events(r.Context(), filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
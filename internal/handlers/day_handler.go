@@ -16,6 +16,7 @@ type DayHandler struct {
 }
 
 // DayDetails returns exercises and food for a specific day in a program.
+// Trainers may pass version to read a published version instead of the draft.
 func (h *DayHandler) DayDetails(w http.ResponseWriter, r *http.Request) {
 	programID, _ := strconv.Atoi(r.URL.Query().Get(":program_id"))
 	if programID == 0 {
@@ -32,9 +33,22 @@ func (h *DayHandler) DayDetails(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	details, err := h.Service.GetDay(r.Context(), programID, dayNum)
+	version, _ := strconv.Atoi(r.URL.Query().Get("version"))
+	details, err := h.Service.GetDay(r.Context(), actorFromContext(r), programID, dayNum, version)
 	if err != nil {
-		if errors.Is(err, models.ErrDayNotFound) {
+		if errors.Is(err, models.ErrAccessExpired) {
+			writeAccessExpired(w)
+			return
+		}
+		if errors.Is(err, models.ErrForbidden) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		if errors.Is(err, models.ErrWorkoutProgramNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, models.ErrDayNotFound) || errors.Is(err, models.ErrVersionNotFound) {
 			http.Error(w, err.Error(), http.StatusNotFound)
 			return
 		}
@@ -46,19 +60,20 @@ func (h *DayHandler) DayDetails(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(details)
 }
 
-// CompleteDay marks a day as completed for a client.
+type progressRequest struct {
+	DayID int `json:"day_id"`
+}
+
+// CompleteDay marks a day as completed for the authenticated client.
 func (h *DayHandler) CompleteDay(w http.ResponseWriter, r *http.Request) {
-	var req struct {
-		ClientID int `json:"client_id"`
-		DayID    int `json:"day_id"`
-	}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "invalid request", http.StatusBadRequest)
+	var req progressRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.DayID == 0 {
+		http.Error(w, "day_id required", http.StatusBadRequest)
 		return
 	}
-	progress, err := h.Service.CompleteDay(r.Context(), req.ClientID, req.DayID)
+	progress, err := h.Service.CompleteDay(r.Context(), actorFromContext(r), req.DayID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeProgressError(w, err)
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -67,17 +82,14 @@ func (h *DayHandler) CompleteDay(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *DayHandler) CompleteFood(w http.ResponseWriter, r *http.Request) {
-	var req struct {
-		ClientID int `json:"client_id"`
-		DayID    int `json:"day_id"`
-	}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "invalid request", http.StatusBadRequest)
+	var req progressRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.DayID == 0 {
+		http.Error(w, "day_id required", http.StatusBadRequest)
 		return
 	}
-	progress, err := h.Service.CompleteFood(r.Context(), req.ClientID, req.DayID)
+	progress, err := h.Service.CompleteFood(r.Context(), actorFromContext(r), req.DayID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeProgressError(w, err)
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -85,66 +97,91 @@ func (h *DayHandler) CompleteFood(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *DayHandler) CompleteExercise(w http.ResponseWriter, r *http.Request) {
-	var req struct {
-		ClientID int `json:"client_id"`
-		DayID    int `json:"day_id"`
-	}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "invalid request", http.StatusBadRequest)
+	var req progressRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.DayID == 0 {
+		http.Error(w, "day_id required", http.StatusBadRequest)
 		return
 	}
-	progress, err := h.Service.CompleteExercise(r.Context(), req.ClientID, req.DayID)
+	progress, err := h.Service.CompleteExercise(r.Context(), actorFromContext(r), req.DayID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeProgressError(w, err)
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(progress)
 }
 
+// ProgressStatus returns progress for a day. client_id defaults to the
+// authenticated user; trainers pass the id of one of their clients.
 func (h *DayHandler) ProgressStatus(w http.ResponseWriter, r *http.Request) {
+	actor := actorFromContext(r)
 	clientID, _ := strconv.Atoi(r.URL.Query().Get("client_id"))
+	if clientID == 0 {
+		clientID = actor.UserID
+	}
 	dayID, _ := strconv.Atoi(r.URL.Query().Get("day_id"))
-	if clientID == 0 || dayID == 0 {
-		http.Error(w, "client_id and day_id required", http.StatusBadRequest)
+	if dayID == 0 {
+		http.Error(w, "day_id required", http.StatusBadRequest)
 		return
 	}
-	progress, err := h.Service.GetProgress(r.Context(), clientID, dayID)
+	progress, err := h.Service.GetProgress(r.Context(), actor, clientID, dayID)
 	if err != nil {
-		if errors.Is(err, models.ErrDayNotFound) {
-			http.Error(w, err.Error(), http.StatusNotFound)
-			return
-		}
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeProgressError(w, err)
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(progress)
 }
 
+// ProgramProgress returns progress for all days of a program.
 func (h *DayHandler) ProgramProgress(w http.ResponseWriter, r *http.Request) {
+	actor := actorFromContext(r)
 	clientID, _ := strconv.Atoi(r.URL.Query().Get("client_id"))
-	programID, _ := strconv.Atoi(r.URL.Query().Get("program_id"))
-	if clientID == 0 || programID == 0 {
-		http.Error(w, "client_id and program_id required", http.StatusBadRequest)
+	if clientID == 0 {
+		clientID = actor.UserID
+	}
+	programID, _ := strconv.Atoi(r.URL.Query().Get(":program_id"))
+	if programID == 0 {
+		programID, _ = strconv.Atoi(r.URL.Query().Get("program_id"))
+	}
+	if programID == 0 {
+		http.Error(w, "program_id required", http.StatusBadRequest)
 		return
 	}
-	progress, err := h.Service.GetProgramProgress(r.Context(), clientID, programID)
+	progress, err := h.Service.GetProgramProgress(r.Context(), actor, clientID, programID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeProgressError(w, err)
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(progress)
 }
+
+func writeProgressError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, models.ErrAccessExpired):
+		writeAccessExpired(w)
+	case errors.Is(err, models.ErrForbidden):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, models.ErrDayNotFound), errors.Is(err, models.ErrWorkoutProgramNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
 func (h *DayHandler) CreateDay(w http.ResponseWriter, r *http.Request) {
 	var day models.Days
 	if err := json.NewDecoder(r.Body).Decode(&day); err != nil {
 		http.Error(w, "invalid request body", http.StatusBadRequest)
 		return
 	}
-	created, err := h.Service.CreateDay(r.Context(), day)
+	created, err := h.Service.CreateDay(r.Context(), actorFromContext(r), day)
 	if err != nil {
+		if errors.Is(err, models.ErrForbidden) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
 		status := http.StatusInternalServerError
 		if errors.Is(err, models.ErrWorkoutProgramNotFound) || errors.Is(err, models.ErrExerciseNotFound) || errors.Is(err, models.ErrFoodNotFound) {
 			status = http.StatusBadRequest
@@ -157,7 +194,8 @@ func (h *DayHandler) CreateDay(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(created)
 }
 
-// DaysByProgram returns all days with details for a program.
+// DaysByProgram returns all days with details for a program. Trainers may
+// pass version to read a published version instead of the draft.
 func (h *DayHandler) DaysByProgram(w http.ResponseWriter, r *http.Request) {
 	programID, _ := strconv.Atoi(r.URL.Query().Get(":program_id"))
 	if programID == 0 {
@@ -168,8 +206,21 @@ func (h *DayHandler) DaysByProgram(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	days, err := h.Service.DaysByProgram(r.Context(), programID)
+	version, _ := strconv.Atoi(r.URL.Query().Get("version"))
+	days, err := h.Service.DaysByProgram(r.Context(), actorFromContext(r), programID, version)
 	if err != nil {
+		if errors.Is(err, models.ErrAccessExpired) {
+			writeAccessExpired(w)
+			return
+		}
+		if errors.Is(err, models.ErrForbidden) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		if errors.Is(err, models.ErrWorkoutProgramNotFound) || errors.Is(err, models.ErrVersionNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -195,8 +246,16 @@ func (h *DayHandler) UpdateDay(w http.ResponseWriter, r *http.Request) {
 	}
 	day.ID = id
 
-	updated, err := h.Service.UpdateDay(r.Context(), day)
+	updated, err := h.Service.UpdateDay(r.Context(), actorFromContext(r), day)
 	if err != nil {
+		if errors.Is(err, models.ErrForbidden) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		if errors.Is(err, models.ErrVersionImmutable) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
 		if errors.Is(err, models.ErrDayNotFound) {
 			http.Error(w, err.Error(), http.StatusNotFound)
 			return
@@ -224,7 +283,19 @@ func (h *DayHandler) DeleteDay(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.Service.DeleteDay(r.Context(), id); err != nil {
+	if err := h.Service.DeleteDay(r.Context(), actorFromContext(r), id); err != nil {
+		if errors.Is(err, models.ErrForbidden) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		if errors.Is(err, models.ErrVersionImmutable) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		if errors.Is(err, models.ErrWorkoutProgramNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
 		if errors.Is(err, models.ErrDayNotFound) {
 			http.Error(w, err.Error(), http.StatusNotFound)
 			return
@@ -22,7 +22,7 @@ func (h *ExerciseHandler) CreateExercise(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	created, err := h.Service.CreateExercise(r.Context(), ex)
+	created, err := h.Service.CreateExercise(r.Context(), actorFromContext(r), ex)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -51,8 +51,12 @@ func (h *ExerciseHandler) UpdateExercise(w http.ResponseWriter, r *http.Request)
 	}
 	ex.ID = id
 
-	updated, err := h.Service.UpdateExercise(r.Context(), ex)
+	updated, err := h.Service.UpdateExercise(r.Context(), actorFromContext(r), ex)
 	if err != nil {
+		if errors.Is(err, models.ErrForbidden) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
 		if errors.Is(err, models.ErrExerciseNotFound) {
 			http.Error(w, err.Error(), http.StatusNotFound)
 			return
@@ -76,7 +80,11 @@ func (h *ExerciseHandler) DeleteExercise(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	if err := h.Service.DeleteExercise(r.Context(), id); err != nil {
+	if err := h.Service.DeleteExercise(r.Context(), actorFromContext(r), id); err != nil {
+		if errors.Is(err, models.ErrForbidden) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
 		if errors.Is(err, models.ErrExerciseNotFound) {
 			http.Error(w, err.Error(), http.StatusNotFound)
 			return
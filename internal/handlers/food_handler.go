@@ -22,7 +22,7 @@ func (h *FoodHandler) CreateFood(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	created, err := h.Service.CreateFood(r.Context(), f)
+	created, err := h.Service.CreateFood(r.Context(), actorFromContext(r), f)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -51,8 +51,12 @@ func (h *FoodHandler) UpdateFood(w http.ResponseWriter, r *http.Request) {
 	}
 	f.ID = id
 
-	updated, err := h.Service.UpdateFood(r.Context(), f)
+	updated, err := h.Service.UpdateFood(r.Context(), actorFromContext(r), f)
 	if err != nil {
+		if errors.Is(err, models.ErrForbidden) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
 		if errors.Is(err, models.ErrFoodNotFound) {
 			http.Error(w, err.Error(), http.StatusNotFound)
 			return
@@ -76,7 +80,11 @@ func (h *FoodHandler) DeleteFood(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.Service.DeleteFood(r.Context(), id); err != nil {
+	if err := h.Service.DeleteFood(r.Context(), actorFromContext(r), id); err != nil {
+		if errors.Is(err, models.ErrForbidden) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
 		if errors.Is(err, models.ErrFoodNotFound) {
 			http.Error(w, err.Error(), http.StatusNotFound)
 			return
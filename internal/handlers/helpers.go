@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+
+	"workout/internal/models"
+)
+
+// clientIP returns the address of the caller resolved by the request
+// middleware, which only trusts X-Forwarded-For from configured proxies.
+func clientIP(r *http.Request) string {
+	if ip, ok := r.Context().Value("client_ip").(string); ok {
+		return ip
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// actorFromContext returns the authenticated caller set by JWTMiddleware.
+func actorFromContext(r *http.Request) models.Actor {
+	userID, _ := r.Context().Value("user_id").(int)
+	role, _ := r.Context().Value("role").(string)
+	return models.Actor{UserID: userID, Role: role}
+}
+
+// writeAccessExpired responds with 403 and the access_expired code so that
+// clients can tell an expired enrollment from a missing permission.
+func writeAccessExpired(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusForbidden)
+	json.NewEncoder(w).Encode(map[string]string{
+		"code":  "access_expired",
+		"error": models.ErrAccessExpired.Error(),
+	})
+}
@@ -25,8 +25,20 @@ func (h *InviteHandler) InviteClient(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "invalid request", http.StatusBadRequest)
 		return
 	}
-	inv, err := h.Service.InviteClient(r.Context(), req.ProgramID, req.Email, req.Message, req.AccessDays)
+	inv, err := h.Service.InviteClient(r.Context(), actorFromContext(r), req.ProgramID, req.Email, req.Message, req.AccessDays)
 	if err != nil {
+		if err == models.ErrForbidden {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		if err == models.ErrWorkoutProgramNotFound {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err == models.ErrProgramNotPublished {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -54,6 +66,18 @@ func (h *InviteHandler) AcceptInvite(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, err.Error(), http.StatusNotFound)
 			return
 		}
+		if err == models.ErrInviteAccepted {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		if err == models.ErrInviteMismatch {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		if err == models.ErrInviteRevoked {
+			http.Error(w, err.Error(), http.StatusGone)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -81,9 +105,13 @@ func (h *InviteHandler) UpdateAccess(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "program_id and client_id required", http.StatusBadRequest)
 		return
 	}
-	inv, err := h.Service.UpdateAccess(r.Context(), programID, clientID, req.AccessDays)
+	inv, err := h.Service.UpdateAccess(r.Context(), actorFromContext(r), programID, clientID, req.AccessDays)
 	if err != nil {
-		if err == models.ErrInviteNotFound {
+		if err == models.ErrForbidden {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		if err == models.ErrInviteNotFound || err == models.ErrWorkoutProgramNotFound {
 			http.Error(w, err.Error(), http.StatusNotFound)
 			return
 		}
@@ -0,0 +1,20 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"workout/utils"
+)
+
+// JWKSHandler publishes the public keys used to sign access tokens so that
+// other services can verify them without sharing a secret.
+type JWKSHandler struct {
+	Manager *utils.Manager
+}
+
+func (h *JWKSHandler) JWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	json.NewEncoder(w).Encode(h.Manager.JWKS())
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"workout/internal/models"
+	"workout/internal/services"
+)
+
+// OIDCHandler exposes social login through OpenID Connect providers.
+type OIDCHandler struct {
+	Service *services.OIDCService
+}
+
+// oidcStateCookie binds a login to the browser that started it, so a
+// callback URL cannot be replayed in someone else's browser.
+const oidcStateCookie = "oidc_state"
+
+// Start returns the provider URL to open. An optional "invite" query
+// parameter is accepted for the signed in user once the login completes.
+func (h *OIDCHandler) Start(w http.ResponseWriter, r *http.Request) {
+	provider := r.URL.Query().Get(":provider")
+
+	resp, err := h.Service.Start(r.Context(), provider, r.URL.Query().Get("invite"))
+	if err != nil {
+		if errors.Is(err, models.ErrUnknownOIDCProvider) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		log.Printf("OIDC start error: %v", err)
+		http.Error(w, "identity provider is unavailable", http.StatusBadGateway)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    resp.State,
+		Path:     "/auth/oidc/",
+		MaxAge:   int(services.OIDCLoginTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		// Lax so the cookie comes along on the provider's redirect back
+		SameSite: http.SameSiteLaxMode,
+	})
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// Callback completes the login and returns the same result as SignIn.
+func (h *OIDCHandler) Callback(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if e := q.Get("error"); e != "" {
+		http.Error(w, "login was cancelled: "+e, http.StatusUnauthorized)
+		return
+	}
+	if q.Get("code") == "" || q.Get("state") == "" {
+		http.Error(w, "code and state required", http.StatusBadRequest)
+		return
+	}
+	cookie, err := r.Cookie(oidcStateCookie)
+	if err != nil || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(q.Get("state"))) != 1 {
+		http.Error(w, models.ErrInvalidOIDCState.Error(), http.StatusUnauthorized)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: oidcStateCookie, Path: "/auth/oidc/", MaxAge: -1, HttpOnly: true})
+
+	resp, err := h.Service.Callback(r.Context(), q.Get(":provider"), q.Get("code"), q.Get("state"), r.UserAgent(), clientIP(r))
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrUnknownOIDCProvider):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, models.ErrInvalidOIDCState), errors.Is(err, models.ErrInvalidIDToken),
+			errors.Is(err, models.ErrEmailNotVerified):
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+		case errors.Is(err, models.ErrAccountSuspended), errors.Is(err, models.ErrPasswordResetRequired):
+			http.Error(w, err.Error(), http.StatusForbidden)
+		default:
+			log.Printf("OIDC callback error: %v", err)
+			http.Error(w, "Ошибка сервера", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
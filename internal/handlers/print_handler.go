@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"workout/internal/models"
+	"workout/internal/printing"
+	"workout/internal/services"
+)
+
+// PrintHandler serves printable programs and the trainer branding used on
+// them.
+type PrintHandler struct {
+	Service *services.PrintService
+}
+
+// Program downloads a printable program. Supports format (pdf or html) and,
+// for trainers, version to print a published version instead of the draft.
+func (h *PrintHandler) Program(w http.ResponseWriter, r *http.Request) {
+	programID, _ := strconv.Atoi(r.URL.Query().Get(":id"))
+	if programID == 0 {
+		http.Error(w, "id required", http.StatusBadRequest)
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "pdf"
+	}
+	if format != "pdf" && format != "html" {
+		http.Error(w, "format must be pdf or html", http.StatusBadRequest)
+		return
+	}
+	version, _ := strconv.Atoi(r.URL.Query().Get("version"))
+
+	plan, err := h.Service.Plan(r.Context(), actorFromContext(r), programID, version)
+	if err != nil {
+		writePrintError(w, err)
+		return
+	}
+
+	var out bytes.Buffer
+	contentType := "application/pdf"
+	if format == "html" {
+		contentType = "text/html; charset=utf-8"
+		err = printing.RenderHTML(&out, plan)
+	} else {
+		err = printing.RenderPDF(&out, plan)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="program-%d.%s"`, programID, format))
+	w.Write(out.Bytes())
+}
+
+// Branding returns the trainer's printout branding.
+func (h *PrintHandler) Branding(w http.ResponseWriter, r *http.Request) {
+	b, err := h.Service.Branding(r.Context(), actorFromContext(r))
+	if err != nil {
+		writePrintError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(b)
+}
+
+// UpdateBranding replaces the trainer's printout branding.
+func (h *PrintHandler) UpdateBranding(w http.ResponseWriter, r *http.Request) {
+	var b models.Branding
+	if err := json.NewDecoder(r.Body).Decode(&b); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	saved, err := h.Service.UpdateBranding(r.Context(), actorFromContext(r), b)
+	if err != nil {
+		writePrintError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(saved)
+}
+
+func writePrintError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, models.ErrInvalidBranding):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, models.ErrAccessExpired):
+		writeAccessExpired(w)
+	case errors.Is(err, models.ErrForbidden):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, models.ErrWorkoutProgramNotFound), errors.Is(err, models.ErrVersionNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
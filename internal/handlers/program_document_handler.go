@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"gopkg.in/yaml.v2"
+
+	"workout/internal/models"
+	"workout/internal/services"
+)
+
+// maxDocumentSize bounds the body of an import request.
+const maxDocumentSize = 1 << 20
+
+// ProgramDocumentHandler exports and imports programs as JSON or YAML
+// documents.
+type ProgramDocumentHandler struct {
+	Service *services.ProgramDocumentService
+}
+
+// Export downloads a program document. Supports format (json or yaml) and
+// version to export a published version instead of the draft.
+func (h *ProgramDocumentHandler) Export(w http.ResponseWriter, r *http.Request) {
+	programID, _ := strconv.Atoi(r.URL.Query().Get(":id"))
+	if programID == 0 {
+		http.Error(w, "id required", http.StatusBadRequest)
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "yaml" {
+		http.Error(w, "format must be json or yaml", http.StatusBadRequest)
+		return
+	}
+	version, _ := strconv.Atoi(r.URL.Query().Get("version"))
+
+	doc, err := h.Service.Export(r.Context(), actorFromContext(r), programID, version)
+	if err != nil {
+		writeDocumentError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="program-%d.%s"`, programID, format))
+	if format == "yaml" {
+		out, err := yaml.Marshal(doc)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/yaml")
+		w.Write(out)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(doc)
+}
+
+// Import creates a program from a document. YAML is expected when the
+// Content-Type says so, JSON otherwise.
+func (h *ProgramDocumentHandler) Import(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxDocumentSize))
+	if err != nil {
+		http.Error(w, "document is too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	var doc models.ProgramDocument
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "application/yaml", "application/x-yaml", "text/yaml", "text/x-yaml":
+		err = yaml.Unmarshal(body, &doc)
+	default:
+		err = json.Unmarshal(body, &doc)
+	}
+	if err != nil {
+		http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	p, err := h.Service.Import(r.Context(), actorFromContext(r), doc)
+	if err != nil {
+		writeDocumentError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(p)
+}
+
+func writeDocumentError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, models.ErrInvalidProgramDocument):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, models.ErrForbidden):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, models.ErrWorkoutProgramNotFound), errors.Is(err, models.ErrVersionNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
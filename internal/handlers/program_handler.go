@@ -52,8 +52,12 @@ func (h *ProgramHandler) ProgramsByTrainer(w http.ResponseWriter, r *http.Reques
 		}
 	}
 
-	programs, err := h.Service.ProgramsByTrainer(r.Context(), trainerID)
+	programs, err := h.Service.ProgramsByTrainer(r.Context(), actorFromContext(r), trainerID)
 	if err != nil {
+		if errors.Is(err, models.ErrForbidden) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -73,8 +77,16 @@ func (h *ProgramHandler) GetProgram(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	p, err := h.Service.ProgramByID(r.Context(), id)
+	p, err := h.Service.ProgramByID(r.Context(), actorFromContext(r), id)
 	if err != nil {
+		if errors.Is(err, models.ErrAccessExpired) {
+			writeAccessExpired(w)
+			return
+		}
+		if errors.Is(err, models.ErrForbidden) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
 		if errors.Is(err, models.ErrWorkoutProgramNotFound) {
 			http.Error(w, err.Error(), http.StatusNotFound)
 			return
@@ -104,8 +116,12 @@ func (h *ProgramHandler) UpdateProgram(w http.ResponseWriter, r *http.Request) {
 	}
 	p.ID = id
 
-	updated, err := h.Service.UpdateProgram(r.Context(), p)
+	updated, err := h.Service.UpdateProgram(r.Context(), actorFromContext(r), p)
 	if err != nil {
+		if errors.Is(err, models.ErrForbidden) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
 		if errors.Is(err, models.ErrWorkoutProgramNotFound) {
 			http.Error(w, err.Error(), http.StatusNotFound)
 			return
@@ -129,7 +145,15 @@ func (h *ProgramHandler) DeleteProgram(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.Service.DeleteProgram(r.Context(), id); err != nil {
+	if err := h.Service.DeleteProgram(r.Context(), actorFromContext(r), id); err != nil {
+		if errors.Is(err, models.ErrForbidden) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		if errors.Is(err, models.ErrProgramHasClients) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
 		if errors.Is(err, models.ErrWorkoutProgramNotFound) {
 			http.Error(w, err.Error(), http.StatusNotFound)
 			return
@@ -140,3 +164,122 @@ func (h *ProgramHandler) DeleteProgram(w http.ResponseWriter, r *http.Request) {
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// CloneProgram copies a program with all of its days. The body is optional.
+func (h *ProgramHandler) CloneProgram(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(r.URL.Query().Get(":id"))
+	if id == 0 {
+		http.Error(w, "id required", http.StatusBadRequest)
+		return
+	}
+	var req models.CloneProgramRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	created, err := h.Service.CloneProgram(r.Context(), actorFromContext(r), id, req)
+	if err != nil {
+		if errors.Is(err, models.ErrForbidden) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		if errors.Is(err, models.ErrWorkoutProgramNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+// ListInCatalog publishes a program to the public catalog or updates its
+// listing.
+func (h *ProgramHandler) ListInCatalog(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(r.URL.Query().Get(":id"))
+	if id == 0 {
+		http.Error(w, "id required", http.StatusBadRequest)
+		return
+	}
+	var req models.CatalogRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	listed, err := h.Service.ListInCatalog(r.Context(), actorFromContext(r), id, req)
+	if err != nil {
+		writeCatalogError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(listed)
+}
+
+// RemoveFromCatalog hides a program from the public catalog.
+func (h *ProgramHandler) RemoveFromCatalog(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(r.URL.Query().Get(":id"))
+	if id == 0 {
+		http.Error(w, "id required", http.StatusBadRequest)
+		return
+	}
+	if err := h.Service.RemoveFromCatalog(r.Context(), actorFromContext(r), id); err != nil {
+		writeCatalogError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Catalog lists public programs. Supports q, trainer_id, page and per_page.
+func (h *ProgramHandler) Catalog(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter := models.CatalogFilter{Query: q.Get("q")}
+	filter.TrainerID, _ = strconv.Atoi(q.Get("trainer_id"))
+	filter.Page, _ = strconv.Atoi(q.Get("page"))
+	filter.PerPage, _ = strconv.Atoi(q.Get("per_page"))
+
+	list, err := h.Service.Catalog(r.Context(), filter)
+	if err != nil {
+		writeCatalogError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+// CatalogProgram returns a public program by its slug with preview days.
+func (h *ProgramHandler) CatalogProgram(w http.ResponseWriter, r *http.Request) {
+	slug := r.URL.Query().Get(":slug")
+	if slug == "" {
+		http.Error(w, "slug required", http.StatusBadRequest)
+		return
+	}
+	p, err := h.Service.CatalogProgram(r.Context(), slug)
+	if err != nil {
+		writeCatalogError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(p)
+}
+
+func writeCatalogError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, models.ErrInvalidCatalog):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, models.ErrForbidden):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, models.ErrWorkoutProgramNotFound), errors.Is(err, models.ErrCatalogNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, models.ErrSlugTaken), errors.Is(err, models.ErrProgramNotPublished):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"workout/internal/models"
+	"workout/internal/services"
+)
+
+// ProgramVersionHandler exposes publishing and versions of programs.
+type ProgramVersionHandler struct {
+	Service *services.ProgramVersionService
+}
+
+// Publish snapshots the draft of a program as a new version.
+func (h *ProgramVersionHandler) Publish(w http.ResponseWriter, r *http.Request) {
+	programID, _ := strconv.Atoi(r.URL.Query().Get(":id"))
+	if programID == 0 {
+		http.Error(w, "id required", http.StatusBadRequest)
+		return
+	}
+	v, err := h.Service.Publish(r.Context(), actorFromContext(r), programID)
+	if err != nil {
+		writeVersionError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(v)
+}
+
+// Versions lists the published versions of a program.
+func (h *ProgramVersionHandler) Versions(w http.ResponseWriter, r *http.Request) {
+	programID, _ := strconv.Atoi(r.URL.Query().Get(":id"))
+	if programID == 0 {
+		http.Error(w, "id required", http.StatusBadRequest)
+		return
+	}
+	versions, err := h.Service.Versions(r.Context(), actorFromContext(r), programID)
+	if err != nil {
+		writeVersionError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(versions)
+}
+
+// Version returns a published version with its days.
+func (h *ProgramVersionHandler) Version(w http.ResponseWriter, r *http.Request) {
+	programID, _ := strconv.Atoi(r.URL.Query().Get(":id"))
+	version, _ := strconv.Atoi(r.URL.Query().Get(":version"))
+	if programID == 0 || version == 0 {
+		http.Error(w, "id and version required", http.StatusBadRequest)
+		return
+	}
+	v, err := h.Service.Version(r.Context(), actorFromContext(r), programID, version)
+	if err != nil {
+		writeVersionError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// Diff compares version from with version to, or with the draft when to is
+// omitted.
+func (h *ProgramVersionHandler) Diff(w http.ResponseWriter, r *http.Request) {
+	programID, _ := strconv.Atoi(r.URL.Query().Get(":id"))
+	from, _ := strconv.Atoi(r.URL.Query().Get("from"))
+	to, _ := strconv.Atoi(r.URL.Query().Get("to"))
+	if programID == 0 || from == 0 {
+		http.Error(w, "id and from required", http.StatusBadRequest)
+		return
+	}
+	diff, err := h.Service.Diff(r.Context(), actorFromContext(r), programID, from, to)
+	if err != nil {
+		writeVersionError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(diff)
+}
+
+// MigrateClients pins clients of the program to a version.
+func (h *ProgramVersionHandler) MigrateClients(w http.ResponseWriter, r *http.Request) {
+	programID, _ := strconv.Atoi(r.URL.Query().Get(":id"))
+	version, _ := strconv.Atoi(r.URL.Query().Get(":version"))
+	if programID == 0 || version == 0 {
+		http.Error(w, "id and version required", http.StatusBadRequest)
+		return
+	}
+	var req models.MigrateClientsRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+	res, err := h.Service.MigrateClients(r.Context(), actorFromContext(r), programID, version, req.ClientIDs)
+	if err != nil {
+		writeVersionError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(res)
+}
+
+func writeVersionError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, models.ErrForbidden):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, models.ErrWorkoutProgramNotFound), errors.Is(err, models.ErrVersionNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"workout/internal/models"
+	"workout/internal/repositories"
+	"workout/internal/services"
+)
+
+// maxApplicationBody bounds the multipart body of an application.
+const maxApplicationBody = 55 << 20
+
+// TrainerApplicationHandler exposes the trainer application workflow.
+type TrainerApplicationHandler struct {
+	Service *services.TrainerApplicationService
+}
+
+// Submit files an application to become a trainer. Expects a multipart form
+// with credentials, bio and up to five certificates files.
+func (h *TrainerApplicationHandler) Submit(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(int)
+	if !ok {
+		http.Error(w, "user id missing", http.StatusUnauthorized)
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxApplicationBody)
+	if err := r.ParseMultipartForm(10 << 20); err != nil {
+		http.Error(w, "invalid multipart form", http.StatusBadRequest)
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	app, err := h.Service.Submit(r.Context(), userID, r.FormValue("credentials"), r.FormValue("bio"), r.MultipartForm.File["certificates"])
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrInvalidApplication), errors.Is(err, models.ErrInvalidUpload):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, models.ErrApplicationPending), errors.Is(err, models.ErrAlreadyTrainer):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			log.Printf("Trainer application error: %v", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(app)
+}
+
+// MyApplications lists the applications of the authenticated user.
+func (h *TrainerApplicationHandler) MyApplications(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(int)
+	if !ok {
+		http.Error(w, "user id missing", http.StatusUnauthorized)
+		return
+	}
+	apps, err := h.Service.MyApplications(r.Context(), userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(apps)
+}
+
+// Queue lists applications for review. Supports status and page.
+func (h *TrainerApplicationHandler) Queue(w http.ResponseWriter, r *http.Request) {
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	apps, total, err := h.Service.Queue(r.Context(), r.URL.Query().Get("status"), page)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"applications": apps, "total": total})
+}
+
+// Application returns an application with its certificates.
+func (h *TrainerApplicationHandler) Application(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(r.URL.Query().Get(":id"))
+	if id == 0 {
+		http.Error(w, "id required", http.StatusBadRequest)
+		return
+	}
+	app, err := h.Service.Application(r.Context(), id)
+	if err != nil {
+		writeApplicationError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(app)
+}
+
+// File downloads a certificate of an application.
+func (h *TrainerApplicationHandler) File(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(r.URL.Query().Get(":id"))
+	fileID, _ := strconv.Atoi(r.URL.Query().Get(":file_id"))
+	if id == 0 || fileID == 0 {
+		http.Error(w, "id and file_id required", http.StatusBadRequest)
+		return
+	}
+	meta, file, err := h.Service.OpenFile(r.Context(), id, fileID)
+	if err != nil {
+		writeApplicationError(w, err)
+		return
+	}
+	defer file.Close()
+
+	w.Header().Set("Content-Type", meta.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", meta.FileName))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	http.ServeContent(w, r, meta.FileName, meta.CreatedAt, file)
+}
+
+// Approve grants the trainer role to the applicant.
+func (h *TrainerApplicationHandler) Approve(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(r.URL.Query().Get(":id"))
+	if id == 0 {
+		http.Error(w, "id required", http.StatusBadRequest)
+		return
+	}
+	app, err := h.Service.Approve(r.Context(), actorFromContext(r), id)
+	if err != nil {
+		writeApplicationError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(app)
+}
+
+// Reject declines an application with a reason.
+func (h *TrainerApplicationHandler) Reject(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(r.URL.Query().Get(":id"))
+	if id == 0 {
+		http.Error(w, "id required", http.StatusBadRequest)
+		return
+	}
+	var req models.ReviewApplicationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	app, err := h.Service.Reject(r.Context(), actorFromContext(r), id, req.Reason)
+	if err != nil {
+		writeApplicationError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(app)
+}
+
+func writeApplicationError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, models.ErrApplicationNotFound), errors.Is(err, repositories.ErrUserNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, models.ErrApplicationReviewed), errors.Is(err, models.ErrApplicantNotClient):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, models.ErrRejectionReasonRequired):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
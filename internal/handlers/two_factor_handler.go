@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"workout/internal/models"
+	"workout/internal/services"
+)
+
+// TwoFactorHandler exposes TOTP enrollment endpoints.
+type TwoFactorHandler struct {
+	Service *services.TwoFactorService
+}
+
+type twoFactorCodeRequest struct {
+	Code string `json:"code"`
+}
+
+// Enroll starts setting up an authenticator app and returns its secret.
+func (h *TwoFactorHandler) Enroll(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(int)
+	if !ok {
+		http.Error(w, "user id missing", http.StatusUnauthorized)
+		return
+	}
+
+	enrollment, err := h.Service.Enroll(r.Context(), userID)
+	if err != nil {
+		if errors.Is(err, models.ErrTwoFactorAlreadyEnabled) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(enrollment)
+}
+
+// Confirm activates two-factor authentication and returns recovery codes.
+func (h *TwoFactorHandler) Confirm(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(int)
+	if !ok {
+		http.Error(w, "user id missing", http.StatusUnauthorized)
+		return
+	}
+	var req twoFactorCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	codes, err := h.Service.Confirm(r.Context(), userID, req.Code)
+	if err != nil {
+		writeTwoFactorError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(codes)
+}
+
+// Disable turns two-factor authentication off.
+func (h *TwoFactorHandler) Disable(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(int)
+	if !ok {
+		http.Error(w, "user id missing", http.StatusUnauthorized)
+		return
+	}
+	var req twoFactorCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Service.Disable(r.Context(), userID, req.Code); err != nil {
+		writeTwoFactorError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RecoveryCodes replaces the recovery codes of the user.
+func (h *TwoFactorHandler) RecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(int)
+	if !ok {
+		http.Error(w, "user id missing", http.StatusUnauthorized)
+		return
+	}
+	var req twoFactorCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	codes, err := h.Service.RegenerateRecoveryCodes(r.Context(), userID, req.Code)
+	if err != nil {
+		writeTwoFactorError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(codes)
+}
+
+func writeTwoFactorError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, models.ErrInvalidTOTPCode):
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+	case errors.Is(err, models.ErrTwoFactorNotEnabled):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, models.ErrTwoFactorAlreadyEnabled):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -1,11 +1,13 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"log"
 	"net/http"
 	"strconv"
+	"time"
 
 	"workout/internal/models"
 	"workout/internal/repositories"
@@ -28,6 +30,10 @@ func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 
 	createdUser, err := h.Service.CreateUser(r.Context(), user)
 	if err != nil {
+		if errors.Is(err, models.ErrEmailTaken) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -55,6 +61,10 @@ func (h *UserHandler) SignUp(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "Неверный код подтверждения", http.StatusUnauthorized)
 			return
 		}
+		if errors.Is(err, models.ErrEmailTaken) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
 		log.Printf("SignUp error: %v", err)
 		http.Error(w, "Ошибка сервера", http.StatusInternalServerError)
 		return
@@ -65,6 +75,115 @@ func (h *UserHandler) SignUp(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(resp)
 }
 
+// CompleteSignIn exchanges a sign-in challenge and a two-factor code for tokens.
+func (h *UserHandler) CompleteSignIn(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ChallengeToken string `json:"challenge_token"`
+		Code           string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	tokens, err := h.Service.CompleteSignIn(r.Context(), req.ChallengeToken, req.Code, r.UserAgent(), clientIP(r))
+	if err != nil {
+		if errors.Is(err, models.ErrAccountSuspended) || errors.Is(err, models.ErrPasswordResetRequired) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		if errors.Is(err, models.ErrInvalidMFAChallenge) || errors.Is(err, models.ErrInvalidTOTPCode) || errors.Is(err, models.ErrTwoFactorNotEnabled) {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		if errors.Is(err, models.ErrTooManyAttempts) {
+			http.Error(w, err.Error(), http.StatusTooManyRequests)
+			return
+		}
+		log.Printf("CompleteSignIn error: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tokens)
+}
+
+// SendVerificationCode emails a one-time code used to confirm sign up or
+// changes of email and password.
+func (h *UserHandler) SendVerificationCode(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Email string `json:"email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Email == "" {
+		http.Error(w, "email required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Service.SendVerificationCode(r.Context(), req.Email); err != nil {
+		if errors.Is(err, models.ErrInvalidEmail) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, models.ErrVerificationCodeTooSoon) {
+			http.Error(w, err.Error(), http.StatusTooManyRequests)
+			return
+		}
+		log.Printf("SendVerificationCode error: %v", err)
+		http.Error(w, "failed to send verification code", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// RequestPasswordReset sends a password reset token to the given email.
+func (h *UserHandler) RequestPasswordReset(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Email string `json:"email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Email == "" {
+		http.Error(w, "email required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Service.RequestPasswordReset(r.Context(), req.Email); err != nil {
+		log.Printf("RequestPasswordReset error: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// ResetPassword sets a new password using a reset token.
+func (h *UserHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Token == "" || req.Password == "" {
+		http.Error(w, "token and password required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Service.ResetPassword(r.Context(), req.Token, req.Password); err != nil {
+		if errors.Is(err, models.ErrInvalidResetToken) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Printf("ResetPassword error: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
 func (h *UserHandler) SignIn(w http.ResponseWriter, r *http.Request) {
 	var req models.SignInRequest
 	err := json.NewDecoder(r.Body).Decode(&req)
@@ -73,10 +192,22 @@ func (h *UserHandler) SignIn(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	resp, err := h.Service.SignIn(r.Context(), req.Email, req.Password)
+	resp, err := h.Service.SignIn(r.Context(), req.Email, req.Password, r.UserAgent(), clientIP(r))
 	if err != nil {
-		log.Printf("error: %v", err)
-		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+		if errors.Is(err, models.ErrAccountSuspended) || errors.Is(err, models.ErrPasswordResetRequired) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		if errors.Is(err, models.ErrTooManyAttempts) {
+			http.Error(w, err.Error(), http.StatusTooManyRequests)
+			return
+		}
+		if errors.Is(err, models.ErrInvalidCredentials) {
+			http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+			return
+		}
+		log.Printf("SignIn error: %v", err)
+		http.Error(w, "Ошибка сервера", http.StatusInternalServerError)
 		return
 	}
 
@@ -85,19 +216,67 @@ func (h *UserHandler) SignIn(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(resp)
 }
 
-// UpgradeToTrainer upgrades the authenticated user to trainer role.
-func (h *UserHandler) UpgradeToTrainer(w http.ResponseWriter, r *http.Request) {
+// Refresh issues a new token pair in exchange for a refresh token.
+func (h *UserHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+		http.Error(w, "refresh_token required", http.StatusBadRequest)
+		return
+	}
+
+	tokens, err := h.Service.Refresh(r.Context(), req.RefreshToken)
+	if err != nil {
+		if errors.Is(err, models.ErrAccountSuspended) || errors.Is(err, models.ErrPasswordResetRequired) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		if errors.Is(err, models.ErrInvalidRefreshToken) || errors.Is(err, models.ErrRefreshTokenReused) {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		log.Printf("Refresh error: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tokens)
+}
+
+// Logout ends the current session.
+func (h *UserHandler) Logout(w http.ResponseWriter, r *http.Request) {
 	userID, ok := r.Context().Value("user_id").(int)
 	if !ok {
 		http.Error(w, "user id missing", http.StatusUnauthorized)
 		return
 	}
+	sessionID, _ := r.Context().Value("session_id").(int)
+	jti, _ := r.Context().Value("jti").(string)
+	expiresAt, _ := r.Context().Value("token_expires").(time.Time)
 
-	if err := h.Service.UpgradeToTrainer(r.Context(), userID); err != nil {
+	if err := h.Service.Logout(r.Context(), userID, sessionID, jti, expiresAt); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// LogoutEverywhere ends every session of the authenticated user.
+func (h *UserHandler) LogoutEverywhere(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(int)
+	if !ok {
+		http.Error(w, "user id missing", http.StatusUnauthorized)
+		return
+	}
+	jti, _ := r.Context().Value("jti").(string)
+	expiresAt, _ := r.Context().Value("token_expires").(time.Time)
 
+	if err := h.Service.LogoutEverywhere(r.Context(), userID, jti, expiresAt); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -122,8 +301,16 @@ func (h *UserHandler) GetClientsByProgramID(w http.ResponseWriter, r *http.Reque
 		http.Error(w, "program_id required", http.StatusBadRequest)
 		return
 	}
-	clients, err := h.Service.GetClientsByProgramID(r.Context(), programID)
+	clients, err := h.Service.GetClientsByProgramID(r.Context(), actorFromContext(r), programID)
 	if err != nil {
+		if errors.Is(err, models.ErrForbidden) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		if errors.Is(err, models.ErrWorkoutProgramNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -145,7 +332,15 @@ func (h *UserHandler) DeleteClientFromProgram(w http.ResponseWriter, r *http.Req
 		http.Error(w, "program_id and client_id required", http.StatusBadRequest)
 		return
 	}
-	if err := h.Service.DeleteClientFromProgram(r.Context(), programID, clientID); err != nil {
+	if err := h.Service.DeleteClientFromProgram(r.Context(), actorFromContext(r), programID, clientID); err != nil {
+		if errors.Is(err, models.ErrForbidden) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		if errors.Is(err, models.ErrWorkoutProgramNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -167,8 +362,12 @@ func (h *UserHandler) GetProgramsByClientID(w http.ResponseWriter, r *http.Reque
 		http.Error(w, "client_id required", http.StatusBadRequest)
 		return
 	}
-	programs, err := h.Service.GetProgramsByClientID(r.Context(), clientID)
+	programs, err := h.Service.GetProgramsByClientID(r.Context(), actorFromContext(r), clientID)
 	if err != nil {
+		if errors.Is(err, models.ErrForbidden) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -195,6 +394,14 @@ func (h *UserHandler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, err.Error(), http.StatusUnauthorized)
 			return
 		}
+		if errors.Is(err, models.ErrInvalidEmail) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, models.ErrEmailTaken) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
 		if errors.Is(err, repositories.ErrUserNotFound) || errors.Is(err, ErrUserNotFound) {
 			http.Error(w, "user not found", http.StatusNotFound)
 			return
@@ -206,3 +413,96 @@ func (h *UserHandler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(updated)
 }
+
+// ConfirmEmailChange applies a pending email change with the token sent to
+// the new address.
+func (h *UserHandler) ConfirmEmailChange(w http.ResponseWriter, r *http.Request) {
+	h.emailChangeToken(w, r, h.Service.ConfirmEmailChange)
+}
+
+// RevertEmailChange undoes an email change with the token sent to the old
+// address.
+func (h *UserHandler) RevertEmailChange(w http.ResponseWriter, r *http.Request) {
+	h.emailChangeToken(w, r, h.Service.RevertEmailChange)
+}
+
+func (h *UserHandler) emailChangeToken(w http.ResponseWriter, r *http.Request, apply func(context.Context, string) error) {
+	var req models.EmailTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+		http.Error(w, "token required", http.StatusBadRequest)
+		return
+	}
+	if err := apply(r.Context(), req.Token); err != nil {
+		switch {
+		case errors.Is(err, models.ErrInvalidEmailChangeToken):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, models.ErrEmailTaken):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Sessions lists the devices the authenticated user is signed in on.
+func (h *UserHandler) Sessions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(int)
+	if !ok {
+		http.Error(w, "user id missing", http.StatusUnauthorized)
+		return
+	}
+	currentID, _ := r.Context().Value("session_id").(int)
+
+	sessions, err := h.Service.Sessions(r.Context(), userID, currentID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sessions)
+}
+
+// RevokeSession signs the authenticated user out of one of their devices.
+func (h *UserHandler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(int)
+	if !ok {
+		http.Error(w, "user id missing", http.StatusUnauthorized)
+		return
+	}
+	id, _ := strconv.Atoi(r.URL.Query().Get(":id"))
+	if id == 0 {
+		http.Error(w, "id required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Service.RevokeSession(r.Context(), userID, id); err != nil {
+		if errors.Is(err, models.ErrSessionNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// UnlockUser lifts a sign-in lockout of a user account (admin only).
+func (h *UserHandler) UnlockUser(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(r.URL.Query().Get(":id"))
+	if id == 0 {
+		http.Error(w, "id required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Service.UnlockUser(r.Context(), id); err != nil {
+		if errors.Is(err, repositories.ErrUserNotFound) {
+			http.Error(w, "user not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
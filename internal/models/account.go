@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// AccountExport is everything stored about a user, returned on request.
+type AccountExport struct {
+	ExportedAt          time.Time            `json:"exported_at"`
+	Profile             User                 `json:"profile"`
+	Enrollments         []Enrollment         `json:"enrollments"`
+	Invites             []ProgramInvite      `json:"invites"`
+	Progress            []ProgramProgress    `json:"progress"`
+	Programs            []WorkOutProgram     `json:"programs"`
+	TrainerApplications []TrainerApplication `json:"trainer_applications"`
+	Sessions            []Session            `json:"sessions"`
+	APITokens           []APIToken           `json:"api_tokens"`
+	Identities          []UserIdentity       `json:"identities"`
+}
+
+// DeleteAccountRequest confirms an account deletion. Password is required
+// for accounts that have one.
+type DeleteAccountRequest struct {
+	Password string `json:"password"`
+}
+
+// AccountDeletion tells when a scheduled deletion takes effect.
+type AccountDeletion struct {
+	ScheduledAt time.Time `json:"scheduled_at"`
+}
@@ -0,0 +1,12 @@
+package models
+
+// Actor is the authenticated user on whose behalf a service call is made.
+type Actor struct {
+	UserID int
+	Role   string
+}
+
+// IsAdmin reports whether the actor may bypass ownership checks.
+func (a Actor) IsAdmin() bool {
+	return a.Role == "admin"
+}
@@ -0,0 +1,69 @@
+package models
+
+import "time"
+
+// Roles a user can have.
+const (
+	RoleClient  = "client"
+	RoleTrainer = "trainer"
+	RoleAdmin   = "admin"
+)
+
+// ValidRole reports whether role is one of the known roles.
+func ValidRole(role string) bool {
+	return role == RoleClient || role == RoleTrainer || role == RoleAdmin
+}
+
+// UserFilter narrows down the admin user listing.
+type UserFilter struct {
+	Query     string
+	Role      string
+	Suspended *bool
+	Page      int
+	PerPage   int
+}
+
+// UserList is a page of users.
+type UserList struct {
+	Users   []User `json:"users"`
+	Total   int    `json:"total"`
+	Page    int    `json:"page"`
+	PerPage int    `json:"per_page"`
+}
+
+// Enrollment is a program a client has joined through an invite.
+type Enrollment struct {
+	ProgramID     int        `json:"program_id"`
+	ProgramName   string     `json:"program_name"`
+	TrainerID     int        `json:"trainer_id"`
+	AcceptedAt    time.Time  `json:"accepted_at"`
+	AccessExpires *time.Time `json:"access_expires,omitempty"`
+}
+
+// UserDetails is the admin view of a user: the programs they own as a
+// trainer and the programs they are enrolled in as a client.
+type UserDetails struct {
+	User        User             `json:"user"`
+	Programs    []WorkOutProgram `json:"programs"`
+	Enrollments []Enrollment     `json:"enrollments"`
+}
+
+// ChangeRoleRequest is the payload for changing a user's role.
+type ChangeRoleRequest struct {
+	Role string `json:"role"`
+}
+
+// ImpersonateRequest is the payload for viewing the app as another user.
+// Reason, e.g. a support ticket, is kept in the audit log.
+type ImpersonateRequest struct {
+	Reason string `json:"reason"`
+}
+
+// ImpersonationToken is a short-lived access token acting as UserID on
+// behalf of ImpersonatorID. It cannot be refreshed.
+type ImpersonationToken struct {
+	AccessToken    string    `json:"access_token"`
+	UserID         int       `json:"user_id"`
+	ImpersonatorID int       `json:"impersonator_id"`
+	ExpiresAt      time.Time `json:"expires_at"`
+}
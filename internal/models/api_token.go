@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// Scopes that can be granted to personal access tokens.
+const (
+	ScopeProgramsRead  = "programs:read"
+	ScopeProgramsWrite = "programs:write"
+	ScopeClientsRead   = "clients:read"
+	ScopeClientsWrite  = "clients:write"
+	ScopeAnalyticsRead = "analytics:read"
+)
+
+// APITokenScopes lists every valid scope.
+var APITokenScopes = []string{ScopeProgramsRead, ScopeProgramsWrite, ScopeClientsRead, ScopeClientsWrite, ScopeAnalyticsRead}
+
+// APIToken is a long-lived personal access token used by integrations.
+// The token itself is only returned once, on creation.
+type APIToken struct {
+	ID         int        `json:"id"`
+	UserID     int        `json:"user_id"`
+	Name       string     `json:"name"`
+	Token      string     `json:"token,omitempty"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// HasScope reports whether the token was granted the scope.
+func (t APIToken) HasScope(scope string) bool {
+	for _, s := range t.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateAPITokenRequest is the payload for creating a personal access token.
+type CreateAPITokenRequest struct {
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays int      `json:"expires_in_days,omitempty"`
+}
@@ -0,0 +1,64 @@
+package models
+
+import "time"
+
+// Security-relevant events recorded in the audit log.
+const (
+	AuditSignIn            = "auth.sign_in"
+	AuditSignInFailed      = "auth.sign_in_failed"
+	AuditTokenRefresh      = "auth.token_refresh"
+	AuditRefreshTokenReuse = "auth.refresh_token_reuse"
+	AuditRoleChanged       = "user.role_changed"
+	AuditUserSuspended     = "user.suspended"
+	AuditUserReactivated   = "user.reactivated"
+	AuditPasswordReset     = "user.password_reset_forced"
+	AuditEmailChanged      = "user.email_changed"
+	AuditEmailReverted     = "user.email_reverted"
+	AuditImpersonation     = "admin.impersonation_started"
+	AuditImpersonatedCall  = "admin.impersonated_request"
+	AuditAccessChanged     = "invite.access_changed"
+	AuditClientRemoved     = "program.client_removed"
+)
+
+// Types of audit event targets.
+const (
+	AuditTargetUser    = "user"
+	AuditTargetProgram = "program"
+	AuditTargetInvite  = "invite"
+)
+
+// AuditEvent is an entry of the append-only audit log. ActorID is nil when
+// the actor is unknown, e.g. for failed sign-ins.
+type AuditEvent struct {
+	ID         int64                  `json:"id"`
+	Event      string                 `json:"event"`
+	ActorID    *int                   `json:"actor_id,omitempty"`
+	TargetType string                 `json:"target_type,omitempty"`
+	TargetID   *int                   `json:"target_id,omitempty"`
+	IPAddress  string                 `json:"ip_address"`
+	RequestID  string                 `json:"request_id"`
+	Details    map[string]interface{} `json:"details,omitempty"`
+	CreatedAt  time.Time              `json:"created_at"`
+}
+
+// AuditFilter narrows down the audit log listing.
+type AuditFilter struct {
+	Event      string
+	ActorID    int
+	TargetType string
+	TargetID   int
+	IPAddress  string
+	RequestID  string
+	From       *time.Time
+	To         *time.Time
+	Page       int
+	PerPage    int
+}
+
+// AuditList is a page of audit events.
+type AuditList struct {
+	Events  []AuditEvent `json:"events"`
+	Total   int          `json:"total"`
+	Page    int          `json:"page"`
+	PerPage int          `json:"per_page"`
+}
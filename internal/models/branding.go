@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// DefaultBrandColor is used on printouts of trainers without a color.
+const DefaultBrandColor = "#2f6fdf"
+
+// Branding is how a trainer's printed programs are labelled. Empty fields
+// fall back to the trainer's name and DefaultBrandColor.
+type Branding struct {
+	TrainerID int        `json:"trainer_id"`
+	BrandName string     `json:"brand_name"`
+	Color     string     `json:"color"`
+	LogoURL   string     `json:"logo_url,omitempty"`
+	Footer    string     `json:"footer,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
@@ -0,0 +1,55 @@
+package models
+
+import "time"
+
+// Program visibility. Public programs are listed in the catalog.
+const (
+	VisibilityPrivate = "private"
+	VisibilityPublic  = "public"
+)
+
+// MaxPreviewDays caps how many days a catalog entry may reveal.
+const MaxPreviewDays = 7
+
+// CatalogRequest is the body of PUT /program/:id/catalog. An empty Slug is
+// derived from the program name.
+type CatalogRequest struct {
+	Slug          string `json:"slug"`
+	CoverImageURL string `json:"cover_image_url"`
+	Summary       string `json:"summary"`
+	PreviewDays   int    `json:"preview_days"`
+}
+
+// CatalogProgram is a program as shown in the public catalog. It describes
+// the latest published version; Preview holds its first PreviewDays days.
+type CatalogProgram struct {
+	ID            int          `json:"id"`
+	Slug          string       `json:"slug"`
+	Name          string       `json:"name"`
+	Description   string       `json:"description"`
+	Summary       string       `json:"summary"`
+	CoverImageURL string       `json:"cover_image_url,omitempty"`
+	Days          int          `json:"days"`
+	Version       int          `json:"version"`
+	TrainerID     int          `json:"trainer_id"`
+	TrainerName   string       `json:"trainer_name"`
+	PreviewDays   int          `json:"preview_days"`
+	ListedAt      time.Time    `json:"listed_at"`
+	Preview       []DayDetails `json:"preview,omitempty"`
+}
+
+// CatalogFilter narrows down the catalog listing.
+type CatalogFilter struct {
+	Query     string
+	TrainerID int
+	Page      int
+	PerPage   int
+}
+
+// CatalogList is a page of the catalog.
+type CatalogList struct {
+	Programs []CatalogProgram `json:"programs"`
+	Total    int              `json:"total"`
+	Page     int              `json:"page"`
+	PerPage  int              `json:"per_page"`
+}
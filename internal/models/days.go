@@ -7,6 +7,7 @@ import (
 type Days struct {
 	ID               int        `json:"id"`
 	WorkOutProgramID int        `json:"work_out_program_id"`
+	ProgramVersionID *int       `json:"program_version_id,omitempty"`
 	DayNumber        int        `json:"day_number"`
 	ExercisesID      int        `json:"exercises_id"`
 	FoodID           int        `json:"food_id"`
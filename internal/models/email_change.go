@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// EmailChange is a pending or completed change of a user's email. The new
+// address confirms it with ConfirmToken; the old address can undo it with
+// RevertToken.
+type EmailChange struct {
+	ID              int        `json:"id"`
+	UserID          int        `json:"user_id"`
+	OldEmail        string     `json:"old_email"`
+	NewEmail        string     `json:"new_email"`
+	ConfirmToken    string     `json:"-"`
+	RevertToken     string     `json:"-"`
+	ExpiresAt       time.Time  `json:"expires_at"`
+	RevertExpiresAt time.Time  `json:"revert_expires_at"`
+	ConfirmedAt     *time.Time `json:"confirmed_at,omitempty"`
+	CancelledAt     *time.Time `json:"cancelled_at,omitempty"`
+	RevertedAt      *time.Time `json:"reverted_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+// ChangeEmailRequest starts an email change. Password is required when the
+// account has one.
+type ChangeEmailRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// EmailTokenRequest carries a confirm or revert token from an email.
+type EmailTokenRequest struct {
+	Token string `json:"token"`
+}
@@ -4,10 +4,73 @@ import "errors"
 
 var (
 	ErrInvalidVerificationCode = errors.New("invalid verification code")
+	ErrVerificationCodeTooSoon = errors.New("verification code was requested too recently")
 	ErrWorkoutProgramNotFound  = errors.New("workout program not found")
 	ErrExerciseNotFound        = errors.New("exercise not found")
 	ErrFoodNotFound            = errors.New("food not found")
 
+	ErrForbidden     = errors.New("access to this resource is forbidden")
+	ErrAccessExpired = errors.New("access to this program has expired")
+
 	ErrDayNotFound    = errors.New("day not found")
 	ErrInviteNotFound = errors.New("invite not found")
+	ErrInviteAccepted = errors.New("invite has already been accepted")
+	ErrInviteMismatch = errors.New("invite was sent to a different email address")
+	ErrInviteRevoked  = errors.New("invite has been revoked")
+
+	ErrVersionNotFound     = errors.New("program version not found")
+	ErrProgramNotPublished = errors.New("program has no published version")
+	ErrVersionImmutable    = errors.New("days of a published version cannot be changed")
+	ErrProgramHasClients   = errors.New("program has enrolled clients, remove them before deleting it")
+
+	ErrCatalogNotFound = errors.New("catalog program not found")
+	ErrSlugTaken       = errors.New("slug is already in use")
+	ErrInvalidCatalog  = errors.New("slug must be 3-100 lowercase letters, digits or hyphens and the cover image an http(s) URL")
+
+	ErrInvalidProgramDocument = errors.New("invalid program document")
+	ErrInvalidBranding        = errors.New("color must be #RRGGBB, the logo an http(s) URL, the brand name up to 255 and the footer up to 500 characters")
+
+	ErrSessionNotFound     = errors.New("session not found")
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected")
+	ErrInvalidResetToken   = errors.New("invalid or expired password reset token")
+
+	ErrInvalidTOTPCode         = errors.New("invalid two-factor code")
+	ErrTwoFactorNotEnabled     = errors.New("two-factor authentication is not enabled")
+	ErrTwoFactorAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrInvalidMFAChallenge     = errors.New("invalid or expired sign-in challenge")
+
+	ErrInvalidCredentials    = errors.New("invalid credentials")
+	ErrTooManyAttempts       = errors.New("too many failed attempts, try again later")
+	ErrAccountSuspended      = errors.New("account is suspended")
+	ErrPasswordResetRequired = errors.New("password reset is required before signing in")
+
+	ErrInvalidRole            = errors.New("invalid role")
+	ErrCannotAlterSelf        = errors.New("admins cannot suspend or demote themselves")
+	ErrCannotImpersonate      = errors.New("admins cannot impersonate themselves, other admins or suspended users")
+	ErrImpersonationForbidden = errors.New("this action is not allowed while impersonating a user")
+
+	ErrApplicationNotFound     = errors.New("trainer application not found")
+	ErrApplicationPending      = errors.New("a trainer application is already pending review")
+	ErrApplicationReviewed     = errors.New("trainer application has already been reviewed")
+	ErrAlreadyTrainer          = errors.New("user is already a trainer")
+	ErrApplicantNotClient      = errors.New("applicant is no longer a client")
+	ErrInvalidApplication      = errors.New("credentials and bio are required")
+	ErrRejectionReasonRequired = errors.New("a reason is required to reject an application")
+	ErrInvalidUpload           = errors.New("certificates must be PDF, JPEG or PNG files of up to 10 MB")
+
+	ErrDeletionNotScheduled = errors.New("account deletion is not scheduled")
+
+	ErrEmailTaken              = errors.New("email is already in use")
+	ErrInvalidEmail            = errors.New("invalid email address")
+	ErrInvalidEmailChangeToken = errors.New("invalid or expired email change token")
+
+	ErrAPITokenNotFound = errors.New("api token not found")
+	ErrInvalidAPIToken  = errors.New("invalid, expired or revoked api token")
+	ErrInvalidScope     = errors.New("invalid api token scope")
+
+	ErrUnknownOIDCProvider = errors.New("unknown identity provider")
+	ErrInvalidOIDCState    = errors.New("invalid or expired login state")
+	ErrInvalidIDToken      = errors.New("invalid id token")
+	ErrEmailNotVerified    = errors.New("email is not verified by the identity provider")
 )
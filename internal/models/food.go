@@ -6,6 +6,7 @@ import (
 
 type Food struct {
 	ID            int        `json:"id"`
+	TrainerID     *int       `json:"trainer_id,omitempty"`
 	Name          string     `json:"name"`
 	Description   string     `json:"description"`
 	Calories      float64    `json:"calories"`
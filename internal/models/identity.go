@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// UserIdentity links an account at an external OpenID Connect provider to a user.
+type UserIdentity struct {
+	ID        int       `json:"id"`
+	UserID    int       `json:"user_id"`
+	Provider  string    `json:"provider"`
+	Subject   string    `json:"subject"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// OIDCLoginState is kept between redirecting to a provider and its callback.
+type OIDCLoginState struct {
+	State        string
+	Provider     string
+	CodeVerifier string
+	Nonce        string
+	InviteToken  string
+	ExpiresAt    time.Time
+}
+
+// OIDCStartResponse holds the provider URL the client should open.
+type OIDCStartResponse struct {
+	AuthorizationURL string `json:"authorization_url"`
+	// State is bound to the browser with a cookie, not sent in the body
+	State string `json:"-"`
+}
@@ -6,6 +6,7 @@ import "time"
 type ProgramInvite struct {
 	ID            int        `json:"id"`
 	ProgramID     int        `json:"program_id"`
+	VersionID     *int       `json:"version_id,omitempty"`
 	Email         string     `json:"email"`
 	Message       string     `json:"message"`
 	AccessDays    int        `json:"access_days"`
@@ -13,6 +14,24 @@ type ProgramInvite struct {
 	ClientID      *int       `json:"client_id,omitempty"`
 	AcceptedAt    *time.Time `json:"accepted_at,omitempty"`
 	AccessExpires *time.Time `json:"access_expires,omitempty"`
+	ExpiredAt     *time.Time `json:"expired_at,omitempty"`
+	RevokedAt     *time.Time `json:"revoked_at,omitempty"`
 	CreatedAt     time.Time  `json:"created_at"`
 	UpdatedAt     *time.Time `json:"updated_at,omitempty"`
 }
+
+// ExpiredEnrollment is an accepted invite whose access window has passed and
+// whose trainer has not been notified yet.
+type ExpiredEnrollment struct {
+	InviteID      int
+	ProgramID     int
+	ProgramName   string
+	ClientID      int
+	ClientName    string
+	ClientEmail   string
+	TrainerEmail  string
+	TrainerName   string
+	AccessExpires time.Time
+	// NotifyAttempts counts the earlier failed notifications.
+	NotifyAttempts int
+}
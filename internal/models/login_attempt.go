@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// LoginAttempt counts recent failed sign-in attempts for an account or an IP address.
+type LoginAttempt struct {
+	Scope         string    `json:"scope"`
+	Subject       string    `json:"subject"`
+	Failures      int       `json:"failures"`
+	LastFailureAt time.Time `json:"last_failure_at"`
+}
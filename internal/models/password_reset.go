@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// PasswordReset is a single-use token allowing a user to set a new password.
+type PasswordReset struct {
+	ID        int        `json:"id"`
+	UserID    int        `json:"user_id"`
+	Token     string     `json:"-"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
@@ -0,0 +1,85 @@
+package models
+
+import "time"
+
+// Program documents are the portable JSON/YAML form of a program used by
+// export and import. A document looks like:
+//
+//	format: workout-program
+//	version: 1
+//	program:
+//	  name: Beginner strength
+//	  description: Three full-body days
+//	  days: 3
+//	exercises:
+//	  - key: squat
+//	    name: Back squat
+//	    sets: "3"
+//	    repetitions: "8"
+//	food:
+//	  - key: oats
+//	    name: Oatmeal
+//	    calories: 350
+//	days:
+//	  - day: 1
+//	    exercise: squat
+//	    food: oats
+//	    note: Warm up first
+//
+// Exercises and food are listed once and referenced from days by key. Keys
+// only need to be unique within their list of the document. Version is the
+// schema version and is bumped on incompatible changes; importers reject
+// versions newer than ProgramDocumentVersion.
+const (
+	ProgramDocumentFormat  = "workout-program"
+	ProgramDocumentVersion = 1
+)
+
+// ProgramDocument is a program with its days and the exercises and food
+// they reference.
+type ProgramDocument struct {
+	Format     string             `json:"format" yaml:"format"`
+	Version    int                `json:"version" yaml:"version"`
+	ExportedAt *time.Time         `json:"exported_at,omitempty" yaml:"exported_at,omitempty"`
+	Program    DocumentProgram    `json:"program" yaml:"program"`
+	Exercises  []DocumentExercise `json:"exercises" yaml:"exercises"`
+	Food       []DocumentFood     `json:"food" yaml:"food"`
+	Days       []DocumentDay      `json:"days" yaml:"days"`
+}
+
+// DocumentProgram holds the program fields of a document.
+type DocumentProgram struct {
+	Name        string `json:"name" yaml:"name"`
+	Description string `json:"description" yaml:"description"`
+	Days        int    `json:"days" yaml:"days"`
+}
+
+// DocumentExercise is an exercise of a document.
+type DocumentExercise struct {
+	Key         string `json:"key" yaml:"key"`
+	Name        string `json:"name" yaml:"name"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	MediaURL    string `json:"media_url,omitempty" yaml:"media_url,omitempty"`
+	Sets        string `json:"sets,omitempty" yaml:"sets,omitempty"`
+	Repetitions string `json:"repetitions,omitempty" yaml:"repetitions,omitempty"`
+}
+
+// DocumentFood is a food item of a document.
+type DocumentFood struct {
+	Key           string  `json:"key" yaml:"key"`
+	Name          string  `json:"name" yaml:"name"`
+	Description   string  `json:"description,omitempty" yaml:"description,omitempty"`
+	Calories      float64 `json:"calories" yaml:"calories"`
+	Protein       float64 `json:"protein" yaml:"protein"`
+	Fats          float64 `json:"fats" yaml:"fats"`
+	Carbohydrates float64 `json:"carbohydrates" yaml:"carbohydrates"`
+}
+
+// DocumentDay is a day of a document referencing an exercise and a food
+// item by key.
+type DocumentDay struct {
+	Day      int    `json:"day" yaml:"day"`
+	Exercise string `json:"exercise" yaml:"exercise"`
+	Food     string `json:"food" yaml:"food"`
+	Note     string `json:"note,omitempty" yaml:"note,omitempty"`
+}
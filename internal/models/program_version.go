@@ -0,0 +1,61 @@
+package models
+
+import "time"
+
+// ProgramVersion is a published, immutable snapshot of a program and its
+// days. Clients are pinned to a version; the trainer edits the draft.
+type ProgramVersion struct {
+	ID          int          `json:"id"`
+	ProgramID   int          `json:"program_id"`
+	Version     int          `json:"version"`
+	Name        string       `json:"name"`
+	Description string       `json:"description"`
+	Days        int          `json:"days"`
+	PublishedBy *int         `json:"published_by,omitempty"`
+	PublishedAt time.Time    `json:"published_at"`
+	Clients     int          `json:"clients"`
+	DayDetails  []DayDetails `json:"day_details,omitempty"`
+}
+
+// MigrateClientsRequest moves enrollments to a version. An empty ClientIDs
+// moves every client of the program.
+type MigrateClientsRequest struct {
+	ClientIDs []int `json:"client_ids"`
+}
+
+// MigrateClientsResult reports how many enrollments were moved.
+type MigrateClientsResult struct {
+	Version  int `json:"version"`
+	Migrated int `json:"migrated"`
+}
+
+// Kinds of day changes in a version diff.
+const (
+	DayAdded   = "added"
+	DayRemoved = "removed"
+	DayChanged = "changed"
+)
+
+// FieldChange is a field whose value differs between two versions.
+type FieldChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+// DayChange describes how a day, matched by day number, differs.
+type DayChange struct {
+	DayNumber int           `json:"day_number"`
+	Change    string        `json:"change"`
+	Fields    []FieldChange `json:"fields,omitempty"`
+}
+
+// ProgramVersionDiff lists the differences between two versions of a
+// program. To is zero when comparing against the draft.
+type ProgramVersionDiff struct {
+	ProgramID int           `json:"program_id"`
+	From      int           `json:"from"`
+	To        int           `json:"to"`
+	Fields    []FieldChange `json:"fields"`
+	Days      []DayChange   `json:"days"`
+}
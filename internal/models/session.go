@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// Session represents a single signed-in device of a user.
+type Session struct {
+	ID           int        `json:"id"`
+	UserID       int        `json:"user_id"`
+	RefreshToken string     `json:"-"`
+	AccessJTI    string     `json:"-"`
+	UserAgent    string     `json:"user_agent"`
+	IPAddress    string     `json:"ip_address"`
+	ExpiresAt    time.Time  `json:"expires_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+	LastUsedAt   *time.Time `json:"last_used_at,omitempty"`
+	Current      bool       `json:"current"`
+}
@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// Statuses of a trainer application.
+const (
+	ApplicationPending  = "pending"
+	ApplicationApproved = "approved"
+	ApplicationRejected = "rejected"
+)
+
+// TrainerApplication is a client's request to become a trainer.
+type TrainerApplication struct {
+	ID           int               `json:"id"`
+	UserID       int               `json:"user_id"`
+	UserName     string            `json:"user_name,omitempty"`
+	UserEmail    string            `json:"user_email,omitempty"`
+	Credentials  string            `json:"credentials"`
+	Bio          string            `json:"bio"`
+	Status       string            `json:"status"`
+	ReviewerID   *int              `json:"reviewer_id,omitempty"`
+	ReviewReason *string           `json:"review_reason,omitempty"`
+	ReviewedAt   *time.Time        `json:"reviewed_at,omitempty"`
+	Files        []ApplicationFile `json:"files,omitempty"`
+	CreatedAt    time.Time         `json:"created_at"`
+	UpdatedAt    *time.Time        `json:"updated_at,omitempty"`
+}
+
+// ApplicationFile is a certificate attached to a trainer application. Path
+// is relative to the private uploads directory and never exposed.
+type ApplicationFile struct {
+	ID            int       `json:"id"`
+	ApplicationID int       `json:"application_id"`
+	FileName      string    `json:"file_name"`
+	ContentType   string    `json:"content_type"`
+	Size          int64     `json:"size"`
+	Path          string    `json:"-"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// ReviewApplicationRequest is the payload for rejecting an application.
+type ReviewApplicationRequest struct {
+	Reason string `json:"reason"`
+}
@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// TwoFactor holds the TOTP settings of a user.
+type TwoFactor struct {
+	UserID       int        `json:"user_id"`
+	Secret       string     `json:"-"`
+	LastUsedStep int64      `json:"-"`
+	EnabledAt    *time.Time `json:"enabled_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// TOTPEnrollment is returned when a user starts setting up an authenticator app.
+type TOTPEnrollment struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+// RecoveryCodes are single-use codes that replace a TOTP code when the
+// authenticator is lost. They are shown only once.
+type RecoveryCodes struct {
+	Codes []string `json:"recovery_codes"`
+}
+
+// SignInResult is either a token pair or, for accounts with two-factor
+// authentication, a challenge that must be completed with a code first.
+type SignInResult struct {
+	*Tokens
+	MFARequired    bool   `json:"mfa_required,omitempty"`
+	ChallengeToken string `json:"challenge_token,omitempty"`
+}
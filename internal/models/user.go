@@ -3,23 +3,31 @@ package models
 import (
 	"time"
 
-	"github.com/dgrijalva/jwt-go"
+	"github.com/golang-jwt/jwt"
 )
 
 type User struct {
-	ID        int        `json:"id"`
-	Name      string     `json:"name"`
-	Phone     string     `json:"phone,omitempty"`
-	Email     string     `json:"email"`
-	Password  string     `json:"password"`
-	Role      string     `json:"role,omitempty"`
-	CreatedAt time.Time  `json:"created_at"`
-	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+	ID                    int        `json:"id"`
+	Name                  string     `json:"name"`
+	Phone                 string     `json:"phone,omitempty"`
+	Email                 string     `json:"email"`
+	Password              string     `json:"password"`
+	Role                  string     `json:"role,omitempty"`
+	SuspendedAt           *time.Time `json:"suspended_at,omitempty"`
+	PasswordResetRequired bool       `json:"password_reset_required,omitempty"`
+	DeletionScheduledAt   *time.Time `json:"deletion_scheduled_at,omitempty"`
+	PendingEmail          string     `json:"pending_email,omitempty"`
+	CreatedAt             time.Time  `json:"created_at"`
+	UpdatedAt             *time.Time `json:"updated_at,omitempty"`
 }
 
 type Claims struct {
-	UserID uint   `json:"user_id"`
-	Role   string `json:"role"`
+	UserID    uint   `json:"user_id"`
+	Role      string `json:"role"`
+	SessionID int    `json:"sid,omitempty"`
+	Purpose   string `json:"purpose,omitempty"`
+	// ImpersonatorID is the admin acting as the user, if any
+	ImpersonatorID uint `json:"imp,omitempty"`
 	jwt.StandardClaims
 }
 
@@ -28,11 +36,6 @@ type Tokens struct {
 	RefreshToken string
 }
 
-type Session struct {
-	RefreshToken string    `json:"refreshToken" bson:"refreshToken"`
-	ExpiresAt    time.Time `json:"expiresAt" bson:"expiresAt"`
-}
-
 type SignInRequest struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
@@ -61,10 +64,13 @@ type VerificationCodeEntry struct {
 	ID        int       `json:"id"`
 	Email     string    `json:"email"`
 	Code      string    `json:"code"`
+	Attempts  int       `json:"attempts"`
+	ExpiresAt time.Time `json:"expires_at"`
 	CreatedAt time.Time `json:"created_at"`
 }
 
-// UserUpdateRequest represents a profile update payload.
+// UserUpdateRequest represents a profile update payload. A new Email is not
+// applied directly but starts a change confirmed from the new address.
 type UserUpdateRequest struct {
 	Name             string `json:"name,omitempty"`
 	Phone            string `json:"phone,omitempty"`
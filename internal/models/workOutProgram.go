@@ -4,6 +4,9 @@ import (
 	"time"
 )
 
+// WorkOutProgram is a trainer's program. Version is the published version a
+// client sees and is zero for the trainer's draft. Visibility and Slug tell
+// whether and where the program is listed in the public catalog.
 type WorkOutProgram struct {
 	ID          int        `json:"id"`
 	TrainerID   int        `json:"trainer_id"`
@@ -12,6 +15,18 @@ type WorkOutProgram struct {
 	Description string     `json:"description"`
 	Duration    string     `json:"duration,omitempty"`
 	Clients     string     `json:"clients,omitempty"`
+	Version     int        `json:"version,omitempty"`
+	Visibility  string     `json:"visibility,omitempty"`
+	Slug        *string    `json:"slug,omitempty"`
 	CreatedAt   time.Time  `json:"created_at"`
 	UpdatedAt   *time.Time `json:"updated_at,omitempty"`
 }
+
+// CloneProgramRequest is the body of POST /program/:id/clone. An empty Name
+// keeps the source name with a "(copy)" suffix. With CopyResources the
+// exercises and food of the days are duplicated too, so editing them does
+// not change the source program.
+type CloneProgramRequest struct {
+	Name          string `json:"name"`
+	CopyResources bool   `json:"copy_resources"`
+}
@@ -0,0 +1,300 @@
+// Package oidc is a minimal OpenID Connect relying party: discovery, the
+// authorization code flow with PKCE and ID token verification.
+package oidc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"golang.org/x/oauth2"
+
+	"workout/internal/config"
+	"workout/utils"
+)
+
+const (
+	clockSkew        = time.Minute
+	jwksRefetchDelay = time.Minute
+)
+
+// Provider talks to one identity provider. Discovery happens lazily on first
+// use so the API starts even when the provider is temporarily unreachable.
+type Provider struct {
+	Name string
+
+	cfg    config.OIDCProvider
+	client *http.Client
+
+	mu        sync.Mutex
+	oauth     *oauth2.Config
+	jwksURI   string
+	keys      map[string]interface{}
+	fetchedAt time.Time
+}
+
+// IDToken holds the claims of a verified ID token that the API relies on.
+type IDToken struct {
+	Issuer        string   `json:"iss"`
+	Subject       string   `json:"sub"`
+	Audience      audience `json:"aud"`
+	ExpiresAt     int64    `json:"exp"`
+	IssuedAt      int64    `json:"iat"`
+	Nonce         string   `json:"nonce"`
+	Email         string   `json:"email"`
+	EmailVerified flexBool `json:"email_verified"`
+	Name          string   `json:"name"`
+}
+
+// Valid checks the time based claims; issuer, audience and nonce are checked by Verify.
+func (t *IDToken) Valid() error {
+	now := time.Now()
+	if t.ExpiresAt == 0 || now.After(time.Unix(t.ExpiresAt, 0).Add(clockSkew)) {
+		return errors.New("id token is expired")
+	}
+	if t.IssuedAt != 0 && time.Unix(t.IssuedAt, 0).After(now.Add(clockSkew)) {
+		return errors.New("id token is issued in the future")
+	}
+	return nil
+}
+
+// audience accepts both forms of "aud": a single string or an array.
+type audience []string
+
+func (a *audience) UnmarshalJSON(b []byte) error {
+	var single string
+	if err := json.Unmarshal(b, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(b, &many); err != nil {
+		return err
+	}
+	*a = many
+	return nil
+}
+
+// flexBool accepts "email_verified" as a boolean or as a string, as some providers send it.
+type flexBool bool
+
+func (f *flexBool) UnmarshalJSON(b []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	switch v := v.(type) {
+	case bool:
+		*f = flexBool(v)
+	case string:
+		*f = flexBool(v == "true")
+	}
+	return nil
+}
+
+// NewProvider creates a provider from its configuration.
+func NewProvider(cfg config.OIDCProvider) *Provider {
+	return &Provider{
+		Name:   cfg.Name,
+		cfg:    cfg,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+type discoveryDocument struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+func (p *Provider) discover(ctx context.Context) (*oauth2.Config, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.oauth != nil {
+		return p.oauth, nil
+	}
+
+	var doc discoveryDocument
+	if err := p.getJSON(ctx, strings.TrimSuffix(p.cfg.Issuer, "/")+"/.well-known/openid-configuration", &doc); err != nil {
+		return nil, fmt.Errorf("oidc discovery for %s: %v", p.Name, err)
+	}
+	if doc.Issuer != p.cfg.Issuer {
+		return nil, fmt.Errorf("oidc discovery for %s: issuer mismatch %q", p.Name, doc.Issuer)
+	}
+
+	scopes := p.cfg.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{"openid", "email", "profile"}
+	}
+	p.jwksURI = doc.JWKSURI
+	p.oauth = &oauth2.Config{
+		ClientID:     p.cfg.ClientID,
+		ClientSecret: p.cfg.ClientSecret,
+		RedirectURL:  p.cfg.RedirectURL,
+		Scopes:       scopes,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  doc.AuthorizationEndpoint,
+			TokenURL: doc.TokenEndpoint,
+		},
+	}
+	return p.oauth, nil
+}
+
+// AuthCodeURL returns the URL of the provider's login page. The code
+// challenge is derived from verifier (S256).
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	conf, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	return conf.AuthCodeURL(state, oauth2.S256ChallengeOption(verifier), oauth2.SetAuthURLParam("nonce", nonce)), nil
+}
+
+// Exchange redeems an authorization code and returns the verified ID token.
+func (p *Provider) Exchange(ctx context.Context, code, verifier, nonce string) (IDToken, error) {
+	conf, err := p.discover(ctx)
+	if err != nil {
+		return IDToken{}, err
+	}
+	token, err := conf.Exchange(context.WithValue(ctx, oauth2.HTTPClient, p.client), code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return IDToken{}, err
+	}
+	raw, ok := token.Extra("id_token").(string)
+	if !ok || raw == "" {
+		return IDToken{}, errors.New("token response has no id_token")
+	}
+	return p.Verify(ctx, raw, nonce)
+}
+
+// Verify checks the signature and claims of a raw ID token.
+func (p *Provider) Verify(ctx context.Context, raw, nonce string) (IDToken, error) {
+	claims := &IDToken{}
+	if _, err := jwt.ParseWithClaims(raw, claims, func(t *jwt.Token) (interface{}, error) {
+		return p.verificationKey(ctx, t)
+	}); err != nil {
+		return IDToken{}, err
+	}
+
+	if claims.Issuer != p.cfg.Issuer {
+		return IDToken{}, fmt.Errorf("unexpected issuer %q", claims.Issuer)
+	}
+	if !claims.Audience.contains(p.cfg.ClientID) {
+		return IDToken{}, errors.New("id token is not issued for this client")
+	}
+	if claims.Nonce != nonce {
+		return IDToken{}, errors.New("nonce mismatch")
+	}
+	return *claims, nil
+}
+
+func (a audience) contains(clientID string) bool {
+	for _, aud := range a {
+		if aud == clientID {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Provider) verificationKey(ctx context.Context, t *jwt.Token) (interface{}, error) {
+	kid, _ := t.Header["kid"].(string)
+	key, err := p.key(ctx, kid)
+	if err != nil {
+		return nil, err
+	}
+
+	// the algorithm must match the key type, "none" and HMAC are never accepted
+	switch t.Method.(type) {
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+		if _, ok := key.(*rsa.PublicKey); ok {
+			return key, nil
+		}
+	case *jwt.SigningMethodECDSA:
+		if _, ok := key.(*ecdsa.PublicKey); ok {
+			return key, nil
+		}
+	case *jwt.SigningMethodEd25519:
+		if _, ok := key.(ed25519.PublicKey); ok {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("unexpected signing method %v", t.Header["alg"])
+}
+
+// key returns the provider key with the given id, refetching the key set
+// when the id is unknown, which happens after the provider rotates keys.
+func (p *Provider) key(ctx context.Context, kid string) (interface{}, error) {
+	if _, err := p.discover(ctx); err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if key, ok := p.lookup(kid); ok {
+		return key, nil
+	}
+	if time.Since(p.fetchedAt) < jwksRefetchDelay {
+		return nil, fmt.Errorf("unknown key %q", kid)
+	}
+
+	var set utils.JWKS
+	if err := p.getJSON(ctx, p.jwksURI, &set); err != nil {
+		return nil, err
+	}
+	p.fetchedAt = time.Now()
+	p.keys = make(map[string]interface{}, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		pub, err := k.PublicKey()
+		if err != nil {
+			continue
+		}
+		p.keys[k.Kid] = pub
+	}
+
+	if key, ok := p.lookup(kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown key %q", kid)
+}
+
+// lookup finds a key by id; tokens without "kid" are accepted only when the
+// provider publishes a single key.
+func (p *Provider) lookup(kid string) (interface{}, bool) {
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key, true
+		}
+	}
+	key, ok := p.keys[kid]
+	return key, ok
+}
+
+func (p *Provider) getJSON(ctx context.Context, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
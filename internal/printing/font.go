@@ -0,0 +1,412 @@
+package printing
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sort"
+)
+
+// The PDF embeds DejaVu Sans, which covers Latin and Cyrillic including
+// Kazakh. See fonts/LICENSE.
+var (
+	//go:embed fonts/DejaVuSans.ttf
+	dejaVuSans []byte
+	//go:embed fonts/DejaVuSans-Bold.ttf
+	dejaVuSansBold []byte
+)
+
+// fonts are the parsed fonts by pdfFont.
+var fonts = [...]*trueType{
+	regular: mustParseTrueType("DejaVuSans", dejaVuSans),
+	bold:    mustParseTrueType("DejaVuSans-Bold", dejaVuSansBold),
+}
+
+var errBadFont = errors.New("printing: malformed TrueType font")
+
+// trueType is the part of a TrueType font needed to lay out text and embed
+// a subset of it in a PDF.
+type trueType struct {
+	name       string
+	tables     map[string][]byte
+	unitsPerEm int
+	numGlyphs  int
+	advances   []int
+	cmap       map[rune]uint16
+	longLoca   bool
+	// ascent, descent, capHeight and bbox are in font units
+	ascent, descent, capHeight int
+	bbox                       [4]int
+}
+
+func mustParseTrueType(name string, data []byte) *trueType {
+	f, err := parseTrueType(name, data)
+	if err != nil {
+		panic(fmt.Sprintf("%s: %v", name, err))
+	}
+	return f
+}
+
+func parseTrueType(name string, data []byte) (*trueType, error) {
+	if len(data) < 12 {
+		return nil, errBadFont
+	}
+	f := &trueType{name: name, tables: make(map[string][]byte)}
+	for i := 0; i < int(u16(data, 4)); i++ {
+		rec := 12 + 16*i
+		if rec+16 > len(data) {
+			return nil, errBadFont
+		}
+		off, length := int(u32(data, rec+8)), int(u32(data, rec+12))
+		if off+length > len(data) {
+			return nil, errBadFont
+		}
+		f.tables[string(data[rec:rec+4])] = data[off : off+length]
+	}
+	minLen := map[string]int{"head": 54, "hhea": 36, "maxp": 6, "hmtx": 4, "loca": 0, "glyf": 0, "cmap": 4}
+	for tag, n := range minLen {
+		if t, ok := f.tables[tag]; !ok || len(t) < n {
+			return nil, fmt.Errorf("%w: no %s table", errBadFont, tag)
+		}
+	}
+
+	head, hhea := f.tables["head"], f.tables["hhea"]
+	f.unitsPerEm = int(u16(head, 18))
+	for i := range f.bbox {
+		f.bbox[i] = int(int16(u16(head, 36+2*i)))
+	}
+	f.longLoca = u16(head, 50) == 1
+	f.numGlyphs = int(u16(f.tables["maxp"], 4))
+	f.ascent, f.descent = int(int16(u16(hhea, 4))), int(int16(u16(hhea, 6)))
+	if os2 := f.tables["OS/2"]; len(os2) >= 90 && u16(os2, 0) >= 2 {
+		f.capHeight = int(int16(u16(os2, 88)))
+	}
+	if f.unitsPerEm == 0 || f.numGlyphs == 0 {
+		return nil, errBadFont
+	}
+
+	locaLen := 2 * (f.numGlyphs + 1)
+	if f.longLoca {
+		locaLen *= 2
+	}
+	if len(f.tables["loca"]) < locaLen {
+		return nil, errBadFont
+	}
+
+	hmtx := f.tables["hmtx"]
+	metrics := int(u16(hhea, 34))
+	if metrics == 0 || metrics > f.numGlyphs || len(hmtx) < 4*metrics {
+		return nil, errBadFont
+	}
+	f.advances = make([]int, f.numGlyphs)
+	for g := range f.advances {
+		if g < metrics {
+			f.advances[g] = int(u16(hmtx, 4*g))
+		} else {
+			f.advances[g] = f.advances[metrics-1]
+		}
+	}
+
+	var err error
+	if f.cmap, err = parseCmap(f.tables["cmap"]); err != nil {
+		return nil, err
+	}
+	if _, ok := f.cmap['?']; !ok {
+		return nil, fmt.Errorf("%w: no glyph for '?'", errBadFont)
+	}
+	// older fonts have no cap height; take the top of H instead
+	if f.capHeight == 0 {
+		f.capHeight = f.ascent
+		if h := f.outline(f.cmap['H']); len(h) >= 10 {
+			f.capHeight = int(int16(u16(h, 8)))
+		}
+	}
+	return f, nil
+}
+
+// parseCmap reads the Unicode character map, preferring the full-range
+// format 12 table over the BMP-only format 4 one.
+func parseCmap(cmap []byte) (map[rune]uint16, error) {
+	var format4, format12 []byte
+	for i := 0; i < int(u16(cmap, 2)); i++ {
+		rec := 4 + 8*i
+		if rec+8 > len(cmap) {
+			return nil, errBadFont
+		}
+		platform, encoding, off := u16(cmap, rec), u16(cmap, rec+2), int(u32(cmap, rec+4))
+		if off+4 > len(cmap) {
+			return nil, errBadFont
+		}
+		sub := cmap[off:]
+		unicode := platform == 0 || (platform == 3 && (encoding == 1 || encoding == 10))
+		switch {
+		case unicode && u16(sub, 0) == 4:
+			format4 = sub
+		case unicode && u16(sub, 0) == 12:
+			format12 = sub
+		}
+	}
+
+	m := make(map[rune]uint16)
+	switch {
+	case format12 != nil:
+		if len(format12) < 16 {
+			return nil, errBadFont
+		}
+		groups := int(u32(format12, 12))
+		if len(format12) < 16+12*groups {
+			return nil, errBadFont
+		}
+		for i := 0; i < groups; i++ {
+			g := 16 + 12*i
+			start, end, glyph := u32(format12, g), u32(format12, g+4), u32(format12, g+8)
+			for c := start; c <= end && c <= 0x10ffff; c++ {
+				m[rune(c)] = uint16(glyph + c - start)
+			}
+		}
+	case format4 != nil:
+		if len(format4) < 14 {
+			return nil, errBadFont
+		}
+		segX2 := int(u16(format4, 6))
+		if len(format4) < 16+4*segX2 {
+			return nil, errBadFont
+		}
+		for i := 0; i < segX2; i += 2 {
+			end, start := u16(format4, 14+i), u16(format4, 16+segX2+i)
+			delta, rangeOff := u16(format4, 16+2*segX2+i), int(u16(format4, 16+3*segX2+i))
+			for c := int(start); c <= int(end) && c < 0xffff; c++ {
+				g := uint16(c) + delta
+				if rangeOff != 0 {
+					at := 16 + 3*segX2 + i + rangeOff + 2*(c-int(start))
+					if at+2 > len(format4) {
+						return nil, errBadFont
+					}
+					if g = u16(format4, at); g != 0 {
+						g += delta
+					}
+				}
+				if g != 0 {
+					m[rune(c)] = g
+				}
+			}
+		}
+	default:
+		return nil, fmt.Errorf("%w: no Unicode character map", errBadFont)
+	}
+	return m, nil
+}
+
+// printable returns r, or the character printed in its place when the font
+// has no glyph for it.
+func (f *trueType) printable(r rune) rune {
+	if r < 32 {
+		return ' '
+	}
+	if _, ok := f.cmap[r]; ok {
+		return r
+	}
+	return '?'
+}
+
+// glyph returns the glyph printed for r.
+func (f *trueType) glyph(r rune) uint16 {
+	return f.cmap[f.printable(r)]
+}
+
+// width returns the advance of glyph g in thousandths of the font size.
+func (f *trueType) width(g uint16) int {
+	return f.advances[g] * 1000 / f.unitsPerEm
+}
+
+// scale converts font units to thousandths of the font size.
+func (f *trueType) scale(v int) int {
+	return v * 1000 / f.unitsPerEm
+}
+
+// outline returns the glyf entry of glyph g.
+func (f *trueType) outline(g uint16) []byte {
+	if int(g) >= f.numGlyphs {
+		return nil
+	}
+	loca, glyf := f.tables["loca"], f.tables["glyf"]
+	var start, end int
+	if f.longLoca {
+		start, end = int(u32(loca, 4*int(g))), int(u32(loca, 4*int(g)+4))
+	} else {
+		start, end = 2*int(u16(loca, 2*int(g))), 2*int(u16(loca, 2*int(g)+2))
+	}
+	if start >= end || end > len(glyf) {
+		return nil
+	}
+	return glyf[start:end]
+}
+
+// components returns the glyphs a composite glyph is built from.
+func (f *trueType) components(g uint16) []uint16 {
+	data := f.outline(g)
+	if len(data) < 10 || int16(u16(data, 0)) >= 0 {
+		return nil
+	}
+	var glyphs []uint16
+	for p := 10; p+4 <= len(data); {
+		flags := u16(data, p)
+		glyphs = append(glyphs, u16(data, p+2))
+		p += 4
+		if flags&0x0001 != 0 {
+			p += 4
+		} else {
+			p += 2
+		}
+		switch {
+		case flags&0x0008 != 0:
+			p += 2
+		case flags&0x0040 != 0:
+			p += 4
+		case flags&0x0080 != 0:
+			p += 8
+		}
+		if flags&0x0020 == 0 {
+			break
+		}
+	}
+	return glyphs
+}
+
+// subset returns a copy of the font in which only the used glyphs and the
+// glyphs they are built from keep their outlines. Glyph numbers do not
+// change, so text can refer to the glyphs of the full font.
+func (f *trueType) subset(used map[uint16]rune) []byte {
+	keep := make(map[uint16]bool)
+	queue := []uint16{0}
+	for g := range used {
+		queue = append(queue, g)
+	}
+	for len(queue) > 0 {
+		g := queue[len(queue)-1]
+		queue = queue[:len(queue)-1]
+		if !keep[g] {
+			keep[g] = true
+			queue = append(queue, f.components(g)...)
+		}
+	}
+
+	var glyf []byte
+	loca := make([]byte, 4*(f.numGlyphs+1))
+	for g := 0; g < f.numGlyphs; g++ {
+		binary.BigEndian.PutUint32(loca[4*g:], uint32(len(glyf)))
+		if keep[uint16(g)] {
+			glyf = append(glyf, f.outline(uint16(g))...)
+		}
+	}
+	binary.BigEndian.PutUint32(loca[4*f.numGlyphs:], uint32(len(glyf)))
+
+	// the loca table is always written in the long format
+	head := append([]byte(nil), f.tables["head"]...)
+	binary.BigEndian.PutUint32(head[8:], 0)
+	binary.BigEndian.PutUint16(head[50:], 1)
+
+	// a version 3 post table leaves out the glyph names
+	var post []byte
+	if t := f.tables["post"]; len(t) >= 32 {
+		post = append([]byte(nil), t[:32]...)
+		binary.BigEndian.PutUint32(post, 0x00030000)
+	}
+
+	tables := map[string][]byte{"head": head, "hhea": f.tables["hhea"], "maxp": f.tables["maxp"],
+		"hmtx": f.tables["hmtx"], "loca": loca, "glyf": glyf}
+	if post != nil {
+		tables["post"] = post
+	}
+	// hinting programs are kept for the glyphs that use them, the character
+	// map keeps the subset a complete font
+	for _, tag := range []string{"cvt ", "fpgm", "prep", "cmap"} {
+		if t, ok := f.tables[tag]; ok {
+			tables[tag] = t
+		}
+	}
+	return writeTrueType(tables)
+}
+
+// subsetName is the PostScript name of a subset: a tag derived from the
+// glyphs it contains followed by the font name.
+func (f *trueType) subsetName(used map[uint16]rune) string {
+	h := fnv.New32a()
+	for _, g := range sortedGlyphs(used) {
+		h.Write([]byte{byte(g >> 8), byte(g)})
+	}
+	sum := h.Sum32()
+	tag := make([]byte, 6)
+	for i := range tag {
+		tag[i] = 'A' + byte(sum%26)
+		sum /= 26
+	}
+	return string(tag) + "+" + f.name
+}
+
+func writeTrueType(tables map[string][]byte) []byte {
+	tags := make([]string, 0, len(tables))
+	for tag := range tables {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	n := len(tags)
+	selector := 0
+	for 1<<(selector+1) <= n {
+		selector++
+	}
+	var buf bytes.Buffer
+	header := []uint16{1, 0, uint16(n), uint16(16 << selector), uint16(selector), uint16(16*n - 16<<selector)}
+	binary.Write(&buf, binary.BigEndian, header)
+
+	offset := 12 + 16*n
+	for _, tag := range tags {
+		t := tables[tag]
+		buf.WriteString(tag)
+		binary.Write(&buf, binary.BigEndian, []uint32{tableChecksum(t), uint32(offset), uint32(len(t))})
+		offset += (len(t) + 3) &^ 3
+	}
+	for _, tag := range tags {
+		t := tables[tag]
+		buf.Write(t)
+		buf.Write(make([]byte, (4-len(t)%4)%4))
+	}
+	return buf.Bytes()
+}
+
+func tableChecksum(t []byte) uint32 {
+	var sum uint32
+	for i := 0; i < len(t); i += 4 {
+		var word [4]byte
+		copy(word[:], t[i:])
+		sum += binary.BigEndian.Uint32(word[:])
+	}
+	return sum
+}
+
+func sortedGlyphs(used map[uint16]rune) []uint16 {
+	glyphs := make([]uint16, 0, len(used))
+	for g := range used {
+		glyphs = append(glyphs, g)
+	}
+	sort.Slice(glyphs, func(i, j int) bool { return glyphs[i] < glyphs[j] })
+	return glyphs
+}
+
+func u16(b []byte, off int) uint16 {
+	if off+2 > len(b) {
+		return 0
+	}
+	return binary.BigEndian.Uint16(b[off:])
+}
+
+func u32(b []byte, off int) uint32 {
+	if off+4 > len(b) {
+		return 0
+	}
+	return binary.BigEndian.Uint32(b[off:])
+}
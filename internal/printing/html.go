@@ -0,0 +1,108 @@
+package printing
+
+import (
+	"encoding/base64"
+	"html/template"
+	"io"
+)
+
+var htmlTemplate = template.Must(template.New("plan").Funcs(template.FuncMap{
+	"setsReps": setsReps,
+	"macros":   macros,
+	"mediaURL": mediaURL,
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Program.Name}}</title>
+<style>
+  body { font-family: Helvetica, Arial, sans-serif; color: #222; margin: 0 auto; max-width: 800px; padding: 24px; }
+  header { background: {{.Color}}; color: #fff; padding: 16px 24px; border-radius: 6px; display: flex; align-items: center; gap: 16px; }
+  header img { max-height: 56px; max-width: 160px; background: #fff; border-radius: 4px; }
+  header .brand { font-size: 14px; text-transform: uppercase; letter-spacing: .05em; opacity: .9; }
+  header h1 { margin: 4px 0 0; font-size: 26px; }
+  .meta { color: #666; margin: 12px 0; }
+  .day { border-top: 3px solid {{.Color}}; padding: 12px 0 16px; page-break-inside: avoid; break-inside: avoid; }
+  .day h2 { margin: 0 0 8px; color: {{.Color}}; }
+  .item { display: flex; gap: 12px; margin: 8px 0; }
+  .item img { width: 120px; height: 90px; object-fit: cover; border-radius: 4px; }
+  .label { font-size: 12px; color: #888; text-transform: uppercase; }
+  .name { font-weight: bold; }
+  .note { background: #f5f5f5; padding: 8px; border-radius: 4px; }
+  footer { color: #888; font-size: 12px; margin-top: 24px; border-top: 1px solid #ddd; padding-top: 8px; }
+  @media print { body { padding: 0; } header { -webkit-print-color-adjust: exact; print-color-adjust: exact; } }
+</style>
+</head>
+<body>
+<header>
+  {{with .Logo}}<img src="{{.}}" alt="">{{end}}
+  <div>
+    <div class="brand">{{.BrandName}}</div>
+    <h1>{{.Program.Name}}</h1>
+  </div>
+</header>
+<p class="meta">{{.Subtitle}}</p>
+{{with .Program.Description}}<p>{{.}}</p>{{end}}
+{{range .Days}}
+<section class="day">
+  <h2>Day {{.Day.DayNumber}}</h2>
+  <div class="item">
+    {{with $.Thumbnail .Exercise.MediaURL}}<img src="{{.}}" alt="">{{end}}
+    <div>
+      <div class="label">Exercise</div>
+      <div class="name">{{.Exercise.Name}}</div>
+      {{with setsReps .Exercise}}<div>{{.}}</div>{{end}}
+      {{with .Exercise.Description}}<p>{{.}}</p>{{end}}
+      {{with mediaURL .Exercise.MediaURL}}{{if not ($.Thumbnail .)}}<a href="{{.}}">Watch the exercise</a>{{end}}{{end}}
+    </div>
+  </div>
+  <div class="item">
+    <div>
+      <div class="label">Meal</div>
+      <div class="name">{{.Food.Name}}</div>
+      <div>{{macros .Food}}</div>
+      {{with .Food.Description}}<p>{{.}}</p>{{end}}
+    </div>
+  </div>
+  {{with .Day.Note}}<p class="note">{{.}}</p>{{end}}
+</section>
+{{end}}
+<footer>{{with .Branding.Footer}}{{.}} · {{end}}{{.TrainerName}}</footer>
+</body>
+</html>
+`))
+
+// htmlView adds the computed header fields to the plan.
+type htmlView struct {
+	Plan
+	BrandName string
+	Subtitle  string
+	Color     template.CSS
+	Logo      template.URL
+}
+
+// Thumbnail returns the embedded picture for a media URL as a data URI, or
+// an empty string if the plan has none.
+func (v htmlView) Thumbnail(raw string) template.URL {
+	img, ok := v.image(raw)
+	if !ok {
+		return ""
+	}
+	return dataURI(img)
+}
+
+func dataURI(img Image) template.URL {
+	return template.URL("data:image/" + img.Format + ";base64," + base64.StdEncoding.EncodeToString(img.Data))
+}
+
+// RenderHTML writes the plan as a standalone HTML page. Pictures are
+// embedded as data URIs, so the page loads nothing from elsewhere; media
+// without an embedded picture are shown as links.
+func RenderHTML(w io.Writer, p Plan) error {
+	// the color is validated as #RRGGBB when branding is saved
+	v := htmlView{Plan: p, BrandName: p.brandName(), Subtitle: p.subtitle(), Color: template.CSS(p.color())}
+	if img, ok := p.image(p.Branding.LogoURL); ok {
+		v.Logo = dataURI(img)
+	}
+	return htmlTemplate.Execute(w, v)
+}
@@ -0,0 +1,157 @@
+package printing
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	// maxImageBytes and maxImagePixels bound what is downloaded and decoded
+	// for a single picture
+	maxImageBytes  = 2 << 20
+	maxImagePixels = 4096 * 4096
+	// imageFetchTimeout bounds fetching all pictures of one printout
+	imageFetchTimeout = 5 * time.Second
+	imageFetchWorkers = 4
+)
+
+var errPrivateAddress = errors.New("address is not public")
+
+// Image is a picture downloaded to be embedded in a printout, so printouts
+// never load anything from the trainer's media hosts when opened.
+type Image struct {
+	Data []byte
+	// Format is jpeg, png or gif
+	Format        string
+	Width, Height int
+}
+
+// ImageFetcher downloads exercise pictures and logos. The URLs come from
+// trainers, so only public addresses are dialed and responses are capped.
+type ImageFetcher struct {
+	Client *http.Client
+}
+
+// NewImageFetcher returns a fetcher that refuses loopback, private and
+// link-local addresses, including after redirects.
+func NewImageFetcher() *ImageFetcher {
+	dialer := &net.Dialer{
+		Timeout: imageFetchTimeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !publicIP(ip) {
+				return errPrivateAddress
+			}
+			return nil
+		},
+	}
+	return &ImageFetcher{Client: &http.Client{
+		Timeout:   imageFetchTimeout,
+		Transport: &http.Transport{DialContext: dialer.DialContext},
+	}}
+}
+
+func publicIP(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsMulticast() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast())
+}
+
+// Fetch downloads a JPEG, PNG or GIF picture.
+func (f *ImageFetcher) Fetch(ctx context.Context, rawURL string) (Image, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return Image{}, err
+	}
+	resp, err := f.Client.Do(req)
+	if err != nil {
+		return Image{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Image{}, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxImageBytes+1))
+	if err != nil {
+		return Image{}, err
+	}
+	if len(data) > maxImageBytes {
+		return Image{}, errors.New("image is too large")
+	}
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return Image{}, err
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > maxImagePixels {
+		return Image{}, errors.New("image dimensions are out of range")
+	}
+	return Image{Data: data, Format: format, Width: cfg.Width, Height: cfg.Height}, nil
+}
+
+// FetchAll downloads the pictures at urls in parallel, keyed by URL.
+// Pictures that cannot be fetched in time are left out and printed as links.
+func (f *ImageFetcher) FetchAll(ctx context.Context, urls []string) map[string]Image {
+	ctx, cancel := context.WithTimeout(ctx, imageFetchTimeout)
+	defer cancel()
+
+	images := make(map[string]Image)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	queue := make(chan string)
+	for i := 0; i < imageFetchWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for u := range queue {
+				img, err := f.Fetch(ctx, u)
+				if err != nil {
+					log.Printf("Error fetching %s for printing: %v", u, err)
+					continue
+				}
+				mu.Lock()
+				images[u] = img
+				mu.Unlock()
+			}
+		}()
+	}
+	seen := make(map[string]bool)
+	for _, u := range urls {
+		if !seen[u] {
+			seen[u] = true
+			queue <- u
+		}
+	}
+	close(queue)
+	wg.Wait()
+	return images
+}
+
+// ImageURLs lists the pictures a printout of p shows: the branding logo and
+// exercise media that look like images.
+func ImageURLs(p Plan) []string {
+	var urls []string
+	if u := mediaURL(p.Branding.LogoURL); u != "" {
+		urls = append(urls, u)
+	}
+	for _, d := range p.Days {
+		if u := mediaURL(d.Exercise.MediaURL); u != "" && isImage(u) {
+			urls = append(urls, u)
+		}
+	}
+	return urls
+}
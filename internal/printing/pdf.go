@@ -0,0 +1,508 @@
+package printing
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf16"
+)
+
+// A4 page in points.
+const (
+	pageWidth  = 595.28
+	pageHeight = 841.89
+	margin     = 50.0
+	// footerHeight is kept free at the bottom of every page
+	footerHeight = 30.0
+	lineSpacing  = 1.4
+)
+
+type pdfFont int
+
+const (
+	regular pdfFont = iota + 1
+	bold
+)
+
+type color struct{ r, g, b float64 }
+
+var (
+	black = color{0.13, 0.13, 0.13}
+	gray  = color{0.45, 0.45, 0.45}
+	white = color{1, 1, 1}
+	blue  = color{0.1, 0.35, 0.8}
+)
+
+// pdfLine is a single line of text laid out on a page.
+type pdfLine struct {
+	font  pdfFont
+	size  float64
+	text  string
+	color color
+	link  string
+	// rule underlines the whole width of the page
+	rule bool
+	// gap is extra space above the line
+	gap float64
+	// image, if not zero, is the picture drawn instead of text, imageW by
+	// imageH points
+	image          int
+	imageW, imageH float64
+}
+
+func (l pdfLine) height() float64 {
+	if l.image != 0 {
+		return l.gap + l.imageH
+	}
+	return l.gap + l.size*lineSpacing
+}
+
+type pdfLink struct {
+	x, y, w, h float64
+	uri        string
+}
+
+type pdfPage struct {
+	content bytes.Buffer
+	links   []pdfLink
+	// used maps the glyphs printed in each font to the characters they stand for
+	used map[pdfFont]map[uint16]rune
+}
+
+// pdfDoc lays out text top to bottom and starts new pages as needed.
+type pdfDoc struct {
+	pages []*pdfPage
+	// y is the position of the cursor from the bottom of the page
+	y float64
+	// images are shared by all pages; image n is named /Im<n+1>
+	images   []pdfImage
+	imageIDs map[string]int
+}
+
+// addImage returns the number of the picture for url, converting it on
+// first use. Zero means the plan has no usable picture for it.
+func (d *pdfDoc) addImage(p Plan, url string) int {
+	if n, ok := d.imageIDs[url]; ok {
+		return n
+	}
+	n := 0
+	if img, ok := p.image(url); ok {
+		converted, err := newPDFImage(img)
+		if err == nil {
+			d.images = append(d.images, converted)
+			n = len(d.images)
+		}
+	}
+	if d.imageIDs == nil {
+		d.imageIDs = make(map[string]int)
+	}
+	d.imageIDs[url] = n
+	return n
+}
+
+func (d *pdfDoc) page() *pdfPage {
+	return d.pages[len(d.pages)-1]
+}
+
+func (d *pdfDoc) newPage() {
+	d.pages = append(d.pages, &pdfPage{})
+	d.y = pageHeight - margin
+}
+
+func (d *pdfDoc) fits(h float64) bool {
+	return d.y-h >= margin+footerHeight
+}
+
+func (p *pdfPage) text(x, y float64, f pdfFont, size float64, c color, s string) {
+	fmt.Fprintf(&p.content, "%.3f %.3f %.3f rg BT /F%d %.1f Tf %.2f %.2f Td <%s> Tj ET\n",
+		c.r, c.g, c.b, f, size, x, y, p.glyphs(f, s))
+}
+
+// glyphs encodes s as the hex glyph numbers of font f and notes the glyphs
+// as used.
+func (p *pdfPage) glyphs(f pdfFont, s string) string {
+	if p.used == nil {
+		p.used = make(map[pdfFont]map[uint16]rune)
+	}
+	if p.used[f] == nil {
+		p.used[f] = make(map[uint16]rune)
+	}
+	var b strings.Builder
+	for _, r := range s {
+		r = fonts[f].printable(r)
+		g := fonts[f].cmap[r]
+		p.used[f][g] = r
+		fmt.Fprintf(&b, "%04X", g)
+	}
+	return b.String()
+}
+
+func (p *pdfPage) rect(x, y, w, h float64, c color) {
+	fmt.Fprintf(&p.content, "%.3f %.3f %.3f rg %.2f %.2f %.2f %.2f re f\n", c.r, c.g, c.b, x, y, w, h)
+}
+
+func (p *pdfPage) image(n int, x, y, w, h float64) {
+	fmt.Fprintf(&p.content, "q %.2f 0 0 %.2f %.2f %.2f cm /Im%d Do Q\n", w, h, x, y, n)
+}
+
+func (p *pdfPage) rule(y float64, width float64, c color) {
+	fmt.Fprintf(&p.content, "%.3f %.3f %.3f RG %.2f w %.2f %.2f m %.2f %.2f l S\n",
+		c.r, c.g, c.b, width, margin, y, pageWidth-margin, y)
+}
+
+// write puts the lines at the cursor. A block that does not fit on the rest
+// of the page moves to a new one unless it is taller than a page anyway.
+func (d *pdfDoc) write(lines []pdfLine) {
+	var total float64
+	for _, l := range lines {
+		total += l.height()
+	}
+	if !d.fits(total) && total <= pageHeight-2*margin-footerHeight {
+		d.newPage()
+	}
+	for _, l := range lines {
+		if !d.fits(l.height()) {
+			d.newPage()
+		}
+		d.y -= l.height()
+		if l.image != 0 {
+			d.page().image(l.image, margin, d.y, l.imageW, l.imageH)
+			if l.link != "" {
+				d.page().links = append(d.page().links, pdfLink{margin, d.y, l.imageW, l.imageH, l.link})
+			}
+			continue
+		}
+		d.page().text(margin, d.y, l.font, l.size, l.color, l.text)
+		if l.rule {
+			d.page().rule(d.y-4, 1.5, l.color)
+		}
+		if l.link != "" {
+			d.page().links = append(d.page().links, pdfLink{margin, d.y - 2, textWidth(l.text, l.font, l.size), l.size + 2, l.link})
+		}
+	}
+}
+
+// paragraph wraps s to the page width.
+func paragraph(f pdfFont, size float64, c color, gap float64, s string) []pdfLine {
+	var lines []pdfLine
+	for i, text := range wrap(s, f, size, pageWidth-2*margin) {
+		l := pdfLine{font: f, size: size, text: text, color: c}
+		if i == 0 {
+			l.gap = gap
+		}
+		lines = append(lines, l)
+	}
+	return lines
+}
+
+// RenderPDF writes the plan as an A4 PDF with one block per day; a day is
+// not split across pages when it fits on one. The glyphs used are embedded
+// from DejaVu Sans, so Cyrillic text prints as written. Pictures are
+// embedded as images; other media are linked.
+func RenderPDF(w io.Writer, p Plan) error {
+	d := &pdfDoc{}
+	d.newPage()
+	r, g, b := rgb(p.color())
+	brand := color{r, g, b}
+
+	// header band with the logo on the right
+	d.page().rect(0, pageHeight-100, pageWidth, 100, brand)
+	titleWidth := pageWidth - 2*margin
+	if logo := d.addImage(p, p.Branding.LogoURL); logo != 0 {
+		lw, lh := fitBox(d.images[logo-1], 140, 56)
+		d.page().image(logo, pageWidth-margin-lw, pageHeight-50-lh/2, lw, lh)
+		titleWidth -= 150
+	}
+	d.page().text(margin, pageHeight-45, bold, 11, white, fit(strings.ToUpper(p.brandName()), bold, 11, titleWidth))
+	d.page().text(margin, pageHeight-75, bold, 22, white, fit(p.Program.Name, bold, 22, titleWidth))
+	d.y = pageHeight - 100
+	d.write(paragraph(regular, 10, gray, 8, p.subtitle()))
+	if p.Program.Description != "" {
+		d.write(paragraph(regular, 11, black, 6, p.Program.Description))
+	}
+
+	for _, day := range p.Days {
+		lines := []pdfLine{{font: bold, size: 15, text: fmt.Sprintf("Day %d", day.Day.DayNumber), color: brand, rule: true, gap: 18}}
+		lines = append(lines, pdfLine{font: regular, size: 8, text: "EXERCISE", color: gray, gap: 6})
+		lines = append(lines, paragraph(bold, 11, black, 0, day.Exercise.Name)...)
+		if sr := setsReps(day.Exercise); sr != "" {
+			lines = append(lines, pdfLine{font: regular, size: 10, text: sr, color: black})
+		}
+		if day.Exercise.Description != "" {
+			lines = append(lines, paragraph(regular, 10, black, 2, day.Exercise.Description)...)
+		}
+		if media := mediaURL(day.Exercise.MediaURL); media != "" {
+			if n := d.addImage(p, media); n != 0 {
+				iw, ih := fitBox(d.images[n-1], 120, 90)
+				lines = append(lines, pdfLine{image: n, imageW: iw, imageH: ih, link: media, gap: 4})
+			} else {
+				lines = append(lines, pdfLine{font: regular, size: 9, text: fit("Media: "+media, regular, 9, pageWidth-2*margin),
+					color: blue, link: media, gap: 2})
+			}
+		}
+		lines = append(lines, pdfLine{font: regular, size: 8, text: "MEAL", color: gray, gap: 8})
+		lines = append(lines, paragraph(bold, 11, black, 0, day.Food.Name)...)
+		lines = append(lines, pdfLine{font: regular, size: 10, text: macros(day.Food), color: black})
+		if day.Food.Description != "" {
+			lines = append(lines, paragraph(regular, 10, black, 2, day.Food.Description)...)
+		}
+		if day.Day.Note != "" {
+			lines = append(lines, paragraph(regular, 10, gray, 8, "Note: "+day.Day.Note)...)
+		}
+
+		d.write(lines)
+	}
+
+	footer := p.TrainerName
+	if p.Branding.Footer != "" {
+		footer = p.Branding.Footer + " · " + footer
+	}
+	for i, page := range d.pages {
+		num := fmt.Sprintf("Page %d of %d", i+1, len(d.pages))
+		page.rule(margin, 0.5, gray)
+		page.text(margin, margin-14, regular, 8, gray, fit(footer, regular, 8, pageWidth-2*margin-80))
+		page.text(pageWidth-margin-textWidth(num, regular, 8), margin-14, regular, 8, gray, num)
+	}
+
+	return writePDF(w, p.Program.Name, d.pages, d.images)
+}
+
+// writePDF serializes the pages with their content streams compressed.
+func writePDF(w io.Writer, title string, pages []*pdfPage, images []pdfImage) error {
+	var buf bytes.Buffer
+	var offsets []int
+	obj := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	used := make(map[pdfFont]map[uint16]rune)
+	for _, p := range pages {
+		for f, glyphs := range p.used {
+			if used[f] == nil {
+				used[f] = make(map[uint16]rune)
+			}
+			for g, r := range glyphs {
+				used[f][g] = r
+			}
+		}
+	}
+
+	// objects 1-5 are fixed, then every font takes four more objects, then
+	// come the images, then every page takes a page object, a content
+	// stream and one object per link
+	pdfFonts := []pdfFont{regular, bold}
+	const firstFontPart = 6
+	firstImage := firstFontPart + 4*len(pdfFonts)
+	firstPage := firstImage + len(images)
+	var kids []string
+	next := firstPage
+	for _, p := range pages {
+		kids = append(kids, fmt.Sprintf("%d 0 R", next))
+		next += 2 + len(p.links)
+	}
+	obj("<< /Type /Catalog /Pages 2 0 R >>")
+	obj(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+	for i, f := range pdfFonts {
+		part := firstFontPart + 4*i
+		obj(fmt.Sprintf("<< /Type /Font /Subtype /Type0 /BaseFont /%s /Encoding /Identity-H /DescendantFonts [%d 0 R] /ToUnicode %d 0 R >>",
+			fonts[f].subsetName(used[f]), part, part+3))
+	}
+	obj(fmt.Sprintf("<< /Title %s /Producer (workout) >>", pdfTextString(title)))
+
+	for i, f := range pdfFonts {
+		part := firstFontPart + 4*i
+		if err := writeFont(obj, part, fonts[f], used[f]); err != nil {
+			return err
+		}
+	}
+
+	var xobjects []string
+	for i, img := range images {
+		xobjects = append(xobjects, fmt.Sprintf("/Im%d %d 0 R", i+1, firstImage+i))
+		obj(fmt.Sprintf("<< /Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /%s /BitsPerComponent 8 /Filter /%s /Length %d >>\nstream\n%s\nendstream",
+			img.width, img.height, img.colorSpace, img.filter, len(img.data), img.data))
+	}
+
+	for _, p := range pages {
+		pageObj := len(offsets) + 1
+		var annots []string
+		for i := range p.links {
+			annots = append(annots, fmt.Sprintf("%d 0 R", pageObj+2+i))
+		}
+		obj(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> /XObject << %s >> >> /Contents %d 0 R /Annots [%s] >>",
+			pageWidth, pageHeight, strings.Join(xobjects, " "), pageObj+1, strings.Join(annots, " ")))
+
+		stream, err := deflate(p.content.Bytes())
+		if err != nil {
+			return err
+		}
+		obj(fmt.Sprintf("<< /Length %d /Filter /FlateDecode >>\nstream\n%s\nendstream", len(stream), stream))
+
+		for _, l := range p.links {
+			obj(fmt.Sprintf("<< /Type /Annot /Subtype /Link /Rect [%.2f %.2f %.2f %.2f] /Border [0 0 0] /A << /S /URI /URI (%s) >> >>",
+				l.x, l.y, l.x+l.w, l.y+l.h, pdfString(l.uri)))
+		}
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R /Info 5 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// writeFont writes the descendant font, descriptor, font file and ToUnicode
+// map of a Type0 font as objects first to first+3. Only the used glyphs are
+// embedded.
+func writeFont(obj func(string), first int, f *trueType, used map[uint16]rune) error {
+	name := f.subsetName(used)
+	glyphs := sortedGlyphs(used)
+
+	var widths strings.Builder
+	for _, g := range glyphs {
+		fmt.Fprintf(&widths, "%d [%d] ", g, f.width(g))
+	}
+	obj(fmt.Sprintf("<< /Type /Font /Subtype /CIDFontType2 /BaseFont /%s /CIDSystemInfo << /Registry (Adobe) /Ordering (Identity) /Supplement 0 >> /FontDescriptor %d 0 R /CIDToGIDMap /Identity /DW 1000 /W [%s] >>",
+		name, first+1, widths.String()))
+	obj(fmt.Sprintf("<< /Type /FontDescriptor /FontName /%s /Flags 32 /FontBBox [%d %d %d %d] /ItalicAngle 0 /Ascent %d /Descent %d /CapHeight %d /StemV 80 /FontFile2 %d 0 R >>",
+		name, f.scale(f.bbox[0]), f.scale(f.bbox[1]), f.scale(f.bbox[2]), f.scale(f.bbox[3]),
+		f.scale(f.ascent), f.scale(f.descent), f.scale(f.capHeight), first+2))
+
+	file := f.subset(used)
+	stream, err := deflate(file)
+	if err != nil {
+		return err
+	}
+	obj(fmt.Sprintf("<< /Length %d /Length1 %d /Filter /FlateDecode >>\nstream\n%s\nendstream", len(stream), len(file), stream))
+
+	var cmap bytes.Buffer
+	cmap.WriteString("/CIDInit /ProcSet findresource begin\n12 dict begin\nbegincmap\n" +
+		"/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def\n" +
+		"/CMapName /Adobe-Identity-UCS def\n/CMapType 2 def\n" +
+		"1 begincodespacerange\n<0000> <FFFF>\nendcodespacerange\n")
+	// a bfchar block holds at most 100 entries
+	for len(glyphs) > 0 {
+		n := min(len(glyphs), 100)
+		fmt.Fprintf(&cmap, "%d beginbfchar\n", n)
+		for _, g := range glyphs[:n] {
+			fmt.Fprintf(&cmap, "<%04X> <", g)
+			for _, u := range utf16.Encode([]rune{used[g]}) {
+				fmt.Fprintf(&cmap, "%04X", u)
+			}
+			cmap.WriteString(">\n")
+		}
+		cmap.WriteString("endbfchar\n")
+		glyphs = glyphs[n:]
+	}
+	cmap.WriteString("endcmap\nCMapName currentdict /CMap defineresource pop\nend\nend\n")
+	stream, err = deflate(cmap.Bytes())
+	if err != nil {
+		return err
+	}
+	obj(fmt.Sprintf("<< /Length %d /Filter /FlateDecode >>\nstream\n%s\nendstream", len(stream), stream))
+	return nil
+}
+
+func deflate(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// wrap breaks s into lines no wider than width. Newlines in s are kept.
+func wrap(s string, f pdfFont, size, width float64) []string {
+	var lines []string
+	for _, para := range strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n") {
+		line := ""
+		for _, word := range strings.Fields(para) {
+			candidate := word
+			if line != "" {
+				candidate = line + " " + word
+			}
+			if textWidth(candidate, f, size) <= width {
+				line = candidate
+				continue
+			}
+			if line != "" {
+				lines = append(lines, line)
+			}
+			// words longer than a line are broken anywhere
+			for textWidth(word, f, size) > width {
+				cut := len([]rune(fit(word, f, size, width))) - 1
+				if cut < 1 {
+					cut = 1
+				}
+				runes := []rune(word)
+				lines = append(lines, string(runes[:cut]))
+				word = string(runes[cut:])
+			}
+			line = word
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// fit shortens s with an ellipsis until it is no wider than width.
+func fit(s string, f pdfFont, size, width float64) string {
+	if textWidth(s, f, size) <= width {
+		return s
+	}
+	runes := []rune(s)
+	for len(runes) > 0 && textWidth(string(runes)+"…", f, size) > width {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "…"
+}
+
+// textWidth returns the printed width of s.
+func textWidth(s string, f pdfFont, size float64) float64 {
+	var units int
+	for _, r := range s {
+		units += fonts[f].width(fonts[f].glyph(r))
+	}
+	return float64(units) * size / 1000
+}
+
+// pdfString encodes s for a PDF string literal.
+func pdfString(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '(' || c == ')' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c < 32:
+			b.WriteByte(' ')
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// pdfTextString encodes s as a UTF-16 text string for document metadata.
+func pdfTextString(s string) string {
+	var b strings.Builder
+	b.WriteString("<FEFF")
+	for _, u := range utf16.Encode([]rune(s)) {
+		fmt.Fprintf(&b, "%04X", u)
+	}
+	b.WriteString(">")
+	return b.String()
+}
@@ -0,0 +1,85 @@
+package printing
+
+import (
+	"bytes"
+	"compress/zlib"
+	"image"
+	imgcolor "image/color"
+	"image/jpeg"
+)
+
+// maxPDFImageSide is the largest side of a decoded picture in the PDF;
+// thumbnails are printed a few centimetres wide, so more is wasted.
+const maxPDFImageSide = 600
+
+// pdfImage is a picture ready to be written as an image XObject.
+type pdfImage struct {
+	width, height int
+	colorSpace    string
+	filter        string
+	data          []byte
+}
+
+// newPDFImage converts a picture for the PDF. RGB and grayscale JPEGs are
+// embedded as they are; other pictures are decoded, flattened onto white,
+// scaled down and compressed.
+func newPDFImage(img Image) (pdfImage, error) {
+	if img.Format == "jpeg" {
+		cfg, err := jpeg.DecodeConfig(bytes.NewReader(img.Data))
+		if err != nil {
+			return pdfImage{}, err
+		}
+		switch cfg.ColorModel {
+		case imgcolor.YCbCrModel:
+			return pdfImage{cfg.Width, cfg.Height, "DeviceRGB", "DCTDecode", img.Data}, nil
+		case imgcolor.GrayModel:
+			return pdfImage{cfg.Width, cfg.Height, "DeviceGray", "DCTDecode", img.Data}, nil
+		}
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(img.Data))
+	if err != nil {
+		return pdfImage{}, err
+	}
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w > maxPDFImageSide || h > maxPDFImageSide {
+		if w >= h {
+			w, h = maxPDFImageSide, max(1, h*maxPDFImageSide/w)
+		} else {
+			w, h = max(1, w*maxPDFImageSide/h), maxPDFImageSide
+		}
+	}
+
+	var raw bytes.Buffer
+	raw.Grow(w * h * 3)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			// nearest neighbour is good enough for a thumbnail
+			r, g, bl, a := src.At(b.Min.X+x*b.Dx()/w, b.Min.Y+y*b.Dy()/h).RGBA()
+			// colors are premultiplied, so adding the missing alpha puts
+			// the pixel on white
+			r, g, bl = r+0xffff-a, g+0xffff-a, bl+0xffff-a
+			raw.Write([]byte{byte(r >> 8), byte(g >> 8), byte(bl >> 8)})
+		}
+	}
+
+	var out bytes.Buffer
+	zw := zlib.NewWriter(&out)
+	if _, err := zw.Write(raw.Bytes()); err != nil {
+		return pdfImage{}, err
+	}
+	if err := zw.Close(); err != nil {
+		return pdfImage{}, err
+	}
+	return pdfImage{w, h, "DeviceRGB", "FlateDecode", out.Bytes()}, nil
+}
+
+// fitBox scales a picture to fit in a w by h box keeping its proportions.
+func fitBox(img pdfImage, w, h float64) (float64, float64) {
+	scale := w / float64(img.width)
+	if s := h / float64(img.height); s < scale {
+		scale = s
+	}
+	return float64(img.width) * scale, float64(img.height) * scale
+}
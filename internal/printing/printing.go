@@ -0,0 +1,111 @@
+// Package printing renders programs as printable HTML pages and PDF files.
+package printing
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"workout/internal/models"
+)
+
+// Plan is a program prepared for printing.
+type Plan struct {
+	Program     models.WorkOutProgram
+	Days        []models.DayDetails
+	Branding    models.Branding
+	TrainerName string
+	GeneratedAt time.Time
+	// Images are the pictures embedded in the printout, keyed by URL; see
+	// ImageURLs
+	Images map[string]Image
+}
+
+// image returns the embedded picture for a media or logo URL.
+func (p Plan) image(raw string) (Image, bool) {
+	u := mediaURL(raw)
+	if u == "" {
+		return Image{}, false
+	}
+	img, ok := p.Images[u]
+	return img, ok
+}
+
+// brandName is the title printed on top of every plan.
+func (p Plan) brandName() string {
+	if p.Branding.BrandName != "" {
+		return p.Branding.BrandName
+	}
+	return p.TrainerName
+}
+
+func (p Plan) color() string {
+	if p.Branding.Color != "" {
+		return p.Branding.Color
+	}
+	return models.DefaultBrandColor
+}
+
+// rgb splits a #RRGGBB color into components between 0 and 1.
+func rgb(hex string) (r, g, b float64) {
+	v, err := strconv.ParseUint(strings.TrimPrefix(hex, "#"), 16, 32)
+	if err != nil {
+		return 0, 0, 0
+	}
+	return float64(v>>16&0xff) / 255, float64(v>>8&0xff) / 255, float64(v&0xff) / 255
+}
+
+// subtitle is the line under the program name.
+func (p Plan) subtitle() string {
+	s := fmt.Sprintf("%d days", p.Program.Days)
+	if p.Program.Version != 0 {
+		s += fmt.Sprintf(" · version %d", p.Program.Version)
+	}
+	return s + " · printed " + p.GeneratedAt.Format("2 Jan 2006")
+}
+
+func setsReps(ex models.Exercises) string {
+	var parts []string
+	if ex.Sets != "" {
+		parts = append(parts, "Sets: "+ex.Sets)
+	}
+	if ex.Repetitions != "" {
+		parts = append(parts, "Reps: "+ex.Repetitions)
+	}
+	return strings.Join(parts, "   ")
+}
+
+func macros(f models.Food) string {
+	return fmt.Sprintf("%s kcal · protein %s g · fats %s g · carbs %s g",
+		number(f.Calories), number(f.Protein), number(f.Fats), number(f.Carbohydrates))
+}
+
+func number(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// mediaURL returns the exercise media link if it is an http(s) URL.
+func mediaURL(raw string) string {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ""
+	}
+	return u.String()
+}
+
+// isImage reports whether a media URL looks like a picture that can be
+// embedded as a thumbnail.
+func isImage(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(path.Ext(u.Path)) {
+	case ".jpg", ".jpeg", ".png", ".gif":
+		return true
+	}
+	return false
+}
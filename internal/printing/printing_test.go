@@ -0,0 +1,256 @@
+package printing
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"image"
+	imgcolor "image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"workout/internal/models"
+)
+
+func testPicture(t *testing.T, format string) []byte {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, 800, 400))
+	for x := 0; x < 800; x++ {
+		img.Set(x, x%400, imgcolor.NRGBA{200, 30, 30, 255})
+	}
+	var buf bytes.Buffer
+	var err error
+	if format == "png" {
+		err = png.Encode(&buf, img)
+	} else {
+		err = jpeg.Encode(&buf, img, nil)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func testServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	pictures := map[string][]byte{
+		"/squat.png": testPicture(t, "png"),
+		"/logo.jpg":  testPicture(t, "jpeg"),
+		"/text.png":  []byte("not a picture"),
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, ok := pictures[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(data)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func testPlan(base string) Plan {
+	day := func(n int, media string) models.DayDetails {
+		return models.DayDetails{
+			Day:      models.Days{DayNumber: n},
+			Exercise: models.Exercises{Name: "Squat", Sets: "3", Repetitions: "10", MediaURL: media},
+			Food:     models.Food{Name: "Oats", Calories: 350},
+		}
+	}
+	return Plan{
+		Program:     models.WorkOutProgram{Name: "Strength", Days: 3},
+		Days:        []models.DayDetails{day(1, base+"/squat.png"), day(2, base+"/missing.png"), day(3, "https://example.com/video.mp4")},
+		Branding:    models.Branding{LogoURL: base + "/logo.jpg"},
+		TrainerName: "Trainer",
+	}
+}
+
+func TestFetchAll(t *testing.T) {
+	srv := testServer(t)
+	p := testPlan(srv.URL)
+	p.Days = append(p.Days, p.Days[0])
+
+	// the test server listens on loopback, which NewImageFetcher refuses
+	f := &ImageFetcher{Client: srv.Client()}
+	images := f.FetchAll(context.Background(), append(ImageURLs(p), srv.URL+"/text.png"))
+
+	tests := []struct {
+		url    string
+		format string
+	}{
+		{srv.URL + "/squat.png", "png"},
+		{srv.URL + "/logo.jpg", "jpeg"},
+		{srv.URL + "/missing.png", ""},
+		{srv.URL + "/text.png", ""},
+	}
+	for _, tt := range tests {
+		img, ok := images[tt.url]
+		if ok != (tt.format != "") || img.Format != tt.format {
+			t.Errorf("%s: got format %q, present %v; want %q", tt.url, img.Format, ok, tt.format)
+		}
+	}
+	if len(images) != 2 {
+		t.Errorf("got %d images, want 2", len(images))
+	}
+}
+
+func TestFetchRefusesPrivateAddresses(t *testing.T) {
+	srv := testServer(t)
+	if _, err := NewImageFetcher().Fetch(context.Background(), srv.URL+"/squat.png"); err == nil {
+		t.Fatal("fetching from loopback succeeded")
+	}
+}
+
+func TestRenderHTMLEmbedsPictures(t *testing.T) {
+	srv := testServer(t)
+	p := testPlan(srv.URL)
+	p.Images = (&ImageFetcher{Client: srv.Client()}).FetchAll(context.Background(), ImageURLs(p))
+
+	var out bytes.Buffer
+	if err := RenderHTML(&out, p); err != nil {
+		t.Fatal(err)
+	}
+	html := out.String()
+	if strings.Contains(html, `src="http`) {
+		t.Error("page loads a picture from a remote host")
+	}
+	for _, want := range []string{`src="data:image/png;base64,`, `src="data:image/jpeg;base64,`,
+		`href="` + srv.URL + `/missing.png"`, `href="https://example.com/video.mp4"`} {
+		if !strings.Contains(html, want) {
+			t.Errorf("page has no %s", want)
+		}
+	}
+}
+
+func TestRenderPDFEmbedsPictures(t *testing.T) {
+	srv := testServer(t)
+	p := testPlan(srv.URL)
+	p.Images = (&ImageFetcher{Client: srv.Client()}).FetchAll(context.Background(), ImageURLs(p))
+
+	var out bytes.Buffer
+	if err := RenderPDF(&out, p); err != nil {
+		t.Fatal(err)
+	}
+	pdf := out.Bytes()
+
+	for _, want := range []string{"/Filter /DCTDecode", "/ColorSpace /DeviceRGB", "/XObject << /Im1 14 0 R /Im2 15 0 R >>"} {
+		if !bytes.Contains(pdf, []byte(want)) {
+			t.Errorf("PDF has no %s", want)
+		}
+	}
+	if n := bytes.Count(pdf, []byte("/Subtype /Image")); n != 2 {
+		t.Errorf("got %d image objects, want 2", n)
+	}
+
+	// every xref entry must point at the start of its object
+	m := regexp.MustCompile(`(?s)xref\n0 (\d+)\n0000000000 65535 f \n(.*?)trailer`).FindSubmatch(pdf)
+	if m == nil {
+		t.Fatal("no xref table")
+	}
+	entries := strings.Split(strings.TrimSpace(string(m[2])), "\n")
+	if n, _ := strconv.Atoi(string(m[1])); n != len(entries)+1 {
+		t.Fatalf("xref declares %d entries, has %d", n, len(entries)+1)
+	}
+	for i, e := range entries {
+		off, _ := strconv.Atoi(e[:10])
+		if want := strconv.Itoa(i+1) + " 0 obj"; !bytes.HasPrefix(pdf[off:], []byte(want)) {
+			t.Errorf("xref entry %d does not point at %q", i+1, want)
+		}
+	}
+}
+
+func TestRenderPDFLinksMissingPictures(t *testing.T) {
+	var out bytes.Buffer
+	if err := RenderPDF(&out, testPlan("https://example.com")); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(out.Bytes(), []byte("/Subtype /Image")) {
+		t.Error("PDF embeds a picture that was never fetched")
+	}
+	if !bytes.Contains(out.Bytes(), []byte("/URI (https://example.com/squat.png)")) {
+		t.Error("PDF does not link the picture")
+	}
+}
+
+func TestFontSubset(t *testing.T) {
+	f := fonts[regular]
+	used := make(map[uint16]rune)
+	for _, r := range "Силовая программа: әлем, қымыз!" {
+		used[f.glyph(r)] = r
+	}
+	sub, err := parseTrueType("subset", f.subset(used))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sub.numGlyphs != f.numGlyphs {
+		t.Fatalf("subset has %d glyphs, want %d", sub.numGlyphs, f.numGlyphs)
+	}
+	for g, r := range used {
+		if !bytes.Equal(sub.outline(g), f.outline(g)) {
+			t.Errorf("outline of %q changed", r)
+		}
+	}
+	if unused := f.glyph('Z'); len(f.outline(unused)) == 0 || len(sub.outline(unused)) != 0 {
+		t.Error("subset keeps the outline of an unused glyph")
+	}
+}
+
+func TestTextWidth(t *testing.T) {
+	if w := textWidth("", regular, 10); w != 0 {
+		t.Errorf("empty text is %v wide", w)
+	}
+	if narrow, wide := textWidth("iii", regular, 10), textWidth("WWW", regular, 10); narrow >= wide {
+		t.Errorf("iii is %v wide, WWW %v", narrow, wide)
+	}
+	if regularW, boldW := textWidth("Программа", regular, 10), textWidth("Программа", bold, 10); regularW >= boldW {
+		t.Errorf("bold text is %v wide, regular %v", boldW, regularW)
+	}
+	if w, q := textWidth("中", regular, 10), textWidth("?", regular, 10); w != q {
+		t.Errorf("a missing character is %v wide, want the width of ? (%v)", w, q)
+	}
+}
+
+func TestRenderPDFPrintsCyrillic(t *testing.T) {
+	p := testPlan("https://example.com")
+	p.Program.Name = "Силовая программа"
+	p.Days[0].Food.Name = "Қымыз"
+
+	var out bytes.Buffer
+	if err := RenderPDF(&out, p); err != nil {
+		t.Fatal(err)
+	}
+	pdf := out.Bytes()
+	for _, want := range []string{"/Subtype /Type0", "/Encoding /Identity-H", "/Subtype /CIDFontType2", "/FontFile2"} {
+		if !bytes.Contains(pdf, []byte(want)) {
+			t.Errorf("PDF has no %s", want)
+		}
+	}
+
+	// the ToUnicode maps let viewers copy and search the text
+	var streams []byte
+	for _, m := range regexp.MustCompile(`(?s)/FlateDecode >>\nstream\n(.*?)\nendstream`).FindAllSubmatch(pdf, -1) {
+		zr, err := zlib.NewReader(bytes.NewReader(m[1]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(zr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		streams = append(streams, data...)
+	}
+	// С, п and Қ
+	for _, want := range []string{"> <0421>", "> <043F>", "> <049A>"} {
+		if !bytes.Contains(streams, []byte(want)) {
+			t.Errorf("no character map entry %s", want)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"workout/internal/models"
+)
+
+// AccountRepository gathers and erases the personal data of a user.
+type AccountRepository struct {
+	DB *sql.DB
+}
+
+// ScheduleDeletion sets when the account is to be deleted, or cancels the
+// deletion when at is nil.
+func (r *AccountRepository) ScheduleDeletion(ctx context.Context, userID int, at *time.Time) error {
+	_, err := r.DB.ExecContext(ctx, `UPDATE users SET deletion_scheduled_at = ? WHERE id = ? AND deleted_at IS NULL`, at, userID)
+	return err
+}
+
+// GetDueDeletions returns users whose grace period has ended.
+func (r *AccountRepository) GetDueDeletions(ctx context.Context, now time.Time, limit int) ([]int, error) {
+	rows, err := r.DB.QueryContext(ctx, `SELECT id FROM users
+        WHERE deletion_scheduled_at IS NOT NULL AND deletion_scheduled_at <= ? AND deleted_at IS NULL
+        ORDER BY deletion_scheduled_at LIMIT ?`, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// GetInvites lists invites accepted by the user or sent to their email.
+func (r *AccountRepository) GetInvites(ctx context.Context, userID int, email string) ([]models.ProgramInvite, error) {
+	rows, err := r.DB.QueryContext(ctx, `SELECT id, program_id, email, COALESCE(message, ''), access_days, client_id,
+        accepted_at, access_expires, expired_at, created_at, updated_at
+        FROM program_invites WHERE client_id = ? OR email = ? ORDER BY id`, userID, email)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []models.ProgramInvite{}
+	for rows.Next() {
+		var inv models.ProgramInvite
+		var clientID sql.NullInt64
+		if err := rows.Scan(&inv.ID, &inv.ProgramID, &inv.Email, &inv.Message, &inv.AccessDays, &clientID,
+			&inv.AcceptedAt, &inv.AccessExpires, &inv.ExpiredAt, &inv.CreatedAt, &inv.UpdatedAt); err != nil {
+			return nil, err
+		}
+		if clientID.Valid {
+			cid := int(clientID.Int64)
+			inv.ClientID = &cid
+		}
+		result = append(result, inv)
+	}
+	return result, rows.Err()
+}
+
+// GetProgress lists all progress records of the user.
+func (r *AccountRepository) GetProgress(ctx context.Context, userID int) ([]models.ProgramProgress, error) {
+	rows, err := r.DB.QueryContext(ctx, `SELECT id, client_id, day_id, food_completed, exercise_completed, completed
+        FROM progress WHERE client_id = ? ORDER BY id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []models.ProgramProgress{}
+	for rows.Next() {
+		var p models.ProgramProgress
+		if err := rows.Scan(&p.ID, &p.ClientID, &p.DayID, &p.FoodCompleted, &p.ExerciseCompleted, &p.Completed); err != nil {
+			return nil, err
+		}
+		result = append(result, p)
+	}
+	return result, rows.Err()
+}
+
+// GetIdentities lists the external identities linked to the user.
+func (r *AccountRepository) GetIdentities(ctx context.Context, userID int) ([]models.UserIdentity, error) {
+	rows, err := r.DB.QueryContext(ctx, `SELECT id, user_id, provider, subject, email, created_at
+        FROM user_identities WHERE user_id = ? ORDER BY id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []models.UserIdentity{}
+	for rows.Next() {
+		var id models.UserIdentity
+		if err := rows.Scan(&id.ID, &id.UserID, &id.Provider, &id.Subject, &id.Email, &id.CreatedAt); err != nil {
+			return nil, err
+		}
+		result = append(result, id)
+	}
+	return result, rows.Err()
+}
+
+// Anonymize erases the personal data of a user in one transaction. The user
+// row and progress records are kept under a placeholder identity so that
+// trainers' aggregate analytics stay correct; credentials, sessions, linked
+// identities and trainer applications are removed.
+func (r *AccountRepository) Anonymize(ctx context.Context, userID int, email string) error {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	placeholder := fmt.Sprintf("deleted-%d@deleted.invalid", userID)
+	statements := []struct {
+		query string
+		args  []interface{}
+	}{
+		{`UPDATE users SET name = 'Deleted user', phone = NULL, email = ?, password = '', suspended_at = ?,
+            password_reset_required = FALSE, deletion_scheduled_at = NULL, deleted_at = ? WHERE id = ?`,
+			[]interface{}{placeholder, now, now, userID}},
+		{`UPDATE workout_programs SET visibility = ?, catalog_listed_at = NULL WHERE trainer_id = ?`,
+			[]interface{}{models.VisibilityPrivate, userID}},
+		{`UPDATE program_invites SET email = ?, message = NULL WHERE client_id = ? OR email = ?`,
+			[]interface{}{placeholder, userID, email}},
+		{`DELETE FROM verification_codes WHERE email = ?`, []interface{}{email}},
+		{`DELETE FROM sessions WHERE user_id = ?`, []interface{}{userID}},
+		{`DELETE FROM api_tokens WHERE user_id = ?`, []interface{}{userID}},
+		{`DELETE FROM user_identities WHERE user_id = ?`, []interface{}{userID}},
+		{`DELETE FROM user_totp WHERE user_id = ?`, []interface{}{userID}},
+		{`DELETE FROM totp_recovery_codes WHERE user_id = ?`, []interface{}{userID}},
+		{`DELETE FROM password_resets WHERE user_id = ?`, []interface{}{userID}},
+		{`DELETE FROM email_changes WHERE user_id = ?`, []interface{}{userID}},
+		{`DELETE FROM trainer_applications WHERE user_id = ?`, []interface{}{userID}},
+		{`DELETE FROM trainer_branding WHERE trainer_id = ?`, []interface{}{userID}},
+	}
+	for _, st := range statements {
+		if _, err := tx.ExecContext(ctx, st.query, st.args...); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -30,12 +30,15 @@ func (r *AnalyticsRepository) TrainerAnalytics(ctx context.Context, trainerID in
 		return res, err
 	}
 
+	// only days of the version each client is pinned to count
 	rows, err := r.DB.QueryContext(ctx, `SELECT p.client_id,
         SUM(CASE WHEN p.completed IS NOT NULL THEN 1 ELSE 0 END) AS completed_days,
         COUNT(d.id) AS total_days
         FROM progress p
         JOIN days d ON p.day_id = d.id
         JOIN workout_programs wp ON d.work_out_program_id = wp.id
+        JOIN program_invites pi ON pi.program_id = wp.id AND pi.client_id = p.client_id
+            AND d.program_version_id <=> pi.version_id
         WHERE wp.trainer_id = ?
         GROUP BY p.client_id`, trainerID)
 	if err != nil {
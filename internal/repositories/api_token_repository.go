@@ -0,0 +1,114 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"workout/internal/models"
+	"workout/utils"
+)
+
+// APITokenRepository stores personal access tokens. Only token hashes are persisted.
+type APITokenRepository struct {
+	DB *sql.DB
+}
+
+func (r *APITokenRepository) CreateToken(ctx context.Context, t models.APIToken) (models.APIToken, error) {
+	t.CreatedAt = time.Now()
+	res, err := r.DB.ExecContext(ctx, `INSERT INTO api_tokens (user_id, name, token_hash, token_prefix, scopes, expires_at, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		t.UserID, t.Name, utils.HashToken(t.Token), t.Prefix, strings.Join(t.Scopes, ","), t.ExpiresAt, t.CreatedAt)
+	if err != nil {
+		return models.APIToken{}, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return models.APIToken{}, err
+	}
+	t.ID = int(id)
+	return t, nil
+}
+
+// GetTokenByValue looks up a token by its raw value.
+func (r *APITokenRepository) GetTokenByValue(ctx context.Context, token string) (models.APIToken, error) {
+	query := `SELECT id, user_id, name, token_prefix, scopes, expires_at, last_used_at, revoked_at, created_at FROM api_tokens WHERE token_hash = ?`
+	return scanAPIToken(r.DB.QueryRowContext(ctx, query, utils.HashToken(token)))
+}
+
+// GetTokensByUser lists all tokens of a user, including revoked ones.
+func (r *APITokenRepository) GetTokensByUser(ctx context.Context, userID int) ([]models.APIToken, error) {
+	rows, err := r.DB.QueryContext(ctx, `SELECT id, user_id, name, token_prefix, scopes, expires_at, last_used_at, revoked_at, created_at
+        FROM api_tokens WHERE user_id = ? ORDER BY created_at DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []models.APIToken{}
+	for rows.Next() {
+		t, err := scanAPIToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, t)
+	}
+	return result, rows.Err()
+}
+
+// TouchToken updates last_used_at, at most once a minute to keep writes low.
+func (r *APITokenRepository) TouchToken(ctx context.Context, id int) error {
+	now := time.Now()
+	_, err := r.DB.ExecContext(ctx, `UPDATE api_tokens SET last_used_at = ? WHERE id = ? AND (last_used_at IS NULL OR last_used_at < ?)`,
+		now, id, now.Add(-time.Minute))
+	return err
+}
+
+// RevokeTokensByUser revokes every active token of the user.
+func (r *APITokenRepository) RevokeTokensByUser(ctx context.Context, userID int) error {
+	_, err := r.DB.ExecContext(ctx, `UPDATE api_tokens SET revoked_at = ? WHERE user_id = ? AND revoked_at IS NULL`, time.Now(), userID)
+	return err
+}
+
+// RevokeToken revokes a token of the given user.
+func (r *APITokenRepository) RevokeToken(ctx context.Context, userID, id int) error {
+	res, err := r.DB.ExecContext(ctx, `UPDATE api_tokens SET revoked_at = ? WHERE id = ? AND user_id = ? AND revoked_at IS NULL`, time.Now(), id, userID)
+	if err != nil {
+		return err
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return models.ErrAPITokenNotFound
+	}
+	return nil
+}
+
+func scanAPIToken(row rowScanner) (models.APIToken, error) {
+	var t models.APIToken
+	var scopes string
+	var expires, lastUsed, revoked sql.NullTime
+	err := row.Scan(&t.ID, &t.UserID, &t.Name, &t.Prefix, &scopes, &expires, &lastUsed, &revoked, &t.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.APIToken{}, models.ErrAPITokenNotFound
+		}
+		return models.APIToken{}, err
+	}
+	t.Scopes = []string{}
+	if scopes != "" {
+		t.Scopes = strings.Split(scopes, ",")
+	}
+	if expires.Valid {
+		t.ExpiresAt = &expires.Time
+	}
+	if lastUsed.Valid {
+		t.LastUsedAt = &lastUsed.Time
+	}
+	if revoked.Valid {
+		t.RevokedAt = &revoked.Time
+	}
+	return t, nil
+}
@@ -0,0 +1,113 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strings"
+
+	"workout/internal/models"
+)
+
+// AuditRepository appends to and queries the audit log. Entries are never
+// updated or deleted.
+type AuditRepository struct {
+	DB *sql.DB
+}
+
+// InsertEvent appends an event to the audit log.
+func (r *AuditRepository) InsertEvent(ctx context.Context, e models.AuditEvent) error {
+	var details sql.NullString
+	if len(e.Details) > 0 {
+		b, err := json.Marshal(e.Details)
+		if err != nil {
+			return err
+		}
+		details = sql.NullString{String: string(b), Valid: true}
+	}
+	var targetType sql.NullString
+	if e.TargetType != "" {
+		targetType = sql.NullString{String: e.TargetType, Valid: true}
+	}
+	_, err := r.DB.ExecContext(ctx, `INSERT INTO audit_events (event, actor_id, target_type, target_id, ip_address, request_id, details, created_at)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		e.Event, e.ActorID, targetType, e.TargetID, e.IPAddress, e.RequestID, details, e.CreatedAt)
+	return err
+}
+
+// ListEvents returns a page of events matching the filter, newest first,
+// and the total number of matches.
+func (r *AuditRepository) ListEvents(ctx context.Context, f models.AuditFilter) ([]models.AuditEvent, int, error) {
+	var where []string
+	var args []interface{}
+	add := func(cond string, arg interface{}) {
+		where = append(where, cond)
+		args = append(args, arg)
+	}
+	if f.Event != "" {
+		add("event = ?", f.Event)
+	}
+	if f.ActorID != 0 {
+		add("actor_id = ?", f.ActorID)
+	}
+	if f.TargetType != "" {
+		add("target_type = ?", f.TargetType)
+	}
+	if f.TargetID != 0 {
+		add("target_id = ?", f.TargetID)
+	}
+	if f.IPAddress != "" {
+		add("ip_address = ?", f.IPAddress)
+	}
+	if f.RequestID != "" {
+		add("request_id = ?", f.RequestID)
+	}
+	if f.From != nil {
+		add("created_at >= ?", *f.From)
+	}
+	if f.To != nil {
+		add("created_at < ?", *f.To)
+	}
+	cond := ""
+	if len(where) > 0 {
+		cond = " WHERE " + strings.Join(where, " AND ")
+	}
+
+	var total int
+	if err := r.DB.QueryRowContext(ctx, `SELECT COUNT(*) FROM audit_events`+cond, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := r.DB.QueryContext(ctx, `SELECT id, event, actor_id, target_type, target_id, ip_address, request_id, details, created_at
+        FROM audit_events`+cond+` ORDER BY id DESC LIMIT ? OFFSET ?`, append(args, f.PerPage, (f.Page-1)*f.PerPage)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	events := []models.AuditEvent{}
+	for rows.Next() {
+		var e models.AuditEvent
+		var actorID, targetID sql.NullInt64
+		var targetType, details sql.NullString
+		if err := rows.Scan(&e.ID, &e.Event, &actorID, &targetType, &targetID, &e.IPAddress, &e.RequestID, &details, &e.CreatedAt); err != nil {
+			return nil, 0, err
+		}
+		if actorID.Valid {
+			id := int(actorID.Int64)
+			e.ActorID = &id
+		}
+		if targetID.Valid {
+			id := int(targetID.Int64)
+			e.TargetID = &id
+		}
+		e.TargetType = targetType.String
+		if details.Valid {
+			if err := json.Unmarshal([]byte(details.String), &e.Details); err != nil {
+				return nil, 0, err
+			}
+		}
+		events = append(events, e)
+	}
+	return events, total, rows.Err()
+}
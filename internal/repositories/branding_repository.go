@@ -0,0 +1,40 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"workout/internal/models"
+)
+
+// BrandingRepository stores the branding of trainers' printouts.
+type BrandingRepository struct {
+	DB *sql.DB
+}
+
+// GetBranding returns the trainer's branding. Trainers who never set it get
+// an empty one.
+func (r *BrandingRepository) GetBranding(ctx context.Context, trainerID int) (models.Branding, error) {
+	b := models.Branding{TrainerID: trainerID}
+	err := r.DB.QueryRowContext(ctx, `SELECT brand_name, color, COALESCE(logo_url, ''), footer, updated_at FROM trainer_branding WHERE trainer_id = ?`, trainerID).
+		Scan(&b.BrandName, &b.Color, &b.LogoURL, &b.Footer, &b.UpdatedAt)
+	if err != nil && err != sql.ErrNoRows {
+		return models.Branding{}, err
+	}
+	return b, nil
+}
+
+// SaveBranding creates or replaces the trainer's branding.
+func (r *BrandingRepository) SaveBranding(ctx context.Context, b models.Branding) (models.Branding, error) {
+	now := time.Now()
+	b.UpdatedAt = &now
+	_, err := r.DB.ExecContext(ctx, `INSERT INTO trainer_branding (trainer_id, brand_name, color, logo_url, footer, updated_at) VALUES (?, ?, ?, ?, ?, ?)
+        ON DUPLICATE KEY UPDATE brand_name = VALUES(brand_name), color = VALUES(color), logo_url = VALUES(logo_url),
+            footer = VALUES(footer), updated_at = VALUES(updated_at)`,
+		b.TrainerID, b.BrandName, b.Color, b.LogoURL, b.Footer, now)
+	if err != nil {
+		return models.Branding{}, err
+	}
+	return b, nil
+}
@@ -13,9 +13,13 @@ type DayRepository struct {
 	DB *sql.DB
 }
 
-func (r *DayRepository) GetDayDetails(ctx context.Context, programID, dayNumber int) (models.DayDetails, error) {
+// GetDayDetails returns a day of a published version, or of the draft when
+// versionID is nil.
+func (r *DayRepository) GetDayDetails(ctx context.Context, programID, dayNumber int, versionID *int) (models.DayDetails, error) {
 	var d models.Days
-	err := r.DB.QueryRowContext(ctx, `SELECT id, work_out_program_id, day_number, exercises_id, food_id, note, created_at, updated_at FROM days WHERE work_out_program_id=? AND day_number=?`, programID, dayNumber).Scan(&d.ID, &d.WorkOutProgramID, &d.DayNumber, &d.ExercisesID, &d.FoodID, &d.Note, &d.CreatedAt, &d.UpdatedAt)
+	err := r.DB.QueryRowContext(ctx, `SELECT id, work_out_program_id, program_version_id, day_number, exercises_id, food_id, note, created_at, updated_at
+        FROM days WHERE work_out_program_id=? AND program_version_id <=> ? AND day_number=?`, programID, versionID, dayNumber).
+		Scan(&d.ID, &d.WorkOutProgramID, &d.ProgramVersionID, &d.DayNumber, &d.ExercisesID, &d.FoodID, &d.Note, &d.CreatedAt, &d.UpdatedAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return models.DayDetails{}, models.ErrDayNotFound
@@ -123,7 +127,8 @@ func (r *DayRepository) GetProgress(ctx context.Context, clientID, dayID int) (m
 	return prog, nil
 }
 
-// GetProgramProgress returns progress info for all days in a program for a client.
+// GetProgramProgress returns progress info for all days of the program
+// version the client is pinned to.
 func (r *DayRepository) GetProgramProgress(ctx context.Context, clientID, programID int) ([]models.DayProgressStatus, error) {
 	rows, err := r.DB.QueryContext(ctx, `SELECT d.id, d.day_number,
         COALESCE(p.food_completed, FALSE),
@@ -131,8 +136,9 @@ func (r *DayRepository) GetProgramProgress(ctx context.Context, clientID, progra
         p.completed
         FROM days d
         LEFT JOIN progress p ON p.day_id = d.id AND p.client_id = ?
-        WHERE d.work_out_program_id = ?
-        ORDER BY d.day_number`, clientID, programID)
+        WHERE d.work_out_program_id = ? AND d.program_version_id <=> (SELECT pi.version_id FROM program_invites pi
+            WHERE pi.client_id = ? AND pi.program_id = ? AND pi.accepted_at IS NOT NULL LIMIT 1)
+        ORDER BY d.day_number`, clientID, programID, clientID, programID)
 	if err != nil {
 		return nil, err
 	}
@@ -193,15 +199,17 @@ func (r *DayRepository) CreateDay(ctx context.Context, day models.Days) (models.
 	return day, nil
 }
 
-func (r *DayRepository) DaysByProgram(ctx context.Context, programID int) ([]models.DayDetails, error) {
-	rows, err := r.DB.QueryContext(ctx, `SELECT d.id, d.work_out_program_id, d.day_number, d.exercises_id, d.food_id, d.note,
+// DaysByProgram lists the days of a published version, or of the draft when
+// versionID is nil.
+func (r *DayRepository) DaysByProgram(ctx context.Context, programID int, versionID *int) ([]models.DayDetails, error) {
+	rows, err := r.DB.QueryContext(ctx, `SELECT d.id, d.work_out_program_id, d.program_version_id, d.day_number, d.exercises_id, d.food_id, d.note,
                 d.created_at, d.updated_at,
                 e.id, e.name, e.description, e.media_url, e.sets, e.repetitions, e.created_at, e.updated_at,
                 f.id, f.name, f.description, f.calories, f.protein, f.fats, f.carbohydrates, f.created_at, f.updated_at
                 FROM days d
                 JOIN exercises e ON d.exercises_id = e.id
                 JOIN food f ON d.food_id = f.id
-                WHERE d.work_out_program_id = ? ORDER BY d.day_number`, programID)
+                WHERE d.work_out_program_id = ? AND d.program_version_id <=> ? ORDER BY d.day_number`, programID, versionID)
 	if err != nil {
 		return nil, err
 	}
@@ -212,7 +220,7 @@ func (r *DayRepository) DaysByProgram(ctx context.Context, programID int) ([]mod
 		var d models.Days
 		var ex models.Exercises
 		var food models.Food
-		err = rows.Scan(&d.ID, &d.WorkOutProgramID, &d.DayNumber, &d.ExercisesID, &d.FoodID, &d.Note,
+		err = rows.Scan(&d.ID, &d.WorkOutProgramID, &d.ProgramVersionID, &d.DayNumber, &d.ExercisesID, &d.FoodID, &d.Note,
 			&d.CreatedAt, &d.UpdatedAt,
 			&ex.ID, &ex.Name, &ex.Description, &ex.MediaURL, &ex.Sets, &ex.Repetitions, &ex.CreatedAt, &ex.UpdatedAt,
 			&food.ID, &food.Name, &food.Description, &food.Calories, &food.Protein, &food.Fats, &food.Carbohydrates, &food.CreatedAt, &food.UpdatedAt)
@@ -224,7 +232,7 @@ func (r *DayRepository) DaysByProgram(ctx context.Context, programID int) ([]mod
 	return result, rows.Err()
 }
 
-// UpdateDay updates a workout day by its ID.
+// UpdateDay updates a draft workout day by its ID.
 func (r *DayRepository) UpdateDay(ctx context.Context, day models.Days) (models.Days, error) {
 	// ensure referenced records exist to avoid foreign key violations
 	var exists bool
@@ -251,7 +259,7 @@ func (r *DayRepository) UpdateDay(ctx context.Context, day models.Days) (models.
 
 	now := time.Now()
 	day.UpdatedAt = &now
-	res, err := r.DB.ExecContext(ctx, `UPDATE days SET work_out_program_id = ?, day_number = ?, exercises_id = ?, food_id = ?, note = ?, updated_at = ? WHERE id = ?`,
+	res, err := r.DB.ExecContext(ctx, `UPDATE days SET work_out_program_id = ?, day_number = ?, exercises_id = ?, food_id = ?, note = ?, updated_at = ? WHERE id = ? AND program_version_id IS NULL`,
 		day.WorkOutProgramID, day.DayNumber, day.ExercisesID, day.FoodID, day.Note, day.UpdatedAt, day.ID)
 	if err != nil {
 		return models.Days{}, err
@@ -266,9 +274,9 @@ func (r *DayRepository) UpdateDay(ctx context.Context, day models.Days) (models.
 	return day, nil
 }
 
-// DeleteDay removes a workout day by its ID.
+// DeleteDay removes a draft workout day by its ID.
 func (r *DayRepository) DeleteDay(ctx context.Context, id int) error {
-	res, err := r.DB.ExecContext(ctx, `DELETE FROM days WHERE id = ?`, id)
+	res, err := r.DB.ExecContext(ctx, `DELETE FROM days WHERE id = ? AND program_version_id IS NULL`, id)
 	if err != nil {
 		return err
 	}
@@ -281,3 +289,35 @@ func (r *DayRepository) DeleteDay(ctx context.Context, id int) error {
 	}
 	return nil
 }
+
+// GetDayProgramID returns the program a day belongs to.
+func (r *DayRepository) GetDayProgramID(ctx context.Context, dayID int) (int, error) {
+	var programID int
+	err := r.DB.QueryRowContext(ctx, `SELECT work_out_program_id FROM days WHERE id = ?`, dayID).Scan(&programID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, models.ErrDayNotFound
+		}
+		return 0, err
+	}
+	return programID, nil
+}
+
+// GetDayVersion returns the program of a day and the published version it
+// belongs to, nil for a draft day.
+func (r *DayRepository) GetDayVersion(ctx context.Context, dayID int) (int, *int, error) {
+	var programID int
+	var versionID sql.NullInt64
+	err := r.DB.QueryRowContext(ctx, `SELECT work_out_program_id, program_version_id FROM days WHERE id = ?`, dayID).Scan(&programID, &versionID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, nil, models.ErrDayNotFound
+		}
+		return 0, nil, err
+	}
+	if !versionID.Valid {
+		return programID, nil, nil
+	}
+	id := int(versionID.Int64)
+	return programID, &id, nil
+}
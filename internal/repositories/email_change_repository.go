@@ -0,0 +1,166 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"workout/internal/models"
+	"workout/utils"
+)
+
+// EmailChangeRepository stores email changes. Tokens are kept hashed.
+type EmailChangeRepository struct {
+	DB *sql.DB
+}
+
+// CreateChange stores a new pending change and cancels any earlier pending
+// change of the user, so only the latest confirm token works.
+func (r *EmailChangeRepository) CreateChange(ctx context.Context, c models.EmailChange) (models.EmailChange, error) {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return models.EmailChange{}, err
+	}
+
+	c.CreatedAt = time.Now()
+	_, err = tx.ExecContext(ctx, `UPDATE email_changes SET cancelled_at = ?
+        WHERE user_id = ? AND confirmed_at IS NULL AND cancelled_at IS NULL AND reverted_at IS NULL`, c.CreatedAt, c.UserID)
+	if err != nil {
+		tx.Rollback()
+		return models.EmailChange{}, err
+	}
+	res, err := tx.ExecContext(ctx, `INSERT INTO email_changes
+        (user_id, old_email, new_email, confirm_token_hash, revert_token_hash, expires_at, revert_expires_at, created_at)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		c.UserID, c.OldEmail, c.NewEmail, utils.HashToken(c.ConfirmToken), utils.HashToken(c.RevertToken),
+		c.ExpiresAt, c.RevertExpiresAt, c.CreatedAt)
+	if err != nil {
+		tx.Rollback()
+		return models.EmailChange{}, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		tx.Rollback()
+		return models.EmailChange{}, err
+	}
+	c.ID = int(id)
+	return c, tx.Commit()
+}
+
+// GetByConfirmToken fetches a change by the token sent to the new address.
+func (r *EmailChangeRepository) GetByConfirmToken(ctx context.Context, token string) (models.EmailChange, error) {
+	return r.get(ctx, `confirm_token_hash = ?`, utils.HashToken(token))
+}
+
+// GetByRevertToken fetches a change by the token sent to the old address.
+func (r *EmailChangeRepository) GetByRevertToken(ctx context.Context, token string) (models.EmailChange, error) {
+	return r.get(ctx, `revert_token_hash = ?`, utils.HashToken(token))
+}
+
+func (r *EmailChangeRepository) get(ctx context.Context, where string, hash string) (models.EmailChange, error) {
+	var c models.EmailChange
+	var confirmedAt, cancelledAt, revertedAt sql.NullTime
+	err := r.DB.QueryRowContext(ctx, `SELECT id, user_id, old_email, new_email, expires_at, revert_expires_at,
+            confirmed_at, cancelled_at, reverted_at, created_at
+        FROM email_changes WHERE `+where, hash).
+		Scan(&c.ID, &c.UserID, &c.OldEmail, &c.NewEmail, &c.ExpiresAt, &c.RevertExpiresAt,
+			&confirmedAt, &cancelledAt, &revertedAt, &c.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.EmailChange{}, models.ErrInvalidEmailChangeToken
+		}
+		return models.EmailChange{}, err
+	}
+	if confirmedAt.Valid {
+		c.ConfirmedAt = &confirmedAt.Time
+	}
+	if cancelledAt.Valid {
+		c.CancelledAt = &cancelledAt.Time
+	}
+	if revertedAt.Valid {
+		c.RevertedAt = &revertedAt.Time
+	}
+	return c, nil
+}
+
+// ConfirmChange marks a pending change confirmed and sets the new email. It
+// fails if the change is no longer pending or the user's email changed since.
+func (r *EmailChangeRepository) ConfirmChange(ctx context.Context, c models.EmailChange) error {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	res, err := tx.ExecContext(ctx, `UPDATE email_changes SET confirmed_at = ?
+        WHERE id = ? AND confirmed_at IS NULL AND cancelled_at IS NULL AND reverted_at IS NULL`, now, c.ID)
+	if err := expectOneRow(res, err); err != nil {
+		tx.Rollback()
+		return err
+	}
+	res, err = tx.ExecContext(ctx, `UPDATE users SET email = ?, updated_at = ? WHERE id = ? AND email = ?`,
+		c.NewEmail, now, c.UserID, c.OldEmail)
+	if err := expectOneRow(res, err); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// RevertChange undoes a change. A pending change is simply cancelled; a
+// confirmed one restores the old email whatever the current email is, and
+// every later change of the user is cancelled or reverted with it, so the
+// revert token of the original address wins over anything done since.
+func (r *EmailChangeRepository) RevertChange(ctx context.Context, c models.EmailChange) error {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	res, err := tx.ExecContext(ctx, `UPDATE email_changes SET reverted_at = ?
+        WHERE id = ? AND cancelled_at IS NULL AND reverted_at IS NULL`, now, c.ID)
+	if err := expectOneRow(res, err); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if c.ConfirmedAt != nil {
+		_, err = tx.ExecContext(ctx, `UPDATE email_changes SET cancelled_at = ?
+            WHERE user_id = ? AND confirmed_at IS NULL AND cancelled_at IS NULL AND reverted_at IS NULL`, now, c.UserID)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `UPDATE email_changes SET reverted_at = ?
+            WHERE user_id = ? AND id > ? AND confirmed_at IS NOT NULL AND reverted_at IS NULL`, now, c.UserID, c.ID)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		res, err = tx.ExecContext(ctx, `UPDATE users SET email = ?, updated_at = ? WHERE id = ?`,
+			c.OldEmail, now, c.UserID)
+		if err := expectOneRow(res, err); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// expectOneRow maps the result of an email change update: a unique key
+// violation means the address is taken, no affected rows means the token no
+// longer applies.
+func expectOneRow(res sql.Result, err error) error {
+	if err != nil {
+		if isDuplicateEntry(err) {
+			return models.ErrEmailTaken
+		}
+		return err
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return models.ErrInvalidEmailChangeToken
+	}
+	return nil
+}
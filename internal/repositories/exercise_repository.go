@@ -14,10 +14,10 @@ type ExerciseRepository struct {
 }
 
 func (r *ExerciseRepository) CreateExercise(ctx context.Context, ex models.Exercises) (models.Exercises, error) {
-	query := `INSERT INTO exercises (name, description, media_url, sets, repetitions, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?)`
+	query := `INSERT INTO exercises (trainer_id, name, description, media_url, sets, repetitions, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
 	ex.CreatedAt = time.Now()
 	ex.UpdatedAt = &ex.CreatedAt
-	res, err := r.DB.ExecContext(ctx, query, ex.Name, ex.Description, ex.MediaURL, ex.Sets, ex.Repetitions, ex.CreatedAt, ex.UpdatedAt)
+	res, err := r.DB.ExecContext(ctx, query, ex.TrainerID, ex.Name, ex.Description, ex.MediaURL, ex.Sets, ex.Repetitions, ex.CreatedAt, ex.UpdatedAt)
 	if err != nil {
 		return models.Exercises{}, err
 	}
@@ -63,3 +63,21 @@ func (r *ExerciseRepository) DeleteExercise(ctx context.Context, id int) error {
 	}
 	return nil
 }
+
+// GetExerciseOwner returns the trainer who created an exercise, or nil for
+// exercises that predate ownership.
+func (r *ExerciseRepository) GetExerciseOwner(ctx context.Context, id int) (*int, error) {
+	var owner sql.NullInt64
+	err := r.DB.QueryRowContext(ctx, `SELECT trainer_id FROM exercises WHERE id = ?`, id).Scan(&owner)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrExerciseNotFound
+		}
+		return nil, err
+	}
+	if !owner.Valid {
+		return nil, nil
+	}
+	trainerID := int(owner.Int64)
+	return &trainerID, nil
+}
@@ -14,10 +14,10 @@ type FoodRepository struct {
 }
 
 func (r *FoodRepository) CreateFood(ctx context.Context, f models.Food) (models.Food, error) {
-	query := `INSERT INTO food (name, description, calories, protein, fats, carbohydrates, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+	query := `INSERT INTO food (trainer_id, name, description, calories, protein, fats, carbohydrates, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
 	f.CreatedAt = time.Now()
 	f.UpdatedAt = &f.CreatedAt
-	res, err := r.DB.ExecContext(ctx, query, f.Name, f.Description, f.Calories, f.Protein, f.Fats, f.Carbohydrates, f.CreatedAt, f.UpdatedAt)
+	res, err := r.DB.ExecContext(ctx, query, f.TrainerID, f.Name, f.Description, f.Calories, f.Protein, f.Fats, f.Carbohydrates, f.CreatedAt, f.UpdatedAt)
 	if err != nil {
 		return models.Food{}, err
 	}
@@ -63,3 +63,21 @@ func (r *FoodRepository) DeleteFood(ctx context.Context, id int) error {
 	}
 	return nil
 }
+
+// GetFoodOwner returns the trainer who created a food item, or nil for
+// items that predate ownership.
+func (r *FoodRepository) GetFoodOwner(ctx context.Context, id int) (*int, error) {
+	var owner sql.NullInt64
+	err := r.DB.QueryRowContext(ctx, `SELECT trainer_id FROM food WHERE id = ?`, id).Scan(&owner)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrFoodNotFound
+		}
+		return nil, err
+	}
+	if !owner.Valid {
+		return nil, nil
+	}
+	trainerID := int(owner.Int64)
+	return &trainerID, nil
+}
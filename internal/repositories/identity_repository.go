@@ -0,0 +1,94 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"workout/internal/models"
+	"workout/utils"
+)
+
+// IdentityRepository stores external identities and pending OIDC logins.
+type IdentityRepository struct {
+	DB *sql.DB
+}
+
+// GetIdentity returns the identity with the given provider subject.
+func (r *IdentityRepository) GetIdentity(ctx context.Context, provider, subject string) (models.UserIdentity, error) {
+	var id models.UserIdentity
+	err := r.DB.QueryRowContext(ctx, `SELECT id, user_id, provider, subject, email, created_at FROM user_identities WHERE provider = ? AND subject = ?`,
+		provider, subject).Scan(&id.ID, &id.UserID, &id.Provider, &id.Subject, &id.Email, &id.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.UserIdentity{}, ErrUserNotFound
+		}
+		return models.UserIdentity{}, err
+	}
+	return id, nil
+}
+
+func (r *IdentityRepository) CreateIdentity(ctx context.Context, id models.UserIdentity) (models.UserIdentity, error) {
+	id.CreatedAt = time.Now()
+	res, err := r.DB.ExecContext(ctx, `INSERT INTO user_identities (user_id, provider, subject, email, created_at) VALUES (?, ?, ?, ?, ?)`,
+		id.UserID, id.Provider, id.Subject, id.Email, id.CreatedAt)
+	if err != nil {
+		return models.UserIdentity{}, err
+	}
+	lastID, err := res.LastInsertId()
+	if err != nil {
+		return models.UserIdentity{}, err
+	}
+	id.ID = int(lastID)
+	return id, nil
+}
+
+// SaveLoginState stores the PKCE verifier and nonce of a started login under the hashed state.
+func (r *IdentityRepository) SaveLoginState(ctx context.Context, st models.OIDCLoginState) error {
+	var invite sql.NullString
+	if st.InviteToken != "" {
+		invite = sql.NullString{String: st.InviteToken, Valid: true}
+	}
+	_, err := r.DB.ExecContext(ctx, `INSERT INTO oidc_login_states (state_hash, provider, code_verifier, nonce, invite_token, expires_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		utils.HashToken(st.State), st.Provider, st.CodeVerifier, st.Nonce, invite, st.ExpiresAt)
+	return err
+}
+
+// ConsumeLoginState returns and deletes a login state, so each state is used once.
+func (r *IdentityRepository) ConsumeLoginState(ctx context.Context, state string) (models.OIDCLoginState, error) {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return models.OIDCLoginState{}, err
+	}
+
+	st := models.OIDCLoginState{State: state}
+	var invite sql.NullString
+	err = tx.QueryRowContext(ctx, `SELECT provider, code_verifier, nonce, invite_token, expires_at FROM oidc_login_states WHERE state_hash = ? FOR UPDATE`,
+		utils.HashToken(state)).Scan(&st.Provider, &st.CodeVerifier, &st.Nonce, &invite, &st.ExpiresAt)
+	if err != nil {
+		tx.Rollback()
+		if err == sql.ErrNoRows {
+			return models.OIDCLoginState{}, models.ErrInvalidOIDCState
+		}
+		return models.OIDCLoginState{}, err
+	}
+	if _, err = tx.ExecContext(ctx, `DELETE FROM oidc_login_states WHERE state_hash = ?`, utils.HashToken(state)); err != nil {
+		tx.Rollback()
+		return models.OIDCLoginState{}, err
+	}
+	if err = tx.Commit(); err != nil {
+		return models.OIDCLoginState{}, err
+	}
+
+	st.InviteToken = invite.String
+	if st.ExpiresAt.Before(time.Now()) {
+		return models.OIDCLoginState{}, models.ErrInvalidOIDCState
+	}
+	return st, nil
+}
+
+// DeleteExpiredLoginStates removes logins that were started but never completed.
+func (r *IdentityRepository) DeleteExpiredLoginStates(ctx context.Context) error {
+	_, err := r.DB.ExecContext(ctx, `DELETE FROM oidc_login_states WHERE expires_at < ?`, time.Now())
+	return err
+}
@@ -3,6 +3,7 @@ package repositories
 import (
 	"context"
 	"database/sql"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -34,10 +35,10 @@ func (r *InviteRepository) CreateInvite(ctx context.Context, inv models.ProgramI
 func (r *InviteRepository) getInviteByToken(ctx context.Context, token string) (models.ProgramInvite, error) {
 	var inv models.ProgramInvite
 	var clientID sql.NullInt64
-	var acceptedAt, expires sql.NullTime
-	query := `SELECT id, program_id, email, message, access_days, token, client_id, accepted_at, access_expires, created_at, updated_at FROM program_invites WHERE token = ?`
+	var acceptedAt, expires, revokedAt sql.NullTime
+	query := `SELECT id, program_id, email, message, access_days, token, client_id, accepted_at, access_expires, revoked_at, created_at, updated_at FROM program_invites WHERE token = ?`
 	err := r.DB.QueryRowContext(ctx, query, token).Scan(&inv.ID, &inv.ProgramID, &inv.Email, &inv.Message, &inv.AccessDays,
-		&inv.Token, &clientID, &acceptedAt, &expires, &inv.CreatedAt, &inv.UpdatedAt)
+		&inv.Token, &clientID, &acceptedAt, &expires, &revokedAt, &inv.CreatedAt, &inv.UpdatedAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return models.ProgramInvite{}, models.ErrInviteNotFound
@@ -54,21 +55,55 @@ func (r *InviteRepository) getInviteByToken(ctx context.Context, token string) (
 	if expires.Valid {
 		inv.AccessExpires = &expires.Time
 	}
+	if revokedAt.Valid {
+		inv.RevokedAt = &revokedAt.Time
+	}
 	return inv, nil
 }
 
-func (r *InviteRepository) AcceptInvite(ctx context.Context, token string, clientID int) (models.ProgramInvite, error) {
+// AcceptInvite enrolls the client through an invite sent to email and pins
+// the enrollment to the latest published version of the program. An invite
+// can be accepted only once and not after the client was removed from the
+// program; re-accepting would reset the access window.
+func (r *InviteRepository) AcceptInvite(ctx context.Context, token string, clientID int, email string) (models.ProgramInvite, error) {
 	inv, err := r.getInviteByToken(ctx, token)
 	if err != nil {
 		return models.ProgramInvite{}, err
 	}
+	if inv.RevokedAt != nil {
+		return models.ProgramInvite{}, models.ErrInviteRevoked
+	}
+	if inv.AcceptedAt != nil {
+		return models.ProgramInvite{}, models.ErrInviteAccepted
+	}
+	if !strings.EqualFold(strings.TrimSpace(inv.Email), strings.TrimSpace(email)) {
+		return models.ProgramInvite{}, models.ErrInviteMismatch
+	}
+	var versionID sql.NullInt64
+	err = r.DB.QueryRowContext(ctx, `SELECT id FROM program_versions WHERE program_id = ? ORDER BY version DESC LIMIT 1`, inv.ProgramID).Scan(&versionID)
+	if err != nil && err != sql.ErrNoRows {
+		return models.ProgramInvite{}, err
+	}
 	now := time.Now()
 	expires := now.Add(time.Duration(inv.AccessDays) * 24 * time.Hour)
-	_, err = r.DB.ExecContext(ctx, `UPDATE program_invites SET client_id=?, accepted_at=?, access_expires=?, updated_at=? WHERE id=?`,
-		clientID, now, expires, now, inv.ID)
+	res, err := r.DB.ExecContext(ctx, `UPDATE program_invites SET client_id=?, version_id=?, accepted_at=?, access_expires=?, expired_at=NULL, updated_at=?
+        WHERE id=? AND accepted_at IS NULL AND revoked_at IS NULL`,
+		clientID, versionID, now, expires, now, inv.ID)
 	if err != nil {
 		return models.ProgramInvite{}, err
 	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return models.ProgramInvite{}, err
+	}
+	// a concurrent request accepted it first or the client was removed
+	if rows == 0 {
+		return models.ProgramInvite{}, models.ErrInviteAccepted
+	}
+	if versionID.Valid {
+		id := int(versionID.Int64)
+		inv.VersionID = &id
+	}
 	inv.ClientID = &clientID
 	inv.AcceptedAt = &now
 	inv.AccessExpires = &expires
@@ -90,8 +125,10 @@ func (r *InviteRepository) UpdateAccessDuration(ctx context.Context, programID,
 	if acceptedAt.Valid {
 		now := time.Now()
 		expires := acceptedAt.Time.Add(time.Duration(days) * 24 * time.Hour)
-		_, err = r.DB.ExecContext(ctx, `UPDATE program_invites SET access_days=?, access_expires=?, updated_at=? WHERE id=?`,
-			days, expires, now, inv.ID)
+		// extending access re-arms the expiry notification
+		_, err = r.DB.ExecContext(ctx, `UPDATE program_invites SET access_days=?, access_expires=?,
+            expired_at=IF(? > NOW(), NULL, expired_at), expiry_notify_attempts=0, expiry_notify_after=NULL, updated_at=? WHERE id=?`,
+			days, expires, expires, now, inv.ID)
 		if err != nil {
 			return models.ProgramInvite{}, err
 		}
@@ -101,7 +138,6 @@ func (r *InviteRepository) UpdateAccessDuration(ctx context.Context, programID,
 		inv.ProgramID = programID
 		cid := clientID
 		inv.ClientID = &cid
-		inv.ID = inv.ID
 		inv.UpdatedAt = &now
 		return inv, nil
 	}
@@ -132,3 +168,48 @@ func (r *InviteRepository) GetProgramFromInvite(ctx context.Context, token strin
 	}
 	return p, nil
 }
+
+// GetNewlyExpired returns accepted invites whose access has run out, that
+// have not been marked expired yet and whose notification is not backing off
+// after a failure.
+func (r *InviteRepository) GetNewlyExpired(ctx context.Context, now time.Time, limit int) ([]models.ExpiredEnrollment, error) {
+	query := `SELECT pi.id, wp.id, wp.name, c.id, c.name, c.email, t.email, t.name, pi.access_expires, pi.expiry_notify_attempts
+              FROM program_invites pi
+              JOIN workout_programs wp ON wp.id = pi.program_id
+              JOIN users c ON c.id = pi.client_id
+              JOIN users t ON t.id = wp.trainer_id
+              WHERE pi.accepted_at IS NOT NULL AND pi.expired_at IS NULL AND pi.access_expires <= ?
+                AND (pi.expiry_notify_after IS NULL OR pi.expiry_notify_after <= ?)
+              ORDER BY pi.access_expires
+              LIMIT ?`
+	rows, err := r.DB.QueryContext(ctx, query, now, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []models.ExpiredEnrollment{}
+	for rows.Next() {
+		var e models.ExpiredEnrollment
+		if err := rows.Scan(&e.InviteID, &e.ProgramID, &e.ProgramName, &e.ClientID, &e.ClientName, &e.ClientEmail,
+			&e.TrainerEmail, &e.TrainerName, &e.AccessExpires, &e.NotifyAttempts); err != nil {
+			return nil, err
+		}
+		result = append(result, e)
+	}
+	return result, rows.Err()
+}
+
+// MarkExpired records that the invite's access has expired.
+func (r *InviteRepository) MarkExpired(ctx context.Context, id int, at time.Time) error {
+	_, err := r.DB.ExecContext(ctx, `UPDATE program_invites SET expired_at = ? WHERE id = ? AND expired_at IS NULL`, at, id)
+	return err
+}
+
+// DeferExpiryNotice records a failed expiry notification and holds the
+// invite back until retryAt, so it does not block the rest of the batch.
+func (r *InviteRepository) DeferExpiryNotice(ctx context.Context, id int, retryAt time.Time) error {
+	_, err := r.DB.ExecContext(ctx, `UPDATE program_invites SET expiry_notify_attempts = expiry_notify_attempts + 1, expiry_notify_after = ?
+        WHERE id = ? AND expired_at IS NULL`, retryAt, id)
+	return err
+}
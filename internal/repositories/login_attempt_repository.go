@@ -0,0 +1,78 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"workout/internal/models"
+)
+
+// LoginAttemptRepository tracks failed sign-in attempts.
+type LoginAttemptRepository struct {
+	DB *sql.DB
+}
+
+// GetAttempt returns the failure counter for a subject. A subject without
+// failures yields an empty record.
+func (r *LoginAttemptRepository) GetAttempt(ctx context.Context, scope, subject string) (models.LoginAttempt, error) {
+	a := models.LoginAttempt{Scope: scope, Subject: subject}
+	err := r.DB.QueryRowContext(ctx, `SELECT failures, last_failure_at FROM login_attempts WHERE scope = ? AND subject = ?`, scope, subject).
+		Scan(&a.Failures, &a.LastFailureAt)
+	if err == sql.ErrNoRows {
+		return a, nil
+	}
+	return a, err
+}
+
+// ReserveAttempt counts an attempt as failed before it is verified, so that
+// parallel attempts cannot all slip under the threshold. The counter row is
+// locked while locked decides whether the subject may try at all; if not,
+// nothing is recorded and false is returned. Counters whose last failure is
+// older than resetBefore start over from one.
+func (r *LoginAttemptRepository) ReserveAttempt(ctx context.Context, scope, subject string, resetBefore time.Time,
+	locked func(models.LoginAttempt) bool) (bool, error) {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	now := time.Now()
+	if _, err := tx.ExecContext(ctx, `INSERT IGNORE INTO login_attempts (scope, subject, failures, last_failure_at) VALUES (?, ?, 0, ?)`,
+		scope, subject, now); err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	a := models.LoginAttempt{Scope: scope, Subject: subject}
+	err = tx.QueryRowContext(ctx, `SELECT failures, last_failure_at FROM login_attempts WHERE scope = ? AND subject = ? FOR UPDATE`, scope, subject).
+		Scan(&a.Failures, &a.LastFailureAt)
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	if a.LastFailureAt.Before(resetBefore) {
+		a.Failures = 0
+	}
+	if locked(a) {
+		tx.Rollback()
+		return false, nil
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE login_attempts SET failures = ?, last_failure_at = ? WHERE scope = ? AND subject = ?`,
+		a.Failures+1, now, scope, subject); err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// ReleaseAttempt takes back an attempt reserved by ReserveAttempt once it
+// turned out not to be a failure.
+func (r *LoginAttemptRepository) ReleaseAttempt(ctx context.Context, scope, subject string) error {
+	_, err := r.DB.ExecContext(ctx, `UPDATE login_attempts SET failures = GREATEST(failures - 1, 0) WHERE scope = ? AND subject = ?`, scope, subject)
+	return err
+}
+
+// Reset clears the failure counter of a subject.
+func (r *LoginAttemptRepository) Reset(ctx context.Context, scope, subject string) error {
+	_, err := r.DB.ExecContext(ctx, `DELETE FROM login_attempts WHERE scope = ? AND subject = ?`, scope, subject)
+	return err
+}
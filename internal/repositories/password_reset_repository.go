@@ -0,0 +1,65 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"workout/internal/models"
+	"workout/utils"
+)
+
+// PasswordResetRepository stores password reset tokens. Tokens are kept hashed.
+type PasswordResetRepository struct {
+	DB *sql.DB
+}
+
+func (r *PasswordResetRepository) CreateReset(ctx context.Context, pr models.PasswordReset) (models.PasswordReset, error) {
+	pr.CreatedAt = time.Now()
+	res, err := r.DB.ExecContext(ctx, `INSERT INTO password_resets (user_id, token_hash, expires_at, created_at) VALUES (?, ?, ?, ?)`,
+		pr.UserID, utils.HashToken(pr.Token), pr.ExpiresAt, pr.CreatedAt)
+	if err != nil {
+		return models.PasswordReset{}, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return models.PasswordReset{}, err
+	}
+	pr.ID = int(id)
+	return pr, nil
+}
+
+// GetResetByToken fetches a reset request by its token.
+func (r *PasswordResetRepository) GetResetByToken(ctx context.Context, token string) (models.PasswordReset, error) {
+	var pr models.PasswordReset
+	var usedAt sql.NullTime
+	err := r.DB.QueryRowContext(ctx, `SELECT id, user_id, expires_at, used_at, created_at FROM password_resets WHERE token_hash = ?`, utils.HashToken(token)).
+		Scan(&pr.ID, &pr.UserID, &pr.ExpiresAt, &usedAt, &pr.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.PasswordReset{}, models.ErrInvalidResetToken
+		}
+		return models.PasswordReset{}, err
+	}
+	if usedAt.Valid {
+		pr.UsedAt = &usedAt.Time
+	}
+	pr.Token = token
+	return pr, nil
+}
+
+// MarkResetUsed consumes a reset token. It fails if the token was already used.
+func (r *PasswordResetRepository) MarkResetUsed(ctx context.Context, id int) error {
+	res, err := r.DB.ExecContext(ctx, `UPDATE password_resets SET used_at = ? WHERE id = ? AND used_at IS NULL`, time.Now(), id)
+	if err != nil {
+		return err
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return models.ErrInvalidResetToken
+	}
+	return nil
+}
@@ -3,6 +3,7 @@ package repositories
 import (
 	"context"
 	"database/sql"
+	"strings"
 	"time"
 
 	"workout/internal/models"
@@ -33,7 +34,7 @@ VALUES (?, ?, ?, ?, ?, ?)
 
 func (r *ProgramRepository) GetProgramsByTrainer(ctx context.Context, trainerID int) ([]models.WorkOutProgram, error) {
 	query := `
-SELECT id, trainer_id, name, days, description, created_at, updated_at
+SELECT id, trainer_id, name, days, description, visibility, slug, created_at, updated_at
 FROM workout_programs
 WHERE trainer_id = ?
 `
@@ -46,7 +47,7 @@ WHERE trainer_id = ?
 	programs := []models.WorkOutProgram{}
 	for rows.Next() {
 		var p models.WorkOutProgram
-		if err := rows.Scan(&p.ID, &p.TrainerID, &p.Name, &p.Days, &p.Description, &p.CreatedAt, &p.UpdatedAt); err != nil {
+		if err := rows.Scan(&p.ID, &p.TrainerID, &p.Name, &p.Days, &p.Description, &p.Visibility, &p.Slug, &p.CreatedAt, &p.UpdatedAt); err != nil {
 			return nil, err
 		}
 		programs = append(programs, p)
@@ -57,8 +58,8 @@ WHERE trainer_id = ?
 // GetProgramByID fetches a workout program by its ID.
 func (r *ProgramRepository) GetProgramByID(ctx context.Context, id int) (models.WorkOutProgram, error) {
 	var p models.WorkOutProgram
-	query := `SELECT id, trainer_id, name, days, description, created_at, updated_at FROM workout_programs WHERE id = ?`
-	err := r.DB.QueryRowContext(ctx, query, id).Scan(&p.ID, &p.TrainerID, &p.Name, &p.Days, &p.Description, &p.CreatedAt, &p.UpdatedAt)
+	query := `SELECT id, trainer_id, name, days, description, visibility, slug, created_at, updated_at FROM workout_programs WHERE id = ?`
+	err := r.DB.QueryRowContext(ctx, query, id).Scan(&p.ID, &p.TrainerID, &p.Name, &p.Days, &p.Description, &p.Visibility, &p.Slug, &p.CreatedAt, &p.UpdatedAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return models.WorkOutProgram{}, models.ErrWorkoutProgramNotFound
@@ -87,17 +88,40 @@ func (r *ProgramRepository) UpdateProgram(ctx context.Context, p models.WorkOutP
 	return p, nil
 }
 
-// DeleteProgram removes a workout program and all of its days.
+// DeleteProgram removes a workout program with all of its days, versions
+// and pending invites. Programs with enrolled clients are not deleted, as
+// that would also delete the clients' progress.
 func (r *ProgramRepository) DeleteProgram(ctx context.Context, id int) error {
 	tx, err := r.DB.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
 
-	if _, err = tx.ExecContext(ctx, `DELETE FROM days WHERE work_out_program_id = ?`, id); err != nil {
+	// locking the invites keeps clients from enrolling meanwhile
+	var enrolled int
+	err = tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM program_invites WHERE program_id = ? AND accepted_at IS NOT NULL FOR UPDATE`, id).
+		Scan(&enrolled)
+	if err != nil {
 		tx.Rollback()
 		return err
 	}
+	if enrolled > 0 {
+		tx.Rollback()
+		return models.ErrProgramHasClients
+	}
+
+	statements := []string{
+		`DELETE p FROM progress p JOIN days d ON p.day_id = d.id WHERE d.work_out_program_id = ?`,
+		`DELETE FROM program_invites WHERE program_id = ?`,
+		`DELETE FROM days WHERE work_out_program_id = ?`,
+		`DELETE FROM program_versions WHERE program_id = ?`,
+	}
+	for _, query := range statements {
+		if _, err = tx.ExecContext(ctx, query, id); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
 
 	res, err := tx.ExecContext(ctx, `DELETE FROM workout_programs WHERE id = ?`, id)
 	if err != nil {
@@ -116,3 +140,280 @@ func (r *ProgramRepository) DeleteProgram(ctx context.Context, id int) error {
 
 	return tx.Commit()
 }
+
+// CloneProgram copies a program and its draft days in one transaction. With
+// copyResources the referenced exercises and food are duplicated and owned
+// by p.TrainerID; a resource shared by several days is copied once.
+func (r *ProgramRepository) CloneProgram(ctx context.Context, sourceID int, p models.WorkOutProgram, copyResources bool) (models.WorkOutProgram, error) {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return models.WorkOutProgram{}, err
+	}
+
+	p.CreatedAt = time.Now()
+	p.UpdatedAt = &p.CreatedAt
+	res, err := tx.ExecContext(ctx, `INSERT INTO workout_programs (trainer_id, name, days, description, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		p.TrainerID, p.Name, p.Days, p.Description, p.CreatedAt, p.UpdatedAt)
+	if err != nil {
+		tx.Rollback()
+		return models.WorkOutProgram{}, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		tx.Rollback()
+		return models.WorkOutProgram{}, err
+	}
+	p.ID = int(id)
+
+	rows, err := tx.QueryContext(ctx, `SELECT day_number, exercises_id, food_id, note FROM days
+        WHERE work_out_program_id = ? AND program_version_id IS NULL ORDER BY day_number`, sourceID)
+	if err != nil {
+		tx.Rollback()
+		return models.WorkOutProgram{}, err
+	}
+	var days []models.Days
+	for rows.Next() {
+		var d models.Days
+		var note sql.NullString
+		if err := rows.Scan(&d.DayNumber, &d.ExercisesID, &d.FoodID, &note); err != nil {
+			rows.Close()
+			tx.Rollback()
+			return models.WorkOutProgram{}, err
+		}
+		d.Note = note.String
+		days = append(days, d)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		tx.Rollback()
+		return models.WorkOutProgram{}, err
+	}
+
+	exercises := map[int]int{}
+	food := map[int]int{}
+	for _, d := range days {
+		if copyResources {
+			if d.ExercisesID, err = copyRow(ctx, tx, exercises, d.ExercisesID, p.TrainerID, p.CreatedAt,
+				`INSERT INTO exercises (trainer_id, name, description, media_url, sets, repetitions, created_at, updated_at)
+        SELECT ?, name, description, media_url, sets, repetitions, ?, ? FROM exercises WHERE id = ?`); err != nil {
+				tx.Rollback()
+				return models.WorkOutProgram{}, err
+			}
+			if d.FoodID, err = copyRow(ctx, tx, food, d.FoodID, p.TrainerID, p.CreatedAt,
+				`INSERT INTO food (trainer_id, name, description, calories, protein, fats, carbohydrates, created_at, updated_at)
+        SELECT ?, name, description, calories, protein, fats, carbohydrates, ?, ? FROM food WHERE id = ?`); err != nil {
+				tx.Rollback()
+				return models.WorkOutProgram{}, err
+			}
+		}
+		_, err = tx.ExecContext(ctx, `INSERT INTO days (work_out_program_id, day_number, exercises_id, food_id, note, created_at, updated_at)
+        VALUES (?, ?, ?, ?, ?, ?, ?)`, p.ID, d.DayNumber, d.ExercisesID, d.FoodID, d.Note, p.CreatedAt, p.CreatedAt)
+		if err != nil {
+			tx.Rollback()
+			return models.WorkOutProgram{}, err
+		}
+	}
+
+	return p, tx.Commit()
+}
+
+// copyRow runs an INSERT ... SELECT copying the row id once and remembers
+// the new id in copied.
+func copyRow(ctx context.Context, tx *sql.Tx, copied map[int]int, id, ownerID int, now time.Time, query string) (int, error) {
+	if newID, ok := copied[id]; ok {
+		return newID, nil
+	}
+	res, err := tx.ExecContext(ctx, query, ownerID, now, now, id)
+	if err != nil {
+		return 0, err
+	}
+	newID, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	copied[id] = int(newID)
+	return int(newID), nil
+}
+
+// SetCatalogListing makes the program public with the given catalog details.
+// The listing date is kept when an already public program is edited.
+func (r *ProgramRepository) SetCatalogListing(ctx context.Context, programID int, req models.CatalogRequest) error {
+	now := time.Now()
+	_, err := r.DB.ExecContext(ctx, `UPDATE workout_programs SET visibility = ?, slug = ?, cover_image_url = ?, summary = ?, preview_days = ?,
+        catalog_listed_at = CASE WHEN visibility = ? THEN catalog_listed_at ELSE ? END, updated_at = ? WHERE id = ?`,
+		models.VisibilityPublic, req.Slug, req.CoverImageURL, req.Summary, req.PreviewDays, models.VisibilityPublic, now, now, programID)
+	if err != nil {
+		if isDuplicateEntry(err) {
+			return models.ErrSlugTaken
+		}
+		return err
+	}
+	return nil
+}
+
+// RemoveCatalogListing hides the program from the catalog. The slug is kept
+// for when it is listed again.
+func (r *ProgramRepository) RemoveCatalogListing(ctx context.Context, programID int) error {
+	_, err := r.DB.ExecContext(ctx, `UPDATE workout_programs SET visibility = ?, catalog_listed_at = NULL, updated_at = ? WHERE id = ?`,
+		models.VisibilityPrivate, time.Now(), programID)
+	return err
+}
+
+// catalogFrom selects public programs of active trainers together with their
+// latest published version.
+const catalogFrom = ` FROM workout_programs p
+        JOIN users u ON u.id = p.trainer_id AND u.suspended_at IS NULL
+        JOIN program_versions v ON v.program_id = p.id
+            AND v.version = (SELECT MAX(version) FROM program_versions WHERE program_id = p.id)
+        WHERE p.visibility = ?`
+
+const catalogColumns = `p.id, p.slug, v.id, v.name, COALESCE(v.description, ''), COALESCE(p.summary, ''), COALESCE(p.cover_image_url, ''),
+        v.days, v.version, p.trainer_id, u.name, p.preview_days, p.catalog_listed_at`
+
+func scanCatalogProgram(row interface{ Scan(...interface{}) error }) (models.CatalogProgram, int, error) {
+	var c models.CatalogProgram
+	var versionID int
+	err := row.Scan(&c.ID, &c.Slug, &versionID, &c.Name, &c.Description, &c.Summary, &c.CoverImageURL,
+		&c.Days, &c.Version, &c.TrainerID, &c.TrainerName, &c.PreviewDays, &c.ListedAt)
+	return c, versionID, err
+}
+
+// GetCatalog returns a page of the catalog, newest listings first, and the
+// total number of matching programs. Query matches name and summary.
+func (r *ProgramRepository) GetCatalog(ctx context.Context, f models.CatalogFilter) ([]models.CatalogProgram, int, error) {
+	cond := ""
+	args := []interface{}{models.VisibilityPublic}
+	if f.Query != "" {
+		like := "%" + strings.NewReplacer("\\", "\\\\", "%", "\\%", "_", "\\_").Replace(f.Query) + "%"
+		cond += ` AND (v.name LIKE ? OR p.summary LIKE ?)`
+		args = append(args, like, like)
+	}
+	if f.TrainerID != 0 {
+		cond += ` AND p.trainer_id = ?`
+		args = append(args, f.TrainerID)
+	}
+
+	var total int
+	if err := r.DB.QueryRowContext(ctx, `SELECT COUNT(*)`+catalogFrom+cond, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := r.DB.QueryContext(ctx, `SELECT `+catalogColumns+catalogFrom+cond+` ORDER BY p.catalog_listed_at DESC, p.id DESC LIMIT ? OFFSET ?`,
+		append(args, f.PerPage, (f.Page-1)*f.PerPage)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	programs := []models.CatalogProgram{}
+	for rows.Next() {
+		c, _, err := scanCatalogProgram(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		programs = append(programs, c)
+	}
+	return programs, total, rows.Err()
+}
+
+// GetCatalogProgram returns a public program by slug with the id of the
+// version it describes.
+func (r *ProgramRepository) GetCatalogProgram(ctx context.Context, slug string) (models.CatalogProgram, int, error) {
+	c, versionID, err := scanCatalogProgram(r.DB.QueryRowContext(ctx, `SELECT `+catalogColumns+catalogFrom+` AND p.slug = ?`,
+		models.VisibilityPublic, slug))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.CatalogProgram{}, 0, models.ErrCatalogNotFound
+		}
+		return models.CatalogProgram{}, 0, err
+	}
+	return c, versionID, nil
+}
+
+// ImportProgram creates a program from a validated document in one
+// transaction. Exercises and food identical to ones the trainer owns or to
+// shared library items are reused instead of being created again.
+func (r *ProgramRepository) ImportProgram(ctx context.Context, trainerID int, doc models.ProgramDocument) (models.WorkOutProgram, error) {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return models.WorkOutProgram{}, err
+	}
+
+	now := time.Now()
+	exercises := make(map[string]int, len(doc.Exercises))
+	for _, e := range doc.Exercises {
+		id, err := findOrCreate(ctx, tx,
+			`SELECT id FROM exercises WHERE (trainer_id = ? OR trainer_id IS NULL) AND name = ? AND COALESCE(description, '') = ?
+            AND COALESCE(media_url, '') = ? AND COALESCE(sets, '') = ? AND COALESCE(repetitions, '') = ?
+            ORDER BY trainer_id IS NULL, id LIMIT 1`,
+			`INSERT INTO exercises (trainer_id, name, description, media_url, sets, repetitions, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			[]interface{}{trainerID, e.Name, e.Description, e.MediaURL, e.Sets, e.Repetitions},
+			now)
+		if err != nil {
+			tx.Rollback()
+			return models.WorkOutProgram{}, err
+		}
+		exercises[e.Key] = id
+	}
+	food := make(map[string]int, len(doc.Food))
+	for _, f := range doc.Food {
+		id, err := findOrCreate(ctx, tx,
+			`SELECT id FROM food WHERE (trainer_id = ? OR trainer_id IS NULL) AND name = ? AND COALESCE(description, '') = ?
+            AND calories = ? AND protein = ? AND fats = ? AND carbohydrates = ?
+            ORDER BY trainer_id IS NULL, id LIMIT 1`,
+			`INSERT INTO food (trainer_id, name, description, calories, protein, fats, carbohydrates, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			[]interface{}{trainerID, f.Name, f.Description, f.Calories, f.Protein, f.Fats, f.Carbohydrates},
+			now)
+		if err != nil {
+			tx.Rollback()
+			return models.WorkOutProgram{}, err
+		}
+		food[f.Key] = id
+	}
+
+	p := models.WorkOutProgram{TrainerID: trainerID, Name: doc.Program.Name, Days: doc.Program.Days, Description: doc.Program.Description,
+		Visibility: models.VisibilityPrivate, CreatedAt: now, UpdatedAt: &now}
+	res, err := tx.ExecContext(ctx, `INSERT INTO workout_programs (trainer_id, name, days, description, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		p.TrainerID, p.Name, p.Days, p.Description, p.CreatedAt, p.UpdatedAt)
+	if err != nil {
+		tx.Rollback()
+		return models.WorkOutProgram{}, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		tx.Rollback()
+		return models.WorkOutProgram{}, err
+	}
+	p.ID = int(id)
+
+	for _, d := range doc.Days {
+		_, err = tx.ExecContext(ctx, `INSERT INTO days (work_out_program_id, day_number, exercises_id, food_id, note, created_at, updated_at)
+        VALUES (?, ?, ?, ?, ?, ?, ?)`, p.ID, d.Day, exercises[d.Exercise], food[d.Food], d.Note, now, now)
+		if err != nil {
+			tx.Rollback()
+			return models.WorkOutProgram{}, err
+		}
+	}
+
+	return p, tx.Commit()
+}
+
+// findOrCreate returns the id found by the select query or inserts a new
+// row. Both queries take args; the insert also gets created_at and
+// updated_at.
+func findOrCreate(ctx context.Context, tx *sql.Tx, selectQuery, insertQuery string, args []interface{}, now time.Time) (int, error) {
+	var id int
+	err := tx.QueryRowContext(ctx, selectQuery, args...).Scan(&id)
+	if err == nil {
+		return id, nil
+	}
+	if err != sql.ErrNoRows {
+		return 0, err
+	}
+	res, err := tx.ExecContext(ctx, insertQuery, append(args, now, now)...)
+	if err != nil {
+		return 0, err
+	}
+	newID, err := res.LastInsertId()
+	return int(newID), err
+}
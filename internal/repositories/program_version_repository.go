@@ -0,0 +1,187 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"workout/internal/models"
+)
+
+// ProgramVersionRepository stores published program versions. A version's
+// days are rows of the days table tagged with program_version_id.
+type ProgramVersionRepository struct {
+	DB *sql.DB
+}
+
+// Publish snapshots the program and its draft days as the next version.
+func (r *ProgramVersionRepository) Publish(ctx context.Context, programID, publishedBy int) (models.ProgramVersion, error) {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return models.ProgramVersion{}, err
+	}
+
+	v := models.ProgramVersion{ProgramID: programID, PublishedBy: &publishedBy, PublishedAt: time.Now()}
+	// locking the program serializes concurrent publishes
+	err = tx.QueryRowContext(ctx, `SELECT name, days, COALESCE(description, '') FROM workout_programs WHERE id = ? FOR UPDATE`, programID).
+		Scan(&v.Name, &v.Days, &v.Description)
+	if err != nil {
+		tx.Rollback()
+		if err == sql.ErrNoRows {
+			return models.ProgramVersion{}, models.ErrWorkoutProgramNotFound
+		}
+		return models.ProgramVersion{}, err
+	}
+	if err := tx.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) + 1 FROM program_versions WHERE program_id = ?`, programID).Scan(&v.Version); err != nil {
+		tx.Rollback()
+		return models.ProgramVersion{}, err
+	}
+
+	res, err := tx.ExecContext(ctx, `INSERT INTO program_versions (program_id, version, name, description, days, published_by, published_at)
+        VALUES (?, ?, ?, ?, ?, ?, ?)`, programID, v.Version, v.Name, v.Description, v.Days, publishedBy, v.PublishedAt)
+	if err != nil {
+		tx.Rollback()
+		return models.ProgramVersion{}, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		tx.Rollback()
+		return models.ProgramVersion{}, err
+	}
+	v.ID = int(id)
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO days (work_out_program_id, program_version_id, day_number, exercises_id, food_id, note, created_at, updated_at)
+        SELECT work_out_program_id, ?, day_number, exercises_id, food_id, note, ?, ?
+        FROM days WHERE work_out_program_id = ? AND program_version_id IS NULL`, v.ID, v.PublishedAt, v.PublishedAt, programID)
+	if err != nil {
+		tx.Rollback()
+		return models.ProgramVersion{}, err
+	}
+	return v, tx.Commit()
+}
+
+const versionColumns = `v.id, v.program_id, v.version, v.name, COALESCE(v.description, ''), v.days, v.published_by, v.published_at,
+        (SELECT COUNT(*) FROM program_invites pi WHERE pi.version_id = v.id AND pi.accepted_at IS NOT NULL)`
+
+func scanVersion(row interface{ Scan(...interface{}) error }) (models.ProgramVersion, error) {
+	var v models.ProgramVersion
+	err := row.Scan(&v.ID, &v.ProgramID, &v.Version, &v.Name, &v.Description, &v.Days, &v.PublishedBy, &v.PublishedAt, &v.Clients)
+	return v, err
+}
+
+// GetVersions lists the published versions of a program, newest first, with
+// the number of clients pinned to each.
+func (r *ProgramVersionRepository) GetVersions(ctx context.Context, programID int) ([]models.ProgramVersion, error) {
+	rows, err := r.DB.QueryContext(ctx, `SELECT `+versionColumns+` FROM program_versions v WHERE v.program_id = ? ORDER BY v.version DESC`, programID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []models.ProgramVersion{}
+	for rows.Next() {
+		v, err := scanVersion(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, v)
+	}
+	return result, rows.Err()
+}
+
+// GetVersion fetches a version of a program by its number.
+func (r *ProgramVersionRepository) GetVersion(ctx context.Context, programID, version int) (models.ProgramVersion, error) {
+	v, err := scanVersion(r.DB.QueryRowContext(ctx, `SELECT `+versionColumns+` FROM program_versions v WHERE v.program_id = ? AND v.version = ?`, programID, version))
+	if err == sql.ErrNoRows {
+		return models.ProgramVersion{}, models.ErrVersionNotFound
+	}
+	return v, err
+}
+
+// GetVersionByID fetches a version by its ID.
+func (r *ProgramVersionRepository) GetVersionByID(ctx context.Context, id int) (models.ProgramVersion, error) {
+	v, err := scanVersion(r.DB.QueryRowContext(ctx, `SELECT `+versionColumns+` FROM program_versions v WHERE v.id = ?`, id))
+	if err == sql.ErrNoRows {
+		return models.ProgramVersion{}, models.ErrVersionNotFound
+	}
+	return v, err
+}
+
+// HasVersions reports whether the program has been published.
+func (r *ProgramVersionRepository) HasVersions(ctx context.Context, programID int) (bool, error) {
+	var exists bool
+	err := r.DB.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM program_versions WHERE program_id = ?)`, programID).Scan(&exists)
+	return exists, err
+}
+
+// ClientVersionID returns the version the client's enrollment is pinned to,
+// nil if it follows the draft.
+func (r *ProgramVersionRepository) ClientVersionID(ctx context.Context, clientID, programID int) (*int, error) {
+	var versionID sql.NullInt64
+	err := r.DB.QueryRowContext(ctx, `SELECT version_id FROM program_invites
+        WHERE client_id = ? AND program_id = ? AND accepted_at IS NOT NULL LIMIT 1`, clientID, programID).Scan(&versionID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrForbidden
+		}
+		return nil, err
+	}
+	if !versionID.Valid {
+		return nil, nil
+	}
+	id := int(versionID.Int64)
+	return &id, nil
+}
+
+// MigrateClients pins enrollments of the program to the version, all of them
+// or only those of clientIDs. Progress moves to the day with the same number
+// in the new version; days without a counterpart keep their history. It
+// returns the number of enrollments moved.
+func (r *ProgramVersionRepository) MigrateClients(ctx context.Context, programID, versionID int, clientIDs []int) (int, error) {
+	where := `pi.program_id = ? AND pi.accepted_at IS NOT NULL AND NOT (pi.version_id <=> ?)`
+	args := []interface{}{programID, versionID}
+	if len(clientIDs) > 0 {
+		where += ` AND pi.client_id IN (?` + strings.Repeat(`, ?`, len(clientIDs)-1) + `)`
+		for _, id := range clientIDs {
+			args = append(args, id)
+		}
+	}
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	statements := []string{
+		`UPDATE IGNORE progress p
+            JOIN days od ON od.id = p.day_id
+            JOIN program_invites pi ON pi.client_id = p.client_id AND pi.program_id = od.work_out_program_id
+                AND od.program_version_id <=> pi.version_id
+            JOIN days nd ON nd.program_version_id = ? AND nd.day_number = od.day_number
+        SET p.day_id = nd.id
+        WHERE ` + where,
+		`INSERT IGNORE INTO progress (client_id, day_id, food_completed, exercise_completed)
+        SELECT pi.client_id, nd.id, FALSE, FALSE
+        FROM program_invites pi
+            JOIN days nd ON nd.program_version_id = ?
+        WHERE ` + where,
+	}
+	for _, query := range statements {
+		if _, err := tx.ExecContext(ctx, query, append([]interface{}{versionID}, args...)...); err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+	}
+	res, err := tx.ExecContext(ctx, `UPDATE program_invites pi SET pi.version_id = ?, pi.updated_at = NOW() WHERE `+where,
+		append([]interface{}{versionID}, args...)...)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	migrated, err := res.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	return int(migrated), tx.Commit()
+}
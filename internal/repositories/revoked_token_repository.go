@@ -0,0 +1,36 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// RevokedTokenRepository keeps the ids (jti) of access tokens that were
+// invalidated before their expiry.
+type RevokedTokenRepository struct {
+	DB *sql.DB
+}
+
+// RevokeToken adds an access token id to the revocation list. Entries are
+// only needed until the token would have expired on its own.
+func (r *RevokedTokenRepository) RevokeToken(ctx context.Context, jti string, userID int, expiresAt time.Time) error {
+	if jti == "" || expiresAt.Before(time.Now()) {
+		return nil
+	}
+	_, err := r.DB.ExecContext(ctx, `INSERT IGNORE INTO revoked_tokens (jti, user_id, expires_at) VALUES (?, ?, ?)`, jti, userID, expiresAt)
+	return err
+}
+
+// IsRevoked reports whether the access token id is on the revocation list.
+func (r *RevokedTokenRepository) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	var exists bool
+	err := r.DB.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM revoked_tokens WHERE jti = ?)`, jti).Scan(&exists)
+	return exists, err
+}
+
+// DeleteExpired drops entries whose tokens have expired anyway.
+func (r *RevokedTokenRepository) DeleteExpired(ctx context.Context) error {
+	_, err := r.DB.ExecContext(ctx, `DELETE FROM revoked_tokens WHERE expires_at < ?`, time.Now())
+	return err
+}
@@ -0,0 +1,177 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"workout/internal/models"
+	"workout/utils"
+)
+
+// SessionRepository stores refresh sessions, one row per signed-in device.
+type SessionRepository struct {
+	DB *sql.DB
+}
+
+// CreateSession stores a new session. Only a hash of the refresh token is persisted.
+func (r *SessionRepository) CreateSession(ctx context.Context, s models.Session) (models.Session, error) {
+	s.CreatedAt = time.Now()
+	res, err := r.DB.ExecContext(ctx, `INSERT INTO sessions (user_id, refresh_token_hash, user_agent, ip_address, expires_at, created_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		s.UserID, utils.HashToken(s.RefreshToken), s.UserAgent, s.IPAddress, s.ExpiresAt, s.CreatedAt)
+	if err != nil {
+		return models.Session{}, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return models.Session{}, err
+	}
+	s.ID = int(id)
+	return s, nil
+}
+
+// GetSessionByRefreshToken looks up an active session by its refresh token.
+func (r *SessionRepository) GetSessionByRefreshToken(ctx context.Context, refreshToken string) (models.Session, error) {
+	query := `SELECT id, user_id, user_agent, ip_address, access_jti, expires_at, created_at, last_used_at FROM sessions WHERE refresh_token_hash = ?`
+	s, err := scanSession(r.DB.QueryRowContext(ctx, query, utils.HashToken(refreshToken)))
+	if err != nil {
+		return models.Session{}, err
+	}
+	s.RefreshToken = refreshToken
+	return s, nil
+}
+
+// GetSessionByID fetches a session by its ID.
+func (r *SessionRepository) GetSessionByID(ctx context.Context, id int) (models.Session, error) {
+	query := `SELECT id, user_id, user_agent, ip_address, access_jti, expires_at, created_at, last_used_at FROM sessions WHERE id = ?`
+	return scanSession(r.DB.QueryRowContext(ctx, query, id))
+}
+
+// GetSessionsByUser lists the sessions of a user that have not expired yet.
+func (r *SessionRepository) GetSessionsByUser(ctx context.Context, userID int) ([]models.Session, error) {
+	rows, err := r.DB.QueryContext(ctx, `SELECT id, user_id, user_agent, ip_address, access_jti, expires_at, created_at, last_used_at
+        FROM sessions
+        WHERE user_id = ? AND expires_at > ?
+        ORDER BY COALESCE(last_used_at, created_at) DESC`, userID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []models.Session{}
+	for rows.Next() {
+		s, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, s)
+	}
+	return result, rows.Err()
+}
+
+// SetAccessJTI remembers the id of the latest access token issued for a session.
+func (r *SessionRepository) SetAccessJTI(ctx context.Context, id int, jti string) error {
+	_, err := r.DB.ExecContext(ctx, `UPDATE sessions SET access_jti = ? WHERE id = ?`, jti, id)
+	return err
+}
+
+// RotateRefreshToken replaces the refresh token of a session and remembers the
+// old one so that a later replay of it can be detected.
+func (r *SessionRepository) RotateRefreshToken(ctx context.Context, sessionID int, oldToken, newToken string) error {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	res, err := tx.ExecContext(ctx, `UPDATE sessions SET refresh_token_hash = ?, last_used_at = ? WHERE id = ? AND refresh_token_hash = ?`,
+		utils.HashToken(newToken), now, sessionID, utils.HashToken(oldToken))
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if rows == 0 {
+		// another request rotated the token first
+		tx.Rollback()
+		return models.ErrInvalidRefreshToken
+	}
+
+	if _, err = tx.ExecContext(ctx, `INSERT INTO used_refresh_tokens (token_hash, session_id, used_at) VALUES (?, ?, ?)`,
+		utils.HashToken(oldToken), sessionID, now); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetSessionIDByUsedRefreshToken returns the session an already rotated
+// refresh token belonged to.
+func (r *SessionRepository) GetSessionIDByUsedRefreshToken(ctx context.Context, refreshToken string) (int, error) {
+	var id int
+	err := r.DB.QueryRowContext(ctx, `SELECT session_id FROM used_refresh_tokens WHERE token_hash = ?`, utils.HashToken(refreshToken)).Scan(&id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, models.ErrSessionNotFound
+		}
+		return 0, err
+	}
+	return id, nil
+}
+
+// DeleteSessionByID removes a session regardless of its owner.
+func (r *SessionRepository) DeleteSessionByID(ctx context.Context, id int) error {
+	_, err := r.DB.ExecContext(ctx, `DELETE FROM sessions WHERE id = ?`, id)
+	return err
+}
+
+// DeleteSessionsByUser removes every session of a user.
+func (r *SessionRepository) DeleteSessionsByUser(ctx context.Context, userID int) error {
+	_, err := r.DB.ExecContext(ctx, `DELETE FROM sessions WHERE user_id = ?`, userID)
+	return err
+}
+
+// DeleteSession revokes a session belonging to the given user.
+func (r *SessionRepository) DeleteSession(ctx context.Context, userID, id int) error {
+	res, err := r.DB.ExecContext(ctx, `DELETE FROM sessions WHERE id = ? AND user_id = ?`, id, userID)
+	if err != nil {
+		return err
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return models.ErrSessionNotFound
+	}
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanSession(row rowScanner) (models.Session, error) {
+	var s models.Session
+	var userAgent, ip, jti sql.NullString
+	var lastUsed sql.NullTime
+	err := row.Scan(&s.ID, &s.UserID, &userAgent, &ip, &jti, &s.ExpiresAt, &s.CreatedAt, &lastUsed)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.Session{}, models.ErrSessionNotFound
+		}
+		return models.Session{}, err
+	}
+	s.UserAgent = userAgent.String
+	s.IPAddress = ip.String
+	s.AccessJTI = jti.String
+	if lastUsed.Valid {
+		s.LastUsedAt = &lastUsed.Time
+	}
+	return s, nil
+}
@@ -0,0 +1,262 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"workout/internal/models"
+)
+
+// TrainerApplicationRepository stores applications to become a trainer.
+type TrainerApplicationRepository struct {
+	DB *sql.DB
+}
+
+const applicationColumns = `ta.id, ta.user_id, u.name, u.email, ta.credentials, ta.bio, ta.status,
+    ta.reviewer_id, ta.review_reason, ta.reviewed_at, ta.created_at, ta.updated_at`
+
+// CreateApplication stores a pending application together with its files. A
+// unique key allows one pending application per user, so a concurrent submit
+// fails with ErrApplicationPending.
+func (r *TrainerApplicationRepository) CreateApplication(ctx context.Context, a models.TrainerApplication) (models.TrainerApplication, error) {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return models.TrainerApplication{}, err
+	}
+
+	a.Status = models.ApplicationPending
+	a.CreatedAt = time.Now()
+	res, err := tx.ExecContext(ctx, `INSERT INTO trainer_applications (user_id, credentials, bio, status, created_at) VALUES (?, ?, ?, ?, ?)`,
+		a.UserID, a.Credentials, a.Bio, a.Status, a.CreatedAt)
+	if err != nil {
+		tx.Rollback()
+		if isDuplicateEntry(err) {
+			return models.TrainerApplication{}, models.ErrApplicationPending
+		}
+		return models.TrainerApplication{}, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		tx.Rollback()
+		return models.TrainerApplication{}, err
+	}
+	a.ID = int(id)
+
+	for i := range a.Files {
+		f := &a.Files[i]
+		f.ApplicationID = a.ID
+		f.CreatedAt = a.CreatedAt
+		res, err := tx.ExecContext(ctx, `INSERT INTO trainer_application_files (application_id, file_name, content_type, size, path, created_at) VALUES (?, ?, ?, ?, ?, ?)`,
+			f.ApplicationID, f.FileName, f.ContentType, f.Size, f.Path, f.CreatedAt)
+		if err != nil {
+			tx.Rollback()
+			return models.TrainerApplication{}, err
+		}
+		fid, err := res.LastInsertId()
+		if err != nil {
+			tx.Rollback()
+			return models.TrainerApplication{}, err
+		}
+		f.ID = int(fid)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.TrainerApplication{}, err
+	}
+	return a, nil
+}
+
+// HasPendingApplication reports whether the user has an application awaiting review.
+func (r *TrainerApplicationRepository) HasPendingApplication(ctx context.Context, userID int) (bool, error) {
+	var exists bool
+	err := r.DB.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM trainer_applications WHERE user_id = ? AND status = ?)`,
+		userID, models.ApplicationPending).Scan(&exists)
+	return exists, err
+}
+
+// GetApplication returns an application with its files.
+func (r *TrainerApplicationRepository) GetApplication(ctx context.Context, id int) (models.TrainerApplication, error) {
+	row := r.DB.QueryRowContext(ctx, `SELECT `+applicationColumns+`
+        FROM trainer_applications ta JOIN users u ON u.id = ta.user_id
+        WHERE ta.id = ?`, id)
+	a, err := scanApplication(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.TrainerApplication{}, models.ErrApplicationNotFound
+		}
+		return models.TrainerApplication{}, err
+	}
+	a.Files, err = r.getFiles(ctx, a.ID)
+	return a, err
+}
+
+// ListApplications returns a page of applications with the given status,
+// oldest first so the review queue is worked in order, and the total count.
+func (r *TrainerApplicationRepository) ListApplications(ctx context.Context, status string, limit, offset int) ([]models.TrainerApplication, int, error) {
+	var total int
+	if err := r.DB.QueryRowContext(ctx, `SELECT COUNT(*) FROM trainer_applications WHERE status = ?`, status).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	rows, err := r.DB.QueryContext(ctx, `SELECT `+applicationColumns+`
+        FROM trainer_applications ta JOIN users u ON u.id = ta.user_id
+        WHERE ta.status = ?
+        ORDER BY ta.created_at, ta.id
+        LIMIT ? OFFSET ?`, status, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	apps, err := scanApplications(rows)
+	return apps, total, err
+}
+
+// GetApplicationsByUser lists the applications of a user, newest first.
+func (r *TrainerApplicationRepository) GetApplicationsByUser(ctx context.Context, userID int) ([]models.TrainerApplication, error) {
+	rows, err := r.DB.QueryContext(ctx, `SELECT `+applicationColumns+`
+        FROM trainer_applications ta JOIN users u ON u.id = ta.user_id
+        WHERE ta.user_id = ?
+        ORDER BY ta.created_at DESC, ta.id DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	apps, err := scanApplications(rows)
+	if err != nil {
+		return nil, err
+	}
+	for i := range apps {
+		if apps[i].Files, err = r.getFiles(ctx, apps[i].ID); err != nil {
+			return nil, err
+		}
+	}
+	return apps, nil
+}
+
+// ApproveApplication marks a pending application approved and makes its
+// applicant a trainer in one transaction. Only a client is promoted; if the
+// applicant's role changed since they applied, nothing is approved.
+func (r *TrainerApplicationRepository) ApproveApplication(ctx context.Context, id, reviewerID int) error {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	userID, err := review(ctx, tx, id, reviewerID, models.ApplicationApproved, nil)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	res, err := tx.ExecContext(ctx, `UPDATE users SET role = ?, updated_at = ? WHERE id = ? AND role = ?`,
+		models.RoleTrainer, time.Now(), userID, models.RoleClient)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if rows == 0 {
+		tx.Rollback()
+		return models.ErrApplicantNotClient
+	}
+	return tx.Commit()
+}
+
+// RejectApplication marks a pending application rejected with a reason.
+func (r *TrainerApplicationRepository) RejectApplication(ctx context.Context, id, reviewerID int, reason string) error {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if _, err := review(ctx, tx, id, reviewerID, models.ApplicationRejected, &reason); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetFile returns a file of an application.
+func (r *TrainerApplicationRepository) GetFile(ctx context.Context, applicationID, fileID int) (models.ApplicationFile, error) {
+	var f models.ApplicationFile
+	err := r.DB.QueryRowContext(ctx, `SELECT id, application_id, file_name, content_type, size, path, created_at
+        FROM trainer_application_files WHERE id = ? AND application_id = ?`, fileID, applicationID).
+		Scan(&f.ID, &f.ApplicationID, &f.FileName, &f.ContentType, &f.Size, &f.Path, &f.CreatedAt)
+	if err == sql.ErrNoRows {
+		return models.ApplicationFile{}, models.ErrApplicationNotFound
+	}
+	return f, err
+}
+
+func (r *TrainerApplicationRepository) getFiles(ctx context.Context, applicationID int) ([]models.ApplicationFile, error) {
+	rows, err := r.DB.QueryContext(ctx, `SELECT id, application_id, file_name, content_type, size, path, created_at
+        FROM trainer_application_files WHERE application_id = ? ORDER BY id`, applicationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	files := []models.ApplicationFile{}
+	for rows.Next() {
+		var f models.ApplicationFile
+		if err := rows.Scan(&f.ID, &f.ApplicationID, &f.FileName, &f.ContentType, &f.Size, &f.Path, &f.CreatedAt); err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	return files, rows.Err()
+}
+
+// review moves a pending application to status and returns its applicant.
+func review(ctx context.Context, tx *sql.Tx, id, reviewerID int, status string, reason *string) (int, error) {
+	var userID int
+	var current string
+	err := tx.QueryRowContext(ctx, `SELECT user_id, status FROM trainer_applications WHERE id = ? FOR UPDATE`, id).Scan(&userID, &current)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, models.ErrApplicationNotFound
+		}
+		return 0, err
+	}
+	if current != models.ApplicationPending {
+		return 0, models.ErrApplicationReviewed
+	}
+	_, err = tx.ExecContext(ctx, `UPDATE trainer_applications SET status = ?, reviewer_id = ?, review_reason = ?, reviewed_at = ? WHERE id = ?`,
+		status, reviewerID, reason, time.Now(), id)
+	return userID, err
+}
+
+func scanApplications(rows *sql.Rows) ([]models.TrainerApplication, error) {
+	defer rows.Close()
+	apps := []models.TrainerApplication{}
+	for rows.Next() {
+		a, err := scanApplication(rows)
+		if err != nil {
+			return nil, err
+		}
+		apps = append(apps, a)
+	}
+	return apps, rows.Err()
+}
+
+func scanApplication(row rowScanner) (models.TrainerApplication, error) {
+	var a models.TrainerApplication
+	var reviewerID sql.NullInt64
+	var reason sql.NullString
+	var reviewedAt sql.NullTime
+	err := row.Scan(&a.ID, &a.UserID, &a.UserName, &a.UserEmail, &a.Credentials, &a.Bio, &a.Status,
+		&reviewerID, &reason, &reviewedAt, &a.CreatedAt, &a.UpdatedAt)
+	if err != nil {
+		return models.TrainerApplication{}, err
+	}
+	if reviewerID.Valid {
+		id := int(reviewerID.Int64)
+		a.ReviewerID = &id
+	}
+	if reason.Valid {
+		a.ReviewReason = &reason.String
+	}
+	if reviewedAt.Valid {
+		a.ReviewedAt = &reviewedAt.Time
+	}
+	return a, nil
+}
@@ -0,0 +1,117 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"workout/internal/models"
+	"workout/utils"
+)
+
+// TwoFactorRepository stores TOTP secrets and recovery codes.
+type TwoFactorRepository struct {
+	DB *sql.DB
+}
+
+// GetTwoFactor returns the TOTP settings of a user, enabled or pending.
+func (r *TwoFactorRepository) GetTwoFactor(ctx context.Context, userID int) (models.TwoFactor, error) {
+	var tf models.TwoFactor
+	var enabledAt sql.NullTime
+	err := r.DB.QueryRowContext(ctx, `SELECT user_id, secret, last_used_step, enabled_at, created_at FROM user_totp WHERE user_id = ?`, userID).
+		Scan(&tf.UserID, &tf.Secret, &tf.LastUsedStep, &enabledAt, &tf.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.TwoFactor{}, models.ErrTwoFactorNotEnabled
+		}
+		return models.TwoFactor{}, err
+	}
+	if enabledAt.Valid {
+		tf.EnabledAt = &enabledAt.Time
+	}
+	return tf, nil
+}
+
+// SavePendingSecret stores a new, not yet confirmed secret for the user.
+func (r *TwoFactorRepository) SavePendingSecret(ctx context.Context, userID int, secret string) error {
+	_, err := r.DB.ExecContext(ctx, `INSERT INTO user_totp (user_id, secret, last_used_step, enabled_at, created_at) VALUES (?, ?, 0, NULL, ?)
+        ON DUPLICATE KEY UPDATE secret = VALUES(secret), last_used_step = 0, enabled_at = NULL, created_at = VALUES(created_at)`,
+		userID, secret, time.Now())
+	return err
+}
+
+// Enable marks the user's secret as confirmed.
+func (r *TwoFactorRepository) Enable(ctx context.Context, userID int) error {
+	_, err := r.DB.ExecContext(ctx, `UPDATE user_totp SET enabled_at = ? WHERE user_id = ?`, time.Now(), userID)
+	return err
+}
+
+// Disable removes the TOTP secret and all recovery codes of the user.
+func (r *TwoFactorRepository) Disable(ctx context.Context, userID int) error {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, `DELETE FROM totp_recovery_codes WHERE user_id = ?`, userID); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, `DELETE FROM user_totp WHERE user_id = ?`, userID); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// ConsumeStep records the time step of an accepted code. It fails when the
+// step was already used so a code cannot be replayed.
+func (r *TwoFactorRepository) ConsumeStep(ctx context.Context, userID int, step int64) error {
+	res, err := r.DB.ExecContext(ctx, `UPDATE user_totp SET last_used_step = ? WHERE user_id = ? AND last_used_step < ?`, step, userID, step)
+	if err != nil {
+		return err
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return models.ErrInvalidTOTPCode
+	}
+	return nil
+}
+
+// ReplaceRecoveryCodes drops the user's recovery codes and stores new ones.
+func (r *TwoFactorRepository) ReplaceRecoveryCodes(ctx context.Context, userID int, codes []string) error {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, `DELETE FROM totp_recovery_codes WHERE user_id = ?`, userID); err != nil {
+		tx.Rollback()
+		return err
+	}
+	for _, code := range codes {
+		if _, err = tx.ExecContext(ctx, `INSERT INTO totp_recovery_codes (user_id, code_hash) VALUES (?, ?)`, userID, utils.HashToken(code)); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// UseRecoveryCode consumes one unused recovery code of the user.
+func (r *TwoFactorRepository) UseRecoveryCode(ctx context.Context, userID int, code string) error {
+	res, err := r.DB.ExecContext(ctx, `UPDATE totp_recovery_codes SET used_at = ? WHERE user_id = ? AND code_hash = ? AND used_at IS NULL`,
+		time.Now(), userID, utils.HashToken(code))
+	if err != nil {
+		return err
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return models.ErrInvalidTOTPCode
+	}
+	return nil
+}
@@ -5,9 +5,11 @@ import (
 	"database/sql"
 	"errors"
 	_ "fmt"
-	_ "strings"
+	"strings"
 	"time"
 	_ "time"
+
+	"github.com/go-sql-driver/mysql"
 	"workout/internal/models"
 )
 
@@ -15,6 +17,15 @@ var (
 	ErrUserNotFound = errors.New("user not found")
 )
 
+// mysqlDuplicateEntry is the MySQL error number of unique key violations.
+const mysqlDuplicateEntry = 1062
+
+// isDuplicateEntry reports whether err is a unique key violation.
+func isDuplicateEntry(err error) bool {
+	var me *mysql.MySQLError
+	return errors.As(err, &me) && me.Number == mysqlDuplicateEntry
+}
+
 type UserRepository struct {
 	DB *sql.DB
 }
@@ -24,60 +35,16 @@ type Session struct {
 	Expiry string `json:"expiry"`
 }
 
-func (r *UserRepository) SetSession(ctx context.Context, id string, session models.Session) error {
-
-	query := `
-		UPDATE users 
-		SET refresh_token = ?, expires_at = ? 
-		WHERE id = ?
-	`
-
-	result, err := r.DB.ExecContext(ctx, query, session.RefreshToken, session.ExpiresAt, id)
-	if err != nil {
-		return err
-	}
-
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return err
-	}
-
-	if rowsAffected == 0 {
-		return errors.New("no rows updated")
-	}
-
-	return nil
-}
-
-func (r *UserRepository) GetSession(ctx context.Context, id string) (models.Session, error) {
-	query := `
-		SELECT refresh_token, expires_at
-		FROM users
-		WHERE id = ?
-	`
-
-	var session models.Session
-	err := r.DB.QueryRowContext(ctx, query, id).Scan(&session.RefreshToken, &session.ExpiresAt)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return session, errors.New("no session found for the user")
-		}
-		return session, err
-	}
-
-	return session, nil
-}
-
 func (r *UserRepository) GetUserByEmail(ctx context.Context, email string) (models.User, error) {
 	var user models.User
 	query := `
-        SELECT id, name, phone, email, password, role, created_at, updated_at
+        SELECT id, name, phone, email, password, role, suspended_at, password_reset_required, deletion_scheduled_at, created_at, updated_at
         FROM users
         WHERE email = ?
     `
 	err := r.DB.QueryRowContext(ctx, query, email).Scan(
 		&user.ID, &user.Name, &user.Phone, &user.Email, &user.Password,
-		&user.Role,
+		&user.Role, &user.SuspendedAt, &user.PasswordResetRequired, &user.DeletionScheduledAt,
 		&user.CreatedAt, &user.UpdatedAt,
 	)
 	if err == sql.ErrNoRows {
@@ -92,8 +59,9 @@ func (r *UserRepository) GetUserByEmail(ctx context.Context, email string) (mode
 // GetUserByID retrieves a user by id.
 func (r *UserRepository) GetUserByID(ctx context.Context, id int) (models.User, error) {
 	var user models.User
-	query := `SELECT id, name, phone, email, password, role, created_at, updated_at FROM users WHERE id = ?`
-	err := r.DB.QueryRowContext(ctx, query, id).Scan(&user.ID, &user.Name, &user.Phone, &user.Email, &user.Password, &user.Role, &user.CreatedAt, &user.UpdatedAt)
+	query := `SELECT id, name, phone, email, password, role, suspended_at, password_reset_required, deletion_scheduled_at, created_at, updated_at FROM users WHERE id = ?`
+	err := r.DB.QueryRowContext(ctx, query, id).Scan(&user.ID, &user.Name, &user.Phone, &user.Email, &user.Password, &user.Role,
+		&user.SuspendedAt, &user.PasswordResetRequired, &user.DeletionScheduledAt, &user.CreatedAt, &user.UpdatedAt)
 	if err == sql.ErrNoRows {
 		return models.User{}, ErrUserNotFound
 	}
@@ -110,6 +78,9 @@ func (r *UserRepository) UpdateUser(ctx context.Context, u models.User) (models.
 	query := `UPDATE users SET name=?, phone=?, email=?, password=?, updated_at=? WHERE id=?`
 	res, err := r.DB.ExecContext(ctx, query, u.Name, u.Phone, u.Email, u.Password, u.UpdatedAt, u.ID)
 	if err != nil {
+		if isDuplicateEntry(err) {
+			return models.User{}, models.ErrEmailTaken
+		}
 		return models.User{}, err
 	}
 	rows, err := res.RowsAffected()
@@ -122,6 +93,23 @@ func (r *UserRepository) UpdateUser(ctx context.Context, u models.User) (models.
 	return u, nil
 }
 
+// UpdatePassword sets a new password hash for a user and clears a pending
+// forced reset.
+func (r *UserRepository) UpdatePassword(ctx context.Context, userID int, hashedPassword string) error {
+	res, err := r.DB.ExecContext(ctx, `UPDATE users SET password = ?, password_reset_required = FALSE, updated_at = ? WHERE id = ?`, hashedPassword, time.Now(), userID)
+	if err != nil {
+		return err
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
 func (r *UserRepository) CreateUser(ctx context.Context, user models.User) (models.User, error) {
 	query := `
         INSERT INTO users (name, phone, email, password, role, created_at, updated_at)
@@ -134,6 +122,9 @@ func (r *UserRepository) CreateUser(ctx context.Context, user models.User) (mode
 		user.CreatedAt, user.UpdatedAt,
 	)
 	if err != nil {
+		if isDuplicateEntry(err) {
+			return models.User{}, models.ErrEmailTaken
+		}
 		return models.User{}, err
 	}
 
@@ -145,16 +136,46 @@ func (r *UserRepository) CreateUser(ctx context.Context, user models.User) (mode
 	return user, nil
 }
 
-func (r *UserRepository) GetVerificationCodeByEmail(ctx context.Context, email string) (string, error) {
-	var code string
-	err := r.DB.QueryRowContext(ctx, `SELECT code FROM verification_codes WHERE email = ?`, email).Scan(&code)
+// GetVerificationCode returns the latest verification code issued for an email.
+func (r *UserRepository) GetVerificationCode(ctx context.Context, email string) (models.VerificationCodeEntry, error) {
+	var entry models.VerificationCodeEntry
+	var expires sql.NullTime
+	err := r.DB.QueryRowContext(ctx, `SELECT id, email, code, attempts, expires_at, created_at FROM verification_codes WHERE email = ? ORDER BY id DESC LIMIT 1`, email).
+		Scan(&entry.ID, &entry.Email, &entry.Code, &entry.Attempts, &expires, &entry.CreatedAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return "", models.ErrInvalidVerificationCode
+			return models.VerificationCodeEntry{}, models.ErrInvalidVerificationCode
 		}
-		return "", err
+		return models.VerificationCodeEntry{}, err
+	}
+	if expires.Valid {
+		entry.ExpiresAt = expires.Time
+	}
+	return entry, nil
+}
+
+// SaveVerificationCode replaces any previous code of the email with a new one.
+func (r *UserRepository) SaveVerificationCode(ctx context.Context, entry models.VerificationCodeEntry) error {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, `DELETE FROM verification_codes WHERE email = ?`, entry.Email); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, `INSERT INTO verification_codes (email, code, attempts, expires_at, created_at) VALUES (?, ?, 0, ?, ?)`,
+		entry.Email, entry.Code, entry.ExpiresAt, entry.CreatedAt); err != nil {
+		tx.Rollback()
+		return err
 	}
-	return code, nil
+	return tx.Commit()
+}
+
+// IncrementVerificationAttempts records a failed attempt to use a code.
+func (r *UserRepository) IncrementVerificationAttempts(ctx context.Context, id int) error {
+	_, err := r.DB.ExecContext(ctx, `UPDATE verification_codes SET attempts = attempts + 1 WHERE id = ?`, id)
+	return err
 }
 
 // ClearVerificationCode removes a verification code record for an email.
@@ -211,26 +232,38 @@ func (r *UserRepository) GetClientsByProgramID(ctx context.Context, programID in
 	return result, rows.Err()
 }
 
+// AddClientToProgram creates empty progress for the days of the version the
+// client's enrollment is pinned to.
 func (r *UserRepository) AddClientToProgram(ctx context.Context, programID, clientID int) error {
 	query := `INSERT IGNORE INTO progress (client_id, day_id, food_completed, exercise_completed)
-               SELECT ?, d.id, FALSE, FALSE FROM days d WHERE d.work_out_program_id = ?`
+               SELECT pi.client_id, d.id, FALSE, FALSE
+               FROM program_invites pi
+               JOIN days d ON d.work_out_program_id = pi.program_id AND d.program_version_id <=> pi.version_id
+               WHERE pi.client_id = ? AND pi.program_id = ? AND pi.accepted_at IS NOT NULL`
 	_, err := r.DB.ExecContext(ctx, query, clientID, programID)
 	return err
 }
 
+// DeleteClientFromProgram ends the client's enrollment and deletes their
+// progress. The invite is revoked so its link cannot enroll them again.
 func (r *UserRepository) DeleteClientFromProgram(ctx context.Context, programID, clientID int) error {
-	if _, err := r.DB.ExecContext(ctx, `UPDATE program_invites SET client_id=NULL, accepted_at=NULL, access_expires=NULL, updated_at=NOW() WHERE program_id=? AND client_id=?`, programID, clientID); err != nil {
+	if _, err := r.DB.ExecContext(ctx, `UPDATE program_invites SET client_id=NULL, accepted_at=NULL, access_expires=NULL, expired_at=NULL, revoked_at=NOW(), updated_at=NOW() WHERE program_id=? AND client_id=?`, programID, clientID); err != nil {
 		return err
 	}
 	_, err := r.DB.ExecContext(ctx, `DELETE p FROM progress p JOIN days d ON p.day_id = d.id WHERE d.work_out_program_id = ? AND p.client_id = ?`, programID, clientID)
 	return err
 }
 
-func (r *UserRepository) GetProgramsByClientID(ctx context.Context, clientID int) ([]models.WorkOutProgram, error) {
+// GetProgramsByClientID lists the programs the client is enrolled in. With
+// activeOnly, programs whose access has expired are left out.
+func (r *UserRepository) GetProgramsByClientID(ctx context.Context, clientID int, activeOnly bool) ([]models.WorkOutProgram, error) {
 	query := `SELECT wp.id, wp.trainer_id, wp.name, wp.days, wp.description, wp.created_at, wp.updated_at
                  FROM workout_programs wp
                  JOIN program_invites pi ON pi.program_id = wp.id
                  WHERE pi.client_id = ? AND pi.accepted_at IS NOT NULL`
+	if activeOnly {
+		query += ` AND (pi.access_expires IS NULL OR pi.access_expires > NOW())`
+	}
 	rows, err := r.DB.QueryContext(ctx, query, clientID)
 	if err != nil {
 		return nil, err
@@ -247,3 +280,131 @@ func (r *UserRepository) GetProgramsByClientID(ctx context.Context, clientID int
 	}
 	return result, rows.Err()
 }
+
+// IsClientOfTrainer reports whether the client has accepted an invite to any
+// program of the trainer.
+func (r *UserRepository) IsClientOfTrainer(ctx context.Context, clientID, trainerID int) (bool, error) {
+	var exists bool
+	err := r.DB.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM program_invites pi
+        JOIN workout_programs wp ON wp.id = pi.program_id
+        WHERE pi.client_id = ? AND wp.trainer_id = ? AND pi.accepted_at IS NOT NULL)`, clientID, trainerID).Scan(&exists)
+	return exists, err
+}
+
+// IsClientInProgram reports whether the client has accepted an invite to the program.
+func (r *UserRepository) IsClientInProgram(ctx context.Context, clientID, programID int) (bool, error) {
+	var exists bool
+	err := r.DB.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM program_invites
+        WHERE client_id = ? AND program_id = ? AND accepted_at IS NOT NULL)`, clientID, programID).Scan(&exists)
+	return exists, err
+}
+
+// ClientProgramAccess reports whether the client has accepted an invite to
+// the program and whether that access is still within its window.
+func (r *UserRepository) ClientProgramAccess(ctx context.Context, clientID, programID int) (enrolled, active bool, err error) {
+	err = r.DB.QueryRowContext(ctx, `SELECT COUNT(*) > 0,
+        COALESCE(MAX(access_expires IS NULL OR access_expires > NOW()), 0)
+        FROM program_invites
+        WHERE client_id = ? AND program_id = ? AND accepted_at IS NOT NULL`, clientID, programID).Scan(&enrolled, &active)
+	return enrolled, active, err
+}
+
+// ListUsers returns a page of users matching the filter, newest first, and
+// the total number of matches. Query matches name, email and phone.
+func (r *UserRepository) ListUsers(ctx context.Context, f models.UserFilter) ([]models.User, int, error) {
+	var where []string
+	var args []interface{}
+	if f.Query != "" {
+		like := "%" + strings.NewReplacer("\\", "\\\\", "%", "\\%", "_", "\\_").Replace(f.Query) + "%"
+		where = append(where, "(name LIKE ? OR email LIKE ? OR phone LIKE ?)")
+		args = append(args, like, like, like)
+	}
+	if f.Role != "" {
+		where = append(where, "role = ?")
+		args = append(args, f.Role)
+	}
+	if f.Suspended != nil {
+		if *f.Suspended {
+			where = append(where, "suspended_at IS NOT NULL")
+		} else {
+			where = append(where, "suspended_at IS NULL")
+		}
+	}
+	cond := ""
+	if len(where) > 0 {
+		cond = " WHERE " + strings.Join(where, " AND ")
+	}
+
+	var total int
+	if err := r.DB.QueryRowContext(ctx, `SELECT COUNT(*) FROM users`+cond, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `SELECT id, name, COALESCE(phone, ''), email, role, suspended_at, password_reset_required, created_at, updated_at
+              FROM users` + cond + ` ORDER BY id DESC LIMIT ? OFFSET ?`
+	rows, err := r.DB.QueryContext(ctx, query, append(args, f.PerPage, (f.Page-1)*f.PerPage)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	users := []models.User{}
+	for rows.Next() {
+		var u models.User
+		if err := rows.Scan(&u.ID, &u.Name, &u.Phone, &u.Email, &u.Role, &u.SuspendedAt, &u.PasswordResetRequired,
+			&u.CreatedAt, &u.UpdatedAt); err != nil {
+			return nil, 0, err
+		}
+		users = append(users, u)
+	}
+	return users, total, rows.Err()
+}
+
+// GetEnrollments lists the programs a client has accepted invites to.
+func (r *UserRepository) GetEnrollments(ctx context.Context, clientID int) ([]models.Enrollment, error) {
+	query := `SELECT wp.id, wp.name, wp.trainer_id, pi.accepted_at, pi.access_expires
+              FROM program_invites pi
+              JOIN workout_programs wp ON wp.id = pi.program_id
+              WHERE pi.client_id = ? AND pi.accepted_at IS NOT NULL
+              ORDER BY pi.accepted_at DESC`
+	rows, err := r.DB.QueryContext(ctx, query, clientID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []models.Enrollment{}
+	for rows.Next() {
+		var e models.Enrollment
+		if err := rows.Scan(&e.ProgramID, &e.ProgramName, &e.TrainerID, &e.AcceptedAt, &e.AccessExpires); err != nil {
+			return nil, err
+		}
+		result = append(result, e)
+	}
+	return result, rows.Err()
+}
+
+// SetSuspended suspends the user at the given time, or reactivates them when
+// at is nil.
+func (r *UserRepository) SetSuspended(ctx context.Context, userID int, at *time.Time) error {
+	_, err := r.DB.ExecContext(ctx, `UPDATE users SET suspended_at = ?, updated_at = ? WHERE id = ?`, at, time.Now(), userID)
+	return err
+}
+
+// SetPasswordResetRequired flags the user so they cannot sign in until they
+// reset their password.
+func (r *UserRepository) SetPasswordResetRequired(ctx context.Context, userID int, required bool) error {
+	_, err := r.DB.ExecContext(ctx, `UPDATE users SET password_reset_required = ?, updated_at = ? WHERE id = ?`, required, time.Now(), userID)
+	return err
+}
+
+// GetAccountStatus returns the current role of the user and whether the
+// account is suspended.
+func (r *UserRepository) GetAccountStatus(ctx context.Context, userID int) (role string, suspended bool, err error) {
+	var suspendedAt sql.NullTime
+	err = r.DB.QueryRowContext(ctx, `SELECT role, suspended_at FROM users WHERE id = ?`, userID).Scan(&role, &suspendedAt)
+	if err == sql.ErrNoRows {
+		return "", false, ErrUserNotFound
+	}
+	return role, suspendedAt.Valid, err
+}
@@ -0,0 +1,209 @@
+package services
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"workout/internal/mailer"
+	"workout/internal/models"
+	"workout/internal/repositories"
+)
+
+const (
+	// accountDeletionGrace is how long a user can change their mind.
+	accountDeletionGrace = 30 * 24 * time.Hour
+	purgeBatchSize       = 50
+)
+
+// AccountService exports and deletes the personal data of users.
+type AccountService struct {
+	Repo            *repositories.AccountRepository
+	UserRepo        *repositories.UserRepository
+	ProgramRepo     *repositories.ProgramRepository
+	SessionRepo     *repositories.SessionRepository
+	APITokenRepo    *repositories.APITokenRepository
+	ApplicationRepo *repositories.TrainerApplicationRepository
+	Users           *UserService
+	Mailer          mailer.Mailer
+	// UploadsDir is the private directory uploaded files are stored in.
+	UploadsDir string
+}
+
+// Export writes a ZIP archive with everything stored about the user:
+// data.json and the files they uploaded.
+func (s *AccountService) Export(ctx context.Context, userID int, w io.Writer) error {
+	data, err := s.collect(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	zw := zip.NewWriter(w)
+	f, err := zw.Create("data.json")
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(data); err != nil {
+		return err
+	}
+
+	for _, app := range data.TrainerApplications {
+		for _, file := range app.Files {
+			name := fmt.Sprintf("uploads/applications/%d/%d_%s", app.ID, file.ID, filepath.Base(file.FileName))
+			if err := s.addFile(zw, name, filepath.Join(s.UploadsDir, file.Path)); err != nil {
+				return err
+			}
+		}
+	}
+	return zw.Close()
+}
+
+func (s *AccountService) collect(ctx context.Context, userID int) (models.AccountExport, error) {
+	user, err := s.UserRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return models.AccountExport{}, err
+	}
+	user.Password = ""
+	data := models.AccountExport{ExportedAt: time.Now(), Profile: user}
+
+	if data.Enrollments, err = s.UserRepo.GetEnrollments(ctx, userID); err != nil {
+		return data, err
+	}
+	if data.Invites, err = s.Repo.GetInvites(ctx, userID, user.Email); err != nil {
+		return data, err
+	}
+	if data.Progress, err = s.Repo.GetProgress(ctx, userID); err != nil {
+		return data, err
+	}
+	if data.Programs, err = s.ProgramRepo.GetProgramsByTrainer(ctx, userID); err != nil {
+		return data, err
+	}
+	if data.TrainerApplications, err = s.ApplicationRepo.GetApplicationsByUser(ctx, userID); err != nil {
+		return data, err
+	}
+	if data.Sessions, err = s.SessionRepo.GetSessionsByUser(ctx, userID); err != nil {
+		return data, err
+	}
+	if data.APITokens, err = s.APITokenRepo.GetTokensByUser(ctx, userID); err != nil {
+		return data, err
+	}
+	if data.Identities, err = s.Repo.GetIdentities(ctx, userID); err != nil {
+		return data, err
+	}
+	return data, nil
+}
+
+func (s *AccountService) addFile(zw *zip.Writer, name, path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			log.Printf("Export: uploaded file %s is missing", path)
+			return nil
+		}
+		return err
+	}
+	defer src.Close()
+
+	dst, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(dst, src)
+	return err
+}
+
+// RequestDeletion schedules the account for deletion after a grace period.
+// Accounts with a password must confirm it.
+func (s *AccountService) RequestDeletion(ctx context.Context, userID int, password string) (models.AccountDeletion, error) {
+	user, err := s.UserRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return models.AccountDeletion{}, err
+	}
+	if user.Password != "" && bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)) != nil {
+		return models.AccountDeletion{}, models.ErrInvalidCredentials
+	}
+
+	at := time.Now().Add(accountDeletionGrace)
+	if user.DeletionScheduledAt != nil {
+		at = *user.DeletionScheduledAt
+	} else if err := s.Repo.ScheduleDeletion(ctx, userID, &at); err != nil {
+		return models.AccountDeletion{}, err
+	}
+
+	err = s.Mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Your account will be deleted",
+		Body: fmt.Sprintf("Your account is scheduled for deletion on %s.\n\nSign in and cancel the deletion before then if you change your mind.\n",
+			at.Format("2006-01-02")),
+	})
+	if err != nil {
+		log.Printf("Failed to send deletion notice to user %d: %v", userID, err)
+	}
+	return models.AccountDeletion{ScheduledAt: at}, nil
+}
+
+// CancelDeletion keeps the account.
+func (s *AccountService) CancelDeletion(ctx context.Context, userID int) error {
+	user, err := s.UserRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.DeletionScheduledAt == nil {
+		return models.ErrDeletionNotScheduled
+	}
+	return s.Repo.ScheduleDeletion(ctx, userID, nil)
+}
+
+// PurgeDue anonymizes accounts whose grace period has ended and returns how
+// many were processed.
+func (s *AccountService) PurgeDue(ctx context.Context) (int, error) {
+	ids, err := s.Repo.GetDueDeletions(ctx, time.Now(), purgeBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	for i, id := range ids {
+		if err := s.purge(ctx, id); err != nil {
+			return i, fmt.Errorf("purge user %d: %w", id, err)
+		}
+	}
+	return len(ids), nil
+}
+
+func (s *AccountService) purge(ctx context.Context, userID int) error {
+	user, err := s.UserRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	apps, err := s.ApplicationRepo.GetApplicationsByUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.Users.LogoutEverywhere(ctx, userID, "", time.Time{}); err != nil {
+		return err
+	}
+	if err := s.Users.Lockout.Unlock(ctx, user.Email); err != nil {
+		return err
+	}
+	if err := s.Repo.Anonymize(ctx, userID, user.Email); err != nil {
+		return err
+	}
+
+	for _, app := range apps {
+		for _, f := range app.Files {
+			if err := os.Remove(filepath.Join(s.UploadsDir, f.Path)); err != nil && !os.IsNotExist(err) {
+				log.Printf("Failed to remove upload %s of deleted user %d: %v", f.Path, userID, err)
+			}
+		}
+	}
+	return nil
+}
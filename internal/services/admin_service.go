@@ -0,0 +1,163 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"workout/internal/models"
+	"workout/internal/repositories"
+)
+
+const (
+	defaultUsersPerPage = 20
+	maxUsersPerPage     = 100
+)
+
+// AdminService implements user management for administrators.
+type AdminService struct {
+	UserRepo    *repositories.UserRepository
+	ProgramRepo *repositories.ProgramRepository
+	Users       *UserService
+	Audit       *AuditService
+}
+
+// ListUsers returns a page of users matching the filter.
+func (s *AdminService) ListUsers(ctx context.Context, f models.UserFilter) (models.UserList, error) {
+	if f.Page < 1 {
+		f.Page = 1
+	}
+	if f.PerPage < 1 {
+		f.PerPage = defaultUsersPerPage
+	}
+	if f.PerPage > maxUsersPerPage {
+		f.PerPage = maxUsersPerPage
+	}
+	if f.Role != "" && !models.ValidRole(f.Role) {
+		return models.UserList{}, models.ErrInvalidRole
+	}
+	users, total, err := s.UserRepo.ListUsers(ctx, f)
+	if err != nil {
+		return models.UserList{}, err
+	}
+	return models.UserList{Users: users, Total: total, Page: f.Page, PerPage: f.PerPage}, nil
+}
+
+// UserDetails returns a user with the programs they own and the programs
+// they are enrolled in.
+func (s *AdminService) UserDetails(ctx context.Context, userID int) (models.UserDetails, error) {
+	user, err := s.UserRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return models.UserDetails{}, err
+	}
+	user.Password = ""
+
+	programs, err := s.ProgramRepo.GetProgramsByTrainer(ctx, userID)
+	if err != nil {
+		return models.UserDetails{}, err
+	}
+	enrollments, err := s.UserRepo.GetEnrollments(ctx, userID)
+	if err != nil {
+		return models.UserDetails{}, err
+	}
+	return models.UserDetails{User: user, Programs: programs, Enrollments: enrollments}, nil
+}
+
+// ChangeRole sets the role of a user. Admins cannot demote themselves so
+// that the last admin cannot lock everyone out by accident.
+func (s *AdminService) ChangeRole(ctx context.Context, actor models.Actor, userID int, role string) (models.User, error) {
+	if !models.ValidRole(role) {
+		return models.User{}, models.ErrInvalidRole
+	}
+	if userID == actor.UserID && role != models.RoleAdmin {
+		return models.User{}, models.ErrCannotAlterSelf
+	}
+	user, err := s.UserRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return models.User{}, err
+	}
+	if err := s.UserRepo.UpdateUserRole(ctx, userID, role); err != nil {
+		return models.User{}, err
+	}
+	s.Audit.RecordActor(ctx, actor.UserID, models.AuditRoleChanged, models.AuditTargetUser, userID,
+		map[string]interface{}{"from": user.Role, "to": role})
+	user.Role = role
+	user.Password = ""
+	return user, nil
+}
+
+// Suspend blocks the user from signing in and ends all of their sessions.
+func (s *AdminService) Suspend(ctx context.Context, actor models.Actor, userID int) error {
+	if userID == actor.UserID {
+		return models.ErrCannotAlterSelf
+	}
+	user, err := s.UserRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.SuspendedAt == nil {
+		now := time.Now()
+		if err := s.UserRepo.SetSuspended(ctx, userID, &now); err != nil {
+			return err
+		}
+		s.Audit.RecordActor(ctx, actor.UserID, models.AuditUserSuspended, models.AuditTargetUser, userID, nil)
+	}
+	return s.Users.LogoutEverywhere(ctx, userID, "", time.Time{})
+}
+
+// Reactivate lifts a suspension.
+func (s *AdminService) Reactivate(ctx context.Context, actor models.Actor, userID int) error {
+	user, err := s.UserRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.SuspendedAt == nil {
+		return nil
+	}
+	if err := s.UserRepo.SetSuspended(ctx, userID, nil); err != nil {
+		return err
+	}
+	s.Audit.RecordActor(ctx, actor.UserID, models.AuditUserReactivated, models.AuditTargetUser, userID, nil)
+	return nil
+}
+
+// Impersonate issues a short-lived token that lets the admin see the app as
+// the user. Other admins cannot be impersonated so that support staff cannot
+// gain extra privileges this way.
+func (s *AdminService) Impersonate(ctx context.Context, actor models.Actor, userID int, reason string) (models.ImpersonationToken, error) {
+	if userID == actor.UserID {
+		return models.ImpersonationToken{}, models.ErrCannotImpersonate
+	}
+	user, err := s.UserRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return models.ImpersonationToken{}, err
+	}
+	if user.Role == models.RoleAdmin || user.SuspendedAt != nil {
+		return models.ImpersonationToken{}, models.ErrCannotImpersonate
+	}
+
+	token, expiresAt, err := s.Users.IssueImpersonationToken(user, actor.UserID)
+	if err != nil {
+		return models.ImpersonationToken{}, err
+	}
+	s.Audit.RecordActor(ctx, actor.UserID, models.AuditImpersonation, models.AuditTargetUser, userID,
+		map[string]interface{}{"reason": strings.TrimSpace(reason), "expires_at": expiresAt})
+	return models.ImpersonationToken{AccessToken: token, UserID: userID, ImpersonatorID: actor.UserID, ExpiresAt: expiresAt}, nil
+}
+
+// ForcePasswordReset signs the user out everywhere and blocks sign in until
+// they set a new password using the reset token emailed to them.
+func (s *AdminService) ForcePasswordReset(ctx context.Context, actor models.Actor, userID int) error {
+	user, err := s.UserRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := s.UserRepo.SetPasswordResetRequired(ctx, userID, true); err != nil {
+		return err
+	}
+	s.Audit.RecordActor(ctx, actor.UserID, models.AuditPasswordReset, models.AuditTargetUser, userID, nil)
+	if err := s.Users.RevokeAllAccess(ctx, userID); err != nil {
+		return err
+	}
+	return s.Users.RequestPasswordReset(ctx, user.Email)
+}
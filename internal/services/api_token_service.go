@@ -0,0 +1,95 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"workout/internal/models"
+	"workout/internal/repositories"
+)
+
+// APITokenPrefix marks personal access tokens so they can be told apart from JWTs.
+const APITokenPrefix = "wkt_"
+
+// APITokenService manages personal access tokens for integrations.
+type APITokenService struct {
+	Repo     *repositories.APITokenRepository
+	UserRepo *repositories.UserRepository
+}
+
+// CreateToken issues a new named token with the requested scopes. The raw
+// token is part of the result and cannot be retrieved later.
+func (s *APITokenService) CreateToken(ctx context.Context, userID int, req models.CreateAPITokenRequest) (models.APIToken, error) {
+	if strings.TrimSpace(req.Name) == "" || len(req.Scopes) == 0 {
+		return models.APIToken{}, models.ErrInvalidScope
+	}
+	for _, scope := range req.Scopes {
+		if !validScope(scope) {
+			return models.APIToken{}, models.ErrInvalidScope
+		}
+	}
+
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		return models.APIToken{}, err
+	}
+	raw := APITokenPrefix + hex.EncodeToString(b)
+
+	t := models.APIToken{
+		UserID: userID,
+		Name:   strings.TrimSpace(req.Name),
+		Token:  raw,
+		Prefix: raw[:len(APITokenPrefix)+6],
+		Scopes: req.Scopes,
+	}
+	if req.ExpiresInDays > 0 {
+		expires := time.Now().Add(time.Duration(req.ExpiresInDays) * 24 * time.Hour)
+		t.ExpiresAt = &expires
+	}
+	return s.Repo.CreateToken(ctx, t)
+}
+
+func (s *APITokenService) TokensByUser(ctx context.Context, userID int) ([]models.APIToken, error) {
+	return s.Repo.GetTokensByUser(ctx, userID)
+}
+
+func (s *APITokenService) RevokeToken(ctx context.Context, userID, id int) error {
+	return s.Repo.RevokeToken(ctx, userID, id)
+}
+
+// Authenticate resolves a raw token to its owner. The owner's current role is
+// used, so a demoted trainer's tokens lose trainer access too.
+func (s *APITokenService) Authenticate(ctx context.Context, raw string) (models.User, models.APIToken, error) {
+	t, err := s.Repo.GetTokenByValue(ctx, raw)
+	if err != nil {
+		if errors.Is(err, models.ErrAPITokenNotFound) {
+			return models.User{}, models.APIToken{}, models.ErrInvalidAPIToken
+		}
+		return models.User{}, models.APIToken{}, err
+	}
+	if t.RevokedAt != nil || (t.ExpiresAt != nil && t.ExpiresAt.Before(time.Now())) {
+		return models.User{}, models.APIToken{}, models.ErrInvalidAPIToken
+	}
+
+	user, err := s.UserRepo.GetUserByID(ctx, t.UserID)
+	if err != nil {
+		return models.User{}, models.APIToken{}, err
+	}
+	if err := s.Repo.TouchToken(ctx, t.ID); err != nil {
+		return models.User{}, models.APIToken{}, err
+	}
+	return user, t, nil
+}
+
+func validScope(scope string) bool {
+	for _, s := range models.APITokenScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
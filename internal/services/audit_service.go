@@ -0,0 +1,62 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"workout/internal/models"
+	"workout/internal/repositories"
+)
+
+const (
+	defaultAuditPerPage = 50
+	maxAuditPerPage     = 500
+)
+
+// AuditService records security-relevant events. The IP address and request
+// ID are taken from the request context set by the HTTP middleware.
+type AuditService struct {
+	Repo *repositories.AuditRepository
+}
+
+// RecordActor logs an event performed by actorID, or by an unknown actor
+// when it is zero. A nil service records nothing. Failures are logged and
+// never fail the audited operation.
+func (s *AuditService) RecordActor(ctx context.Context, actorID int, event, targetType string, targetID int, details map[string]interface{}) {
+	if s == nil {
+		return
+	}
+	e := models.AuditEvent{Event: event, TargetType: targetType, Details: details, CreatedAt: time.Now()}
+	if actorID != 0 {
+		e.ActorID = &actorID
+	}
+	if targetID != 0 {
+		e.TargetID = &targetID
+	}
+	e.IPAddress, _ = ctx.Value("client_ip").(string)
+	e.RequestID, _ = ctx.Value("request_id").(string)
+
+	// the audit entry must be written even if the client went away
+	if err := s.Repo.InsertEvent(context.WithoutCancel(ctx), e); err != nil {
+		log.Printf("Failed to record audit event %s: %v", event, err)
+	}
+}
+
+// Events returns a page of the audit log.
+func (s *AuditService) Events(ctx context.Context, f models.AuditFilter) (models.AuditList, error) {
+	if f.Page < 1 {
+		f.Page = 1
+	}
+	if f.PerPage < 1 {
+		f.PerPage = defaultAuditPerPage
+	}
+	if f.PerPage > maxAuditPerPage {
+		f.PerPage = maxAuditPerPage
+	}
+	events, total, err := s.Repo.ListEvents(ctx, f)
+	if err != nil {
+		return models.AuditList{}, err
+	}
+	return models.AuditList{Events: events, Total: total, Page: f.Page, PerPage: f.PerPage}, nil
+}
@@ -0,0 +1,193 @@
+package services
+
+import (
+	"context"
+
+	"workout/internal/models"
+	"workout/internal/repositories"
+)
+
+// Authorizer checks that an actor owns the resources it operates on.
+// A missing resource yields its not-found error and a resource of another
+// trainer yields models.ErrForbidden. Admins may access everything.
+type Authorizer struct {
+	ProgramRepo  *repositories.ProgramRepository
+	DayRepo      *repositories.DayRepository
+	ExerciseRepo *repositories.ExerciseRepository
+	FoodRepo     *repositories.FoodRepository
+	UserRepo     *repositories.UserRepository
+	VersionRepo  *repositories.ProgramVersionRepository
+}
+
+// Program checks that the program exists and belongs to the actor.
+func (a *Authorizer) Program(ctx context.Context, actor models.Actor, programID int) (models.WorkOutProgram, error) {
+	p, err := a.ProgramRepo.GetProgramByID(ctx, programID)
+	if err != nil {
+		return models.WorkOutProgram{}, err
+	}
+	if !actor.IsAdmin() && p.TrainerID != actor.UserID {
+		return models.WorkOutProgram{}, models.ErrForbidden
+	}
+	return p, nil
+}
+
+// Day checks that the day exists, belongs to a program of the actor and is
+// a draft day. Days of published versions yield models.ErrVersionImmutable.
+func (a *Authorizer) Day(ctx context.Context, actor models.Actor, dayID int) error {
+	programID, versionID, err := a.DayRepo.GetDayVersion(ctx, dayID)
+	if err != nil {
+		return err
+	}
+	if _, err = a.Program(ctx, actor, programID); err != nil {
+		return err
+	}
+	if versionID != nil {
+		return models.ErrVersionImmutable
+	}
+	return nil
+}
+
+// Exercise checks that the actor may modify the exercise. Exercises without
+// an owner predate ownership and only admins may modify them.
+func (a *Authorizer) Exercise(ctx context.Context, actor models.Actor, id int) error {
+	owner, err := a.ExerciseRepo.GetExerciseOwner(ctx, id)
+	if err != nil {
+		return err
+	}
+	return checkOwner(actor, owner, false)
+}
+
+// UseExercise checks that the actor may reference the exercise from a day.
+// Exercises without an owner are shared and may be used by anyone.
+func (a *Authorizer) UseExercise(ctx context.Context, actor models.Actor, id int) error {
+	owner, err := a.ExerciseRepo.GetExerciseOwner(ctx, id)
+	if err != nil {
+		return err
+	}
+	return checkOwner(actor, owner, true)
+}
+
+// Food checks that the actor may modify the food item.
+func (a *Authorizer) Food(ctx context.Context, actor models.Actor, id int) error {
+	owner, err := a.FoodRepo.GetFoodOwner(ctx, id)
+	if err != nil {
+		return err
+	}
+	return checkOwner(actor, owner, false)
+}
+
+// UseFood checks that the actor may reference the food item from a day.
+func (a *Authorizer) UseFood(ctx context.Context, actor models.Actor, id int) error {
+	owner, err := a.FoodRepo.GetFoodOwner(ctx, id)
+	if err != nil {
+		return err
+	}
+	return checkOwner(actor, owner, true)
+}
+
+// Client checks that the client is the actor or is enrolled in one of the
+// actor's programs.
+func (a *Authorizer) Client(ctx context.Context, actor models.Actor, clientID int) error {
+	if actor.IsAdmin() || actor.UserID == clientID {
+		return nil
+	}
+	ok, err := a.UserRepo.IsClientOfTrainer(ctx, clientID, actor.UserID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return models.ErrForbidden
+	}
+	return nil
+}
+
+// ReadProgram checks that the actor may view the program: its trainer, an
+// admin, or a client enrolled in it whose access has not expired.
+func (a *Authorizer) ReadProgram(ctx context.Context, actor models.Actor, programID int) (models.WorkOutProgram, error) {
+	p, err := a.ProgramRepo.GetProgramByID(ctx, programID)
+	if err != nil {
+		return models.WorkOutProgram{}, err
+	}
+	if actor.IsAdmin() || p.TrainerID == actor.UserID {
+		return p, nil
+	}
+	if err := a.ClientAccess(ctx, actor.UserID, programID); err != nil {
+		return models.WorkOutProgram{}, err
+	}
+	return p, nil
+}
+
+// ViewVersion returns the version of the program the actor sees, nil for the
+// draft. The trainer and admins see the draft or the requested version;
+// clients always see the version their enrollment is pinned to. The actor
+// must already be allowed to read the program.
+func (a *Authorizer) ViewVersion(ctx context.Context, actor models.Actor, p models.WorkOutProgram, requested int) (*int, error) {
+	if !actor.IsAdmin() && p.TrainerID != actor.UserID {
+		return a.VersionRepo.ClientVersionID(ctx, actor.UserID, p.ID)
+	}
+	if requested == 0 {
+		return nil, nil
+	}
+	v, err := a.VersionRepo.GetVersion(ctx, p.ID, requested)
+	if err != nil {
+		return nil, err
+	}
+	return &v.ID, nil
+}
+
+// ClientAccess checks that the client is enrolled in the program and that
+// the access granted by the invite is still active. An enrollment whose
+// window has passed yields models.ErrAccessExpired.
+func (a *Authorizer) ClientAccess(ctx context.Context, clientID, programID int) error {
+	enrolled, active, err := a.UserRepo.ClientProgramAccess(ctx, clientID, programID)
+	if err != nil {
+		return err
+	}
+	if !enrolled {
+		return models.ErrForbidden
+	}
+	if !active {
+		return models.ErrAccessExpired
+	}
+	return nil
+}
+
+// Progress checks access to a client's progress in a program. Clients may
+// read and write their own progress while their access is active; trainers
+// may read progress of clients enrolled in their programs, including
+// history of expired enrollments.
+func (a *Authorizer) Progress(ctx context.Context, actor models.Actor, clientID, programID int, write bool) error {
+	if actor.UserID == clientID {
+		return a.ClientAccess(ctx, clientID, programID)
+	}
+	if write {
+		return models.ErrForbidden
+	}
+	if _, err := a.Program(ctx, actor, programID); err != nil {
+		return err
+	}
+	ok, err := a.UserRepo.IsClientInProgram(ctx, clientID, programID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return models.ErrForbidden
+	}
+	return nil
+}
+
+func checkOwner(actor models.Actor, owner *int, sharedIfUnowned bool) error {
+	if actor.IsAdmin() {
+		return nil
+	}
+	if owner == nil {
+		if sharedIfUnowned {
+			return nil
+		}
+		return models.ErrForbidden
+	}
+	if *owner != actor.UserID {
+		return models.ErrForbidden
+	}
+	return nil
+}
@@ -0,0 +1,306 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"workout/internal/models"
+	"workout/internal/repositories"
+)
+
+var (
+	trainer      = models.Actor{UserID: 1, Role: "trainer"}
+	otherTrainer = models.Actor{UserID: 2, Role: "trainer"}
+	admin        = models.Actor{UserID: 3, Role: "admin"}
+	client       = models.Actor{UserID: 4, Role: "client"}
+)
+
+// newTestAuthorizer returns an Authorizer whose repositories share a mocked
+// database.
+func newTestAuthorizer(t *testing.T) (*Authorizer, sqlmock.Sqlmock) {
+	db, mock := newMockDB(t)
+	return &Authorizer{
+		ProgramRepo:  &repositories.ProgramRepository{DB: db},
+		DayRepo:      &repositories.DayRepository{DB: db},
+		ExerciseRepo: &repositories.ExerciseRepository{DB: db},
+		FoodRepo:     &repositories.FoodRepository{DB: db},
+		UserRepo:     &repositories.UserRepository{DB: db},
+		VersionRepo:  &repositories.ProgramVersionRepository{DB: db},
+	}, mock
+}
+
+// expectProgram expects a program lookup. A zero trainerID means the program
+// does not exist.
+func expectProgram(mock sqlmock.Sqlmock, programID, trainerID int) {
+	q := mock.ExpectQuery(query("FROM workout_programs WHERE id = ?")).WithArgs(programID)
+	rows := sqlmock.NewRows([]string{"id", "trainer_id", "name", "days", "description", "visibility", "slug", "created_at", "updated_at"})
+	if trainerID != 0 {
+		rows.AddRow(programID, trainerID, "Strength", 3, "", "private", nil, time.Now(), nil)
+	}
+	q.WillReturnRows(rows)
+}
+
+var versionColumnNames = []string{"id", "program_id", "version", "name", "description", "days", "published_by", "published_at", "clients"}
+
+// expectVersion expects a lookup of a version by number.
+func expectVersion(mock sqlmock.Sqlmock, programID, version, versionID int) {
+	mock.ExpectQuery(query("FROM program_versions v WHERE v.program_id = ? AND v.version = ?")).WithArgs(programID, version).
+		WillReturnRows(sqlmock.NewRows(versionColumnNames).
+			AddRow(versionID, programID, version, "Strength v"+strconv.Itoa(version), "", 3, trainer.UserID, time.Now(), 0))
+}
+
+func TestAuthorizerProgram(t *testing.T) {
+	tests := []struct {
+		name    string
+		actor   models.Actor
+		owner   int
+		wantErr error
+	}{
+		{"owner", trainer, trainer.UserID, nil},
+		{"other trainer", otherTrainer, trainer.UserID, models.ErrForbidden},
+		{"client", client, trainer.UserID, models.ErrForbidden},
+		{"admin", admin, trainer.UserID, nil},
+		{"missing", trainer, 0, models.ErrWorkoutProgramNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, mock := newTestAuthorizer(t)
+			expectProgram(mock, 10, tt.owner)
+			p, err := a.Program(context.Background(), tt.actor, 10)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && p.ID != 10 {
+				t.Fatalf("got program %d", p.ID)
+			}
+		})
+	}
+}
+
+func TestAuthorizerDay(t *testing.T) {
+	versionID := 7
+	tests := []struct {
+		name    string
+		actor   models.Actor
+		version *int
+		wantErr error
+	}{
+		{"draft day", trainer, nil, nil},
+		{"published day", trainer, &versionID, models.ErrVersionImmutable},
+		{"other trainer's day", otherTrainer, nil, models.ErrForbidden},
+		{"admin, published day", admin, &versionID, models.ErrVersionImmutable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, mock := newTestAuthorizer(t)
+			mock.ExpectQuery(query("SELECT work_out_program_id, program_version_id FROM days WHERE id = ?")).WithArgs(5).
+				WillReturnRows(sqlmock.NewRows([]string{"work_out_program_id", "program_version_id"}).AddRow(10, tt.version))
+			expectProgram(mock, 10, trainer.UserID)
+			if err := a.Day(context.Background(), tt.actor, 5); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	t.Run("missing day", func(t *testing.T) {
+		a, mock := newTestAuthorizer(t)
+		mock.ExpectQuery(query("FROM days WHERE id = ?")).WithArgs(5).
+			WillReturnRows(sqlmock.NewRows([]string{"work_out_program_id", "program_version_id"}))
+		if err := a.Day(context.Background(), trainer, 5); !errors.Is(err, models.ErrDayNotFound) {
+			t.Fatalf("error = %v, want %v", err, models.ErrDayNotFound)
+		}
+	})
+}
+
+func TestAuthorizerLibraryItems(t *testing.T) {
+	own, other := trainer.UserID, otherTrainer.UserID
+	tests := []struct {
+		name      string
+		actor     models.Actor
+		owner     *int
+		wantWrite error
+		wantUse   error
+	}{
+		{"own item", trainer, &own, nil, nil},
+		{"other trainer's item", trainer, &other, models.ErrForbidden, models.ErrForbidden},
+		{"shared item", trainer, nil, models.ErrForbidden, nil},
+		{"admin, other trainer's item", admin, &other, nil, nil},
+		{"admin, shared item", admin, nil, nil, nil},
+	}
+	checks := []struct {
+		table string
+		write func(*Authorizer, models.Actor) error
+		use   func(*Authorizer, models.Actor) error
+	}{
+		{"exercises",
+			func(a *Authorizer, actor models.Actor) error { return a.Exercise(context.Background(), actor, 8) },
+			func(a *Authorizer, actor models.Actor) error { return a.UseExercise(context.Background(), actor, 8) }},
+		{"food",
+			func(a *Authorizer, actor models.Actor) error { return a.Food(context.Background(), actor, 8) },
+			func(a *Authorizer, actor models.Actor) error { return a.UseFood(context.Background(), actor, 8) }},
+	}
+	for _, c := range checks {
+		for _, tt := range tests {
+			t.Run(c.table+"/"+tt.name, func(t *testing.T) {
+				a, mock := newTestAuthorizer(t)
+				for i := 0; i < 2; i++ {
+					mock.ExpectQuery(query("SELECT trainer_id FROM " + c.table + " WHERE id = ?")).WithArgs(8).
+						WillReturnRows(sqlmock.NewRows([]string{"trainer_id"}).AddRow(tt.owner))
+				}
+				if err := c.write(a, tt.actor); !errors.Is(err, tt.wantWrite) {
+					t.Errorf("modify: error = %v, want %v", err, tt.wantWrite)
+				}
+				if err := c.use(a, tt.actor); !errors.Is(err, tt.wantUse) {
+					t.Errorf("use: error = %v, want %v", err, tt.wantUse)
+				}
+			})
+		}
+	}
+}
+
+func TestAuthorizerClient(t *testing.T) {
+	tests := []struct {
+		name     string
+		actor    models.Actor
+		enrolled *bool
+		wantErr  error
+	}{
+		{"self", client, nil, nil},
+		{"admin", admin, nil, nil},
+		{"trainer of the client", trainer, boolPtr(true), nil},
+		{"other trainer", otherTrainer, boolPtr(false), models.ErrForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, mock := newTestAuthorizer(t)
+			if tt.enrolled != nil {
+				mock.ExpectQuery(query("WHERE pi.client_id = ? AND wp.trainer_id = ?")).WithArgs(client.UserID, tt.actor.UserID).
+					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(*tt.enrolled))
+			}
+			if err := a.Client(context.Background(), tt.actor, client.UserID); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAuthorizerReadProgram(t *testing.T) {
+	tests := []struct {
+		name     string
+		actor    models.Actor
+		enrolled bool
+		active   bool
+		wantErr  error
+	}{
+		{"owner", trainer, false, false, nil},
+		{"admin", admin, false, false, nil},
+		{"enrolled client", client, true, true, nil},
+		{"expired client", client, true, false, models.ErrAccessExpired},
+		{"stranger", otherTrainer, false, false, models.ErrForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, mock := newTestAuthorizer(t)
+			expectProgram(mock, 10, trainer.UserID)
+			if tt.actor != trainer && tt.actor != admin {
+				mock.ExpectQuery(query("FROM program_invites")).WithArgs(tt.actor.UserID, 10).
+					WillReturnRows(sqlmock.NewRows([]string{"enrolled", "active"}).AddRow(tt.enrolled, tt.active))
+			}
+			if _, err := a.ReadProgram(context.Background(), tt.actor, 10); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAuthorizerProgress(t *testing.T) {
+	tests := []struct {
+		name    string
+		actor   models.Actor
+		write   bool
+		setup   func(sqlmock.Sqlmock)
+		wantErr error
+	}{
+		{"own progress", client, true, func(mock sqlmock.Sqlmock) {
+			mock.ExpectQuery(query("FROM program_invites")).WithArgs(client.UserID, 10).
+				WillReturnRows(sqlmock.NewRows([]string{"enrolled", "active"}).AddRow(true, true))
+		}, nil},
+		{"own progress after expiry", client, false, func(mock sqlmock.Sqlmock) {
+			mock.ExpectQuery(query("FROM program_invites")).WithArgs(client.UserID, 10).
+				WillReturnRows(sqlmock.NewRows([]string{"enrolled", "active"}).AddRow(true, false))
+		}, models.ErrAccessExpired},
+		{"trainer writes", trainer, true, func(sqlmock.Sqlmock) {}, models.ErrForbidden},
+		{"trainer reads", trainer, false, func(mock sqlmock.Sqlmock) {
+			expectProgram(mock, 10, trainer.UserID)
+			mock.ExpectQuery(query("WHERE client_id = ? AND program_id = ? AND accepted_at IS NOT NULL")).WithArgs(client.UserID, 10).
+				WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		}, nil},
+		{"trainer reads a stranger", trainer, false, func(mock sqlmock.Sqlmock) {
+			expectProgram(mock, 10, trainer.UserID)
+			mock.ExpectQuery(query("WHERE client_id = ? AND program_id = ? AND accepted_at IS NOT NULL")).WithArgs(client.UserID, 10).
+				WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		}, models.ErrForbidden},
+		{"other trainer reads", otherTrainer, false, func(mock sqlmock.Sqlmock) {
+			expectProgram(mock, 10, trainer.UserID)
+		}, models.ErrForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, mock := newTestAuthorizer(t)
+			tt.setup(mock)
+			if err := a.Progress(context.Background(), tt.actor, client.UserID, 10, tt.write); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAuthorizerViewVersion(t *testing.T) {
+	p := models.WorkOutProgram{ID: 10, TrainerID: trainer.UserID}
+	pinned := 70
+	tests := []struct {
+		name      string
+		actor     models.Actor
+		requested int
+		setup     func(sqlmock.Sqlmock)
+		want      *int
+		wantErr   error
+	}{
+		{"trainer, draft", trainer, 0, func(sqlmock.Sqlmock) {}, nil, nil},
+		{"trainer, version", trainer, 2, func(mock sqlmock.Sqlmock) { expectVersion(mock, 10, 2, 70) }, &pinned, nil},
+		{"trainer, missing version", trainer, 9, func(mock sqlmock.Sqlmock) {
+			mock.ExpectQuery(query("FROM program_versions v WHERE v.program_id = ? AND v.version = ?")).WithArgs(10, 9).
+				WillReturnRows(sqlmock.NewRows(versionColumnNames))
+		}, nil, models.ErrVersionNotFound},
+		{"client ignores the request", client, 1, func(mock sqlmock.Sqlmock) {
+			mock.ExpectQuery(query("SELECT version_id FROM program_invites")).WithArgs(client.UserID, 10).
+				WillReturnRows(sqlmock.NewRows([]string{"version_id"}).AddRow(pinned))
+		}, &pinned, nil},
+		{"client on the draft", client, 0, func(mock sqlmock.Sqlmock) {
+			mock.ExpectQuery(query("SELECT version_id FROM program_invites")).WithArgs(client.UserID, 10).
+				WillReturnRows(sqlmock.NewRows([]string{"version_id"}).AddRow(nil))
+		}, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, mock := newTestAuthorizer(t)
+			tt.setup(mock)
+			got, err := a.ViewVersion(context.Background(), tt.actor, p, tt.requested)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+				t.Fatalf("got version %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
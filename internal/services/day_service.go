@@ -9,44 +9,131 @@ import (
 // DayService contains business logic for workout days and progress.
 type DayService struct {
 	Repo *repositories.DayRepository
+	Auth *Authorizer
 }
 
-func (s *DayService) GetDay(ctx context.Context, programID, dayNumber int) (models.DayDetails, error) {
-	return s.Repo.GetDayDetails(ctx, programID, dayNumber)
+// GetDay returns a day of the program version the actor sees. Trainers may
+// ask for a published version, zero means the draft.
+func (s *DayService) GetDay(ctx context.Context, actor models.Actor, programID, dayNumber, version int) (models.DayDetails, error) {
+	p, err := s.Auth.ReadProgram(ctx, actor, programID)
+	if err != nil {
+		return models.DayDetails{}, err
+	}
+	versionID, err := s.Auth.ViewVersion(ctx, actor, p, version)
+	if err != nil {
+		return models.DayDetails{}, err
+	}
+	return s.Repo.GetDayDetails(ctx, programID, dayNumber, versionID)
 }
 
-func (s *DayService) CompleteDay(ctx context.Context, clientID, dayID int) (models.ProgramProgress, error) {
-	return s.Repo.MarkDayCompleted(ctx, clientID, dayID)
+// CompleteDay marks a day as completed for the actor, who must be enrolled
+// in the day's program.
+func (s *DayService) CompleteDay(ctx context.Context, actor models.Actor, dayID int) (models.ProgramProgress, error) {
+	if err := s.authorizeProgress(ctx, actor, actor.UserID, dayID, true); err != nil {
+		return models.ProgramProgress{}, err
+	}
+	return s.Repo.MarkDayCompleted(ctx, actor.UserID, dayID)
 }
 
-func (s *DayService) CompleteFood(ctx context.Context, clientID, dayID int) (models.ProgramProgress, error) {
-	return s.Repo.MarkFoodCompleted(ctx, clientID, dayID)
+func (s *DayService) CompleteFood(ctx context.Context, actor models.Actor, dayID int) (models.ProgramProgress, error) {
+	if err := s.authorizeProgress(ctx, actor, actor.UserID, dayID, true); err != nil {
+		return models.ProgramProgress{}, err
+	}
+	return s.Repo.MarkFoodCompleted(ctx, actor.UserID, dayID)
 }
 
-func (s *DayService) CompleteExercise(ctx context.Context, clientID, dayID int) (models.ProgramProgress, error) {
-	return s.Repo.MarkExerciseCompleted(ctx, clientID, dayID)
+func (s *DayService) CompleteExercise(ctx context.Context, actor models.Actor, dayID int) (models.ProgramProgress, error) {
+	if err := s.authorizeProgress(ctx, actor, actor.UserID, dayID, true); err != nil {
+		return models.ProgramProgress{}, err
+	}
+	return s.Repo.MarkExerciseCompleted(ctx, actor.UserID, dayID)
 }
 
-func (s *DayService) GetProgress(ctx context.Context, clientID, dayID int) (models.ProgramProgress, error) {
+// GetProgress returns a client's progress for a day. Clients read their own
+// progress, trainers that of clients in their programs.
+func (s *DayService) GetProgress(ctx context.Context, actor models.Actor, clientID, dayID int) (models.ProgramProgress, error) {
+	if err := s.authorizeProgress(ctx, actor, clientID, dayID, false); err != nil {
+		return models.ProgramProgress{}, err
+	}
 	return s.Repo.GetProgress(ctx, clientID, dayID)
 }
 
-func (s *DayService) GetProgramProgress(ctx context.Context, clientID, programID int) ([]models.DayProgressStatus, error) {
+func (s *DayService) GetProgramProgress(ctx context.Context, actor models.Actor, clientID, programID int) ([]models.DayProgressStatus, error) {
+	if err := s.Auth.Progress(ctx, actor, clientID, programID, false); err != nil {
+		return nil, err
+	}
 	return s.Repo.GetProgramProgress(ctx, clientID, programID)
 }
 
-func (s *DayService) CreateDay(ctx context.Context, day models.Days) (models.Days, error) {
+// authorizeProgress checks access to progress of a day. Clients may only
+// record progress on days of the version they are pinned to.
+func (s *DayService) authorizeProgress(ctx context.Context, actor models.Actor, clientID, dayID int, write bool) error {
+	programID, versionID, err := s.Repo.GetDayVersion(ctx, dayID)
+	if err != nil {
+		return err
+	}
+	if err := s.Auth.Progress(ctx, actor, clientID, programID, write); err != nil {
+		return err
+	}
+	if !write {
+		return nil
+	}
+	pinned, err := s.Auth.VersionRepo.ClientVersionID(ctx, clientID, programID)
+	if err != nil {
+		return err
+	}
+	if (pinned == nil) != (versionID == nil) || (pinned != nil && *pinned != *versionID) {
+		return models.ErrForbidden
+	}
+	return nil
+}
+
+// CreateDay adds a day to a program of the actor. The referenced exercise and
+// food must be the actor's own or shared ones.
+func (s *DayService) CreateDay(ctx context.Context, actor models.Actor, day models.Days) (models.Days, error) {
+	if err := s.authorizeDayContent(ctx, actor, day); err != nil {
+		return models.Days{}, err
+	}
 	return s.Repo.CreateDay(ctx, day)
 }
 
-func (s *DayService) DaysByProgram(ctx context.Context, programID int) ([]models.DayDetails, error) {
-	return s.Repo.DaysByProgram(ctx, programID)
+// DaysByProgram lists the days of the program version the actor sees.
+// Trainers may ask for a published version, zero means the draft.
+func (s *DayService) DaysByProgram(ctx context.Context, actor models.Actor, programID, version int) ([]models.DayDetails, error) {
+	p, err := s.Auth.ReadProgram(ctx, actor, programID)
+	if err != nil {
+		return nil, err
+	}
+	versionID, err := s.Auth.ViewVersion(ctx, actor, p, version)
+	if err != nil {
+		return nil, err
+	}
+	return s.Repo.DaysByProgram(ctx, programID, versionID)
 }
 
-func (s *DayService) UpdateDay(ctx context.Context, day models.Days) (models.Days, error) {
+func (s *DayService) UpdateDay(ctx context.Context, actor models.Actor, day models.Days) (models.Days, error) {
+	if err := s.Auth.Day(ctx, actor, day.ID); err != nil {
+		return models.Days{}, err
+	}
+	if err := s.authorizeDayContent(ctx, actor, day); err != nil {
+		return models.Days{}, err
+	}
 	return s.Repo.UpdateDay(ctx, day)
 }
 
-func (s *DayService) DeleteDay(ctx context.Context, id int) error {
+func (s *DayService) DeleteDay(ctx context.Context, actor models.Actor, id int) error {
+	if err := s.Auth.Day(ctx, actor, id); err != nil {
+		return err
+	}
 	return s.Repo.DeleteDay(ctx, id)
 }
+
+func (s *DayService) authorizeDayContent(ctx context.Context, actor models.Actor, day models.Days) error {
+	if _, err := s.Auth.Program(ctx, actor, day.WorkOutProgramID); err != nil {
+		return err
+	}
+	if err := s.Auth.UseExercise(ctx, actor, day.ExercisesID); err != nil {
+		return err
+	}
+	return s.Auth.UseFood(ctx, actor, day.FoodID)
+}
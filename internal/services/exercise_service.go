@@ -10,16 +10,26 @@ import (
 // ExerciseService provides business logic for exercises.
 type ExerciseService struct {
 	Repo *repositories.ExerciseRepository
+	Auth *Authorizer
 }
 
-func (s *ExerciseService) CreateExercise(ctx context.Context, ex models.Exercises) (models.Exercises, error) {
+// CreateExercise stores a new exercise owned by the actor.
+func (s *ExerciseService) CreateExercise(ctx context.Context, actor models.Actor, ex models.Exercises) (models.Exercises, error) {
+	ex.TrainerID = &actor.UserID
 	return s.Repo.CreateExercise(ctx, ex)
 }
 
-func (s *ExerciseService) UpdateExercise(ctx context.Context, ex models.Exercises) (models.Exercises, error) {
+func (s *ExerciseService) UpdateExercise(ctx context.Context, actor models.Actor, ex models.Exercises) (models.Exercises, error) {
+	if err := s.Auth.Exercise(ctx, actor, ex.ID); err != nil {
+		return models.Exercises{}, err
+	}
+	ex.TrainerID = nil
 	return s.Repo.UpdateExercise(ctx, ex)
 }
 
-func (s *ExerciseService) DeleteExercise(ctx context.Context, id int) error {
+func (s *ExerciseService) DeleteExercise(ctx context.Context, actor models.Actor, id int) error {
+	if err := s.Auth.Exercise(ctx, actor, id); err != nil {
+		return err
+	}
 	return s.Repo.DeleteExercise(ctx, id)
 }
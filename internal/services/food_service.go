@@ -10,16 +10,26 @@ import (
 // FoodService contains business logic for food items.
 type FoodService struct {
 	Repo *repositories.FoodRepository
+	Auth *Authorizer
 }
 
-func (s *FoodService) CreateFood(ctx context.Context, f models.Food) (models.Food, error) {
+// CreateFood stores a new food item owned by the actor.
+func (s *FoodService) CreateFood(ctx context.Context, actor models.Actor, f models.Food) (models.Food, error) {
+	f.TrainerID = &actor.UserID
 	return s.Repo.CreateFood(ctx, f)
 }
 
-func (s *FoodService) UpdateFood(ctx context.Context, f models.Food) (models.Food, error) {
+func (s *FoodService) UpdateFood(ctx context.Context, actor models.Actor, f models.Food) (models.Food, error) {
+	if err := s.Auth.Food(ctx, actor, f.ID); err != nil {
+		return models.Food{}, err
+	}
+	f.TrainerID = nil
 	return s.Repo.UpdateFood(ctx, f)
 }
 
-func (s *FoodService) DeleteFood(ctx context.Context, id int) error {
+func (s *FoodService) DeleteFood(ctx context.Context, actor models.Actor, id int) error {
+	if err := s.Auth.Food(ctx, actor, id); err != nil {
+		return err
+	}
 	return s.Repo.DeleteFood(ctx, id)
 }
@@ -2,7 +2,11 @@ package services
 
 import (
 	"context"
+	"fmt"
+	"log"
+	"time"
 
+	"workout/internal/mailer"
 	"workout/internal/models"
 	"workout/internal/repositories"
 )
@@ -11,9 +15,35 @@ import (
 type InviteService struct {
 	Repo     *repositories.InviteRepository
 	UserRepo *repositories.UserRepository
+	Auth     *Authorizer
+	Mailer   mailer.Mailer
+	Audit    *AuditService
 }
 
-func (s *InviteService) InviteClient(ctx context.Context, programID int, email, message string, days int) (models.ProgramInvite, error) {
+// expiryBatchSize caps how many enrollments one ExpireAccess run handles.
+const expiryBatchSize = 100
+
+// A failed expiry notification is retried after expiryRetryDelay, doubling
+// each time; after maxExpiryNotifyAttempts the enrollment is marked expired
+// without one.
+const (
+	expiryRetryDelay        = time.Hour
+	maxExpiryNotifyAttempts = 5
+)
+
+// InviteClient invites a client to a program. The program must have been
+// published; the client is pinned to the latest version on acceptance.
+func (s *InviteService) InviteClient(ctx context.Context, actor models.Actor, programID int, email, message string, days int) (models.ProgramInvite, error) {
+	if _, err := s.Auth.Program(ctx, actor, programID); err != nil {
+		return models.ProgramInvite{}, err
+	}
+	published, err := s.Auth.VersionRepo.HasVersions(ctx, programID)
+	if err != nil {
+		return models.ProgramInvite{}, err
+	}
+	if !published {
+		return models.ProgramInvite{}, models.ErrProgramNotPublished
+	}
 	invite := models.ProgramInvite{
 		ProgramID:  programID,
 		Email:      email,
@@ -23,8 +53,14 @@ func (s *InviteService) InviteClient(ctx context.Context, programID int, email,
 	return s.Repo.CreateInvite(ctx, invite)
 }
 
+// AcceptInvite enrolls the client. The invite must have been sent to the
+// client's email and not be accepted yet.
 func (s *InviteService) AcceptInvite(ctx context.Context, token string, clientID int) (models.ProgramInvite, error) {
-	inv, err := s.Repo.AcceptInvite(ctx, token, clientID)
+	client, err := s.UserRepo.GetUserByID(ctx, clientID)
+	if err != nil {
+		return models.ProgramInvite{}, err
+	}
+	inv, err := s.Repo.AcceptInvite(ctx, token, clientID, client.Email)
 	if err != nil {
 		return inv, err
 	}
@@ -34,10 +70,59 @@ func (s *InviteService) AcceptInvite(ctx context.Context, token string, clientID
 	return inv, nil
 }
 
-func (s *InviteService) UpdateAccess(ctx context.Context, programID, clientID, days int) (models.ProgramInvite, error) {
-	return s.Repo.UpdateAccessDuration(ctx, programID, clientID, days)
+func (s *InviteService) UpdateAccess(ctx context.Context, actor models.Actor, programID, clientID, days int) (models.ProgramInvite, error) {
+	if _, err := s.Auth.Program(ctx, actor, programID); err != nil {
+		return models.ProgramInvite{}, err
+	}
+	inv, err := s.Repo.UpdateAccessDuration(ctx, programID, clientID, days)
+	if err != nil {
+		return models.ProgramInvite{}, err
+	}
+	details := map[string]interface{}{"client_id": clientID, "invite_id": inv.ID, "access_days": days}
+	if inv.AccessExpires != nil {
+		details["access_expires"] = inv.AccessExpires
+	}
+	s.Audit.RecordActor(ctx, actor.UserID, models.AuditAccessChanged, models.AuditTargetProgram, programID, details)
+	return inv, nil
 }
 
 func (s *InviteService) GetProgramFromInvite(ctx context.Context, token string) (models.WorkOutProgram, error) {
 	return s.Repo.GetProgramFromInvite(ctx, token)
 }
+
+// ExpireAccess marks enrollments whose access window has passed as expired
+// and emails their trainers. Enrollments whose notification fails are retried
+// with a growing delay and given up on after a few attempts. It returns the
+// number of enrollments marked.
+func (s *InviteService) ExpireAccess(ctx context.Context) (int, error) {
+	now := time.Now()
+	expired, err := s.Repo.GetNewlyExpired(ctx, now, expiryBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	marked := 0
+	for _, e := range expired {
+		err := s.Mailer.Send(ctx, mailer.Message{
+			To:      e.TrainerEmail,
+			Subject: fmt.Sprintf("Access to %s has expired", e.ProgramName),
+			Body: fmt.Sprintf("Hi %s,\n\nThe access of %s (%s) to your program %q expired on %s.\n\nExtend their access to let them continue the program.\n",
+				e.TrainerName, e.ClientName, e.ClientEmail, e.ProgramName, e.AccessExpires.Format("2006-01-02 15:04")),
+		})
+		if err != nil {
+			log.Printf("access expiry: notify trainer of invite %d: %v", e.InviteID, err)
+			if e.NotifyAttempts+1 < maxExpiryNotifyAttempts {
+				retryAt := now.Add(expiryRetryDelay << e.NotifyAttempts)
+				if err := s.Repo.DeferExpiryNotice(ctx, e.InviteID, retryAt); err != nil {
+					return marked, err
+				}
+				continue
+			}
+			log.Printf("access expiry: giving up on notifying the trainer of invite %d", e.InviteID)
+		}
+		if err := s.Repo.MarkExpired(ctx, e.InviteID, now); err != nil {
+			return marked, err
+		}
+		marked++
+	}
+	return marked, nil
+}
@@ -0,0 +1,103 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"workout/internal/models"
+	"workout/internal/repositories"
+)
+
+const (
+	lockoutScopeAccount = "account"
+	lockoutScopeIP      = "ip"
+
+	accountFailureThreshold = 5
+	ipFailureThreshold      = 20
+	lockoutBaseDelay        = time.Minute
+	lockoutMaxDelay         = time.Hour
+	lockoutWindow           = 24 * time.Hour
+)
+
+// LockoutService slows down password guessing. After a number of failed
+// attempts an account or IP address is locked, each further failure doubling
+// the lock duration.
+type LockoutService struct {
+	Repo *repositories.LoginAttemptRepository
+}
+
+// Attempt returns ErrTooManyAttempts while the account or the IP address is
+// locked. Otherwise the attempt is counted as failed up front, before the
+// credentials are verified, and must be released with Release if they turn
+// out to be right.
+func (s *LockoutService) Attempt(ctx context.Context, email, ip string) error {
+	resetBefore := time.Now().Add(-lockoutWindow)
+	// the IP goes first so that a locked IP cannot add to an account's count
+	if ip != "" {
+		ok, err := s.Repo.ReserveAttempt(ctx, lockoutScopeIP, ip, resetBefore, lockedFunc(ipFailureThreshold))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return models.ErrTooManyAttempts
+		}
+	}
+	ok, err := s.Repo.ReserveAttempt(ctx, lockoutScopeAccount, normalizeEmail(email), resetBefore, lockedFunc(accountFailureThreshold))
+	if err == nil && !ok {
+		err = models.ErrTooManyAttempts
+	}
+	if err != nil && ip != "" {
+		if releaseErr := s.Repo.ReleaseAttempt(ctx, lockoutScopeIP, ip); releaseErr != nil {
+			return releaseErr
+		}
+	}
+	return err
+}
+
+// Release takes back an attempt that turned out to have the right
+// credentials.
+func (s *LockoutService) Release(ctx context.Context, email, ip string) error {
+	if err := s.Repo.ReleaseAttempt(ctx, lockoutScopeAccount, normalizeEmail(email)); err != nil {
+		return err
+	}
+	if ip == "" {
+		return nil
+	}
+	return s.Repo.ReleaseAttempt(ctx, lockoutScopeIP, ip)
+}
+
+// Succeed clears the account counter after a successful sign in. The IP
+// counter is left alone so that signing into an own account does not reset it.
+func (s *LockoutService) Succeed(ctx context.Context, email string) error {
+	return s.Repo.Reset(ctx, lockoutScopeAccount, normalizeEmail(email))
+}
+
+// Unlock lifts an account lock.
+func (s *LockoutService) Unlock(ctx context.Context, email string) error {
+	return s.Repo.Reset(ctx, lockoutScopeAccount, normalizeEmail(email))
+}
+
+func lockedFunc(threshold int) func(models.LoginAttempt) bool {
+	return func(a models.LoginAttempt) bool {
+		return lockedUntil(a, threshold).After(time.Now())
+	}
+}
+
+func lockedUntil(a models.LoginAttempt, threshold int) time.Time {
+	if a.Failures < threshold {
+		return time.Time{}
+	}
+	delay := lockoutBaseDelay
+	for i := threshold; i < a.Failures && delay < lockoutMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > lockoutMaxDelay {
+		delay = lockoutMaxDelay
+	}
+	return a.LastFailureAt.Add(delay)
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"workout/internal/models"
+	"workout/internal/repositories"
+)
+
+func TestLockedUntil(t *testing.T) {
+	last := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, 0},
+		{4, 0},
+		{5, time.Minute},
+		{6, 2 * time.Minute},
+		{7, 4 * time.Minute},
+		{10, 32 * time.Minute},
+		{11, time.Hour},
+		{100, time.Hour},
+	}
+	for _, tt := range tests {
+		got := lockedUntil(models.LoginAttempt{Failures: tt.failures, LastFailureAt: last}, accountFailureThreshold)
+		want := time.Time{}
+		if tt.want != 0 {
+			want = last.Add(tt.want)
+		}
+		if !got.Equal(want) {
+			t.Errorf("%d failures: locked until %v, want %v", tt.failures, got, want)
+		}
+	}
+}
+
+func newTestLockout(t *testing.T) (*LockoutService, sqlmock.Sqlmock) {
+	db, mock := newMockDB(t)
+	return &LockoutService{Repo: &repositories.LoginAttemptRepository{DB: db}}, mock
+}
+
+// expectReserve expects an attempt to be reserved for a subject that has
+// failed failures times, the last time at last. A locked subject is left
+// untouched.
+func expectReserve(mock sqlmock.Sqlmock, scope, subject string, failures int, last time.Time, locked bool) {
+	mock.ExpectBegin()
+	mock.ExpectExec(query("INSERT IGNORE INTO login_attempts")).WithArgs(scope, subject, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(query("SELECT failures, last_failure_at FROM login_attempts WHERE scope = ? AND subject = ? FOR UPDATE")).
+		WithArgs(scope, subject).
+		WillReturnRows(sqlmock.NewRows([]string{"failures", "last_failure_at"}).AddRow(failures, last))
+	if locked {
+		mock.ExpectRollback()
+		return
+	}
+	next := failures + 1
+	if last.Before(time.Now().Add(-lockoutWindow)) {
+		next = 1
+	}
+	mock.ExpectExec(query("UPDATE login_attempts SET failures = ?")).WithArgs(next, sqlmock.AnyArg(), scope, subject).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+}
+
+func expectRelease(mock sqlmock.Sqlmock, scope, subject string) {
+	mock.ExpectExec(query("SET failures = GREATEST(failures - 1, 0)")).WithArgs(scope, subject).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+}
+
+func TestLockoutAttempt(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name    string
+		ip      string
+		setup   func(sqlmock.Sqlmock)
+		wantErr error
+	}{
+		{"first attempt", "203.0.113.7", func(mock sqlmock.Sqlmock) {
+			expectReserve(mock, "ip", "203.0.113.7", 0, now, false)
+			expectReserve(mock, "account", "ann@example.com", 0, now, false)
+		}, nil},
+		{"last attempt before the lock", "203.0.113.7", func(mock sqlmock.Sqlmock) {
+			expectReserve(mock, "ip", "203.0.113.7", 4, now, false)
+			expectReserve(mock, "account", "ann@example.com", 4, now, false)
+		}, nil},
+		{"stale failures start over", "203.0.113.7", func(mock sqlmock.Sqlmock) {
+			old := now.Add(-lockoutWindow - time.Hour)
+			expectReserve(mock, "ip", "203.0.113.7", 30, old, false)
+			expectReserve(mock, "account", "ann@example.com", 9, old, false)
+		}, nil},
+		{"expired lock", "203.0.113.7", func(mock sqlmock.Sqlmock) {
+			expectReserve(mock, "ip", "203.0.113.7", 0, now, false)
+			expectReserve(mock, "account", "ann@example.com", 5, now.Add(-2*time.Minute), false)
+		}, nil},
+		{"locked account releases the IP", "203.0.113.7", func(mock sqlmock.Sqlmock) {
+			expectReserve(mock, "ip", "203.0.113.7", 0, now, false)
+			expectReserve(mock, "account", "ann@example.com", 5, now, true)
+			expectRelease(mock, "ip", "203.0.113.7")
+		}, models.ErrTooManyAttempts},
+		{"locked IP leaves the account alone", "203.0.113.7", func(mock sqlmock.Sqlmock) {
+			expectReserve(mock, "ip", "203.0.113.7", 20, now, true)
+		}, models.ErrTooManyAttempts},
+		{"no IP", "", func(mock sqlmock.Sqlmock) {
+			expectReserve(mock, "account", "ann@example.com", 0, now, false)
+		}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, mock := newTestLockout(t)
+			tt.setup(mock)
+			if err := s.Attempt(context.Background(), " Ann@Example.com ", tt.ip); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLockoutRelease(t *testing.T) {
+	s, mock := newTestLockout(t)
+	expectRelease(mock, "account", "ann@example.com")
+	expectRelease(mock, "ip", "203.0.113.7")
+	if err := s.Release(context.Background(), "Ann@example.com", "203.0.113.7"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,147 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+
+	"workout/internal/models"
+	"workout/internal/oidc"
+	"workout/internal/repositories"
+)
+
+// OIDCLoginTTL is how long a started login may take to complete.
+const OIDCLoginTTL = 10 * time.Minute
+
+// OIDCService signs users in through external OpenID Connect providers.
+type OIDCService struct {
+	Providers map[string]*oidc.Provider
+	Repo      *repositories.IdentityRepository
+	UserRepo  *repositories.UserRepository
+	Users     *UserService
+}
+
+// Start begins a login with the provider and returns the URL to send the
+// user to. An optional invite token is accepted once the login completes.
+func (s *OIDCService) Start(ctx context.Context, providerName, inviteToken string) (models.OIDCStartResponse, error) {
+	provider, ok := s.Providers[providerName]
+	if !ok {
+		return models.OIDCStartResponse{}, models.ErrUnknownOIDCProvider
+	}
+	if err := s.Repo.DeleteExpiredLoginStates(ctx); err != nil {
+		log.Printf("Error deleting expired login states: %v", err)
+	}
+
+	state, err := randomString(24)
+	if err != nil {
+		return models.OIDCStartResponse{}, err
+	}
+	nonce, err := randomString(16)
+	if err != nil {
+		return models.OIDCStartResponse{}, err
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	url, err := provider.AuthCodeURL(ctx, state, nonce, verifier)
+	if err != nil {
+		return models.OIDCStartResponse{}, err
+	}
+	err = s.Repo.SaveLoginState(ctx, models.OIDCLoginState{
+		State:        state,
+		Provider:     providerName,
+		CodeVerifier: verifier,
+		Nonce:        nonce,
+		InviteToken:  inviteToken,
+		ExpiresAt:    time.Now().Add(OIDCLoginTTL),
+	})
+	if err != nil {
+		return models.OIDCStartResponse{}, err
+	}
+	return models.OIDCStartResponse{AuthorizationURL: url, State: state}, nil
+}
+
+// Callback completes a login: it redeems the code, resolves the external
+// identity to a user and issues our own tokens. Identities are linked to
+// existing accounts by email only when the provider has verified it; unknown
+// emails get a new client account.
+func (s *OIDCService) Callback(ctx context.Context, providerName, code, state, userAgent, ip string) (models.SignInResult, error) {
+	provider, ok := s.Providers[providerName]
+	if !ok {
+		return models.SignInResult{}, models.ErrUnknownOIDCProvider
+	}
+	login, err := s.Repo.ConsumeLoginState(ctx, state)
+	if err != nil {
+		return models.SignInResult{}, err
+	}
+	if login.Provider != providerName {
+		return models.SignInResult{}, models.ErrInvalidOIDCState
+	}
+
+	idToken, err := provider.Exchange(ctx, code, login.CodeVerifier, login.Nonce)
+	if err != nil {
+		log.Printf("OIDC login with %s failed: %v", providerName, err)
+		return models.SignInResult{}, models.ErrInvalidIDToken
+	}
+
+	user, err := s.resolveUser(ctx, providerName, idToken)
+	if err != nil {
+		return models.SignInResult{}, err
+	}
+
+	// the invite is accepted by SignInExternal, or by CompleteSignIn when a
+	// second factor is required, once the sign in has succeeded
+	return s.Users.SignInExternal(ctx, user, login.InviteToken, userAgent, ip)
+}
+
+func (s *OIDCService) resolveUser(ctx context.Context, providerName string, idToken oidc.IDToken) (models.User, error) {
+	identity, err := s.Repo.GetIdentity(ctx, providerName, idToken.Subject)
+	if err == nil {
+		return s.UserRepo.GetUserByID(ctx, identity.UserID)
+	}
+	if !errors.Is(err, repositories.ErrUserNotFound) {
+		return models.User{}, err
+	}
+
+	email := strings.ToLower(strings.TrimSpace(idToken.Email))
+	if email == "" || !idToken.EmailVerified {
+		return models.User{}, models.ErrEmailNotVerified
+	}
+
+	user, err := s.UserRepo.GetUserByEmail(ctx, email)
+	if errors.Is(err, repositories.ErrUserNotFound) {
+		name := idToken.Name
+		if name == "" {
+			name = email
+		}
+		// no password is set; the user can add one through the reset flow
+		user, err = s.UserRepo.CreateUser(ctx, models.User{Name: name, Email: email, Role: "client"})
+	}
+	if err != nil {
+		return models.User{}, err
+	}
+
+	_, err = s.Repo.CreateIdentity(ctx, models.UserIdentity{
+		UserID:   user.ID,
+		Provider: providerName,
+		Subject:  idToken.Subject,
+		Email:    email,
+	})
+	if err != nil {
+		return models.User{}, err
+	}
+	return user, nil
+}
+
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"context"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"workout/internal/models"
+	"workout/internal/printing"
+	"workout/internal/repositories"
+)
+
+var brandColorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// PrintService prepares programs for printing with the trainer's branding.
+type PrintService struct {
+	DayRepo      *repositories.DayRepository
+	UserRepo     *repositories.UserRepository
+	BrandingRepo *repositories.BrandingRepository
+	Auth         *Authorizer
+	Images       *printing.ImageFetcher
+}
+
+// Plan collects what a printout of the program shows. Trainers print the
+// draft or the requested version; enrolled clients print the version they
+// are pinned to.
+func (s *PrintService) Plan(ctx context.Context, actor models.Actor, programID, version int) (printing.Plan, error) {
+	p, err := s.Auth.ReadProgram(ctx, actor, programID)
+	if err != nil {
+		return printing.Plan{}, err
+	}
+	versionID, err := s.Auth.ViewVersion(ctx, actor, p, version)
+	if err != nil {
+		return printing.Plan{}, err
+	}
+	if versionID != nil {
+		v, err := s.Auth.VersionRepo.GetVersionByID(ctx, *versionID)
+		if err != nil {
+			return printing.Plan{}, err
+		}
+		p.Name, p.Description, p.Days, p.Version = v.Name, v.Description, v.Days, v.Version
+	}
+	days, err := s.DayRepo.DaysByProgram(ctx, programID, versionID)
+	if err != nil {
+		return printing.Plan{}, err
+	}
+	trainer, err := s.UserRepo.GetUserByID(ctx, p.TrainerID)
+	if err != nil {
+		return printing.Plan{}, err
+	}
+	branding, err := s.BrandingRepo.GetBranding(ctx, p.TrainerID)
+	if err != nil {
+		return printing.Plan{}, err
+	}
+	plan := printing.Plan{Program: p, Days: days, Branding: branding, TrainerName: trainer.Name, GeneratedAt: time.Now()}
+	if s.Images != nil {
+		plan.Images = s.Images.FetchAll(ctx, printing.ImageURLs(plan))
+	}
+	return plan, nil
+}
+
+// Branding returns the actor's printout branding.
+func (s *PrintService) Branding(ctx context.Context, actor models.Actor) (models.Branding, error) {
+	return s.BrandingRepo.GetBranding(ctx, actor.UserID)
+}
+
+// UpdateBranding replaces the actor's printout branding.
+func (s *PrintService) UpdateBranding(ctx context.Context, actor models.Actor, b models.Branding) (models.Branding, error) {
+	b.TrainerID = actor.UserID
+	b.BrandName = strings.TrimSpace(b.BrandName)
+	b.Color = strings.ToLower(strings.TrimSpace(b.Color))
+	b.LogoURL = strings.TrimSpace(b.LogoURL)
+	b.Footer = strings.TrimSpace(b.Footer)
+	if b.Color != "" && !brandColorPattern.MatchString(b.Color) {
+		return models.Branding{}, models.ErrInvalidBranding
+	}
+	if b.LogoURL != "" {
+		u, err := url.Parse(b.LogoURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return models.Branding{}, models.ErrInvalidBranding
+		}
+	}
+	if len(b.BrandName) > 255 || len(b.Footer) > 500 {
+		return models.Branding{}, models.ErrInvalidBranding
+	}
+	return s.BrandingRepo.SaveBranding(ctx, b)
+}
@@ -0,0 +1,177 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"workout/internal/models"
+	"workout/internal/repositories"
+)
+
+// maxDocumentDays bounds the length of an imported program.
+const maxDocumentDays = 366
+
+// ProgramDocumentService exports programs to portable documents and imports
+// them back, possibly into another trainer's account.
+type ProgramDocumentService struct {
+	Repo    *repositories.ProgramRepository
+	DayRepo *repositories.DayRepository
+	Auth    *Authorizer
+}
+
+// Export builds the document of the program's draft, or of a published
+// version when version is not zero.
+func (s *ProgramDocumentService) Export(ctx context.Context, actor models.Actor, programID, version int) (models.ProgramDocument, error) {
+	p, err := s.Auth.Program(ctx, actor, programID)
+	if err != nil {
+		return models.ProgramDocument{}, err
+	}
+	var versionID *int
+	if version != 0 {
+		v, err := s.Auth.VersionRepo.GetVersion(ctx, programID, version)
+		if err != nil {
+			return models.ProgramDocument{}, err
+		}
+		versionID = &v.ID
+		p.Name, p.Description, p.Days = v.Name, v.Description, v.Days
+	}
+	days, err := s.DayRepo.DaysByProgram(ctx, programID, versionID)
+	if err != nil {
+		return models.ProgramDocument{}, err
+	}
+
+	now := time.Now().UTC()
+	doc := models.ProgramDocument{
+		Format:     models.ProgramDocumentFormat,
+		Version:    models.ProgramDocumentVersion,
+		ExportedAt: &now,
+		Program:    models.DocumentProgram{Name: p.Name, Description: p.Description, Days: p.Days},
+		Exercises:  []models.DocumentExercise{},
+		Food:       []models.DocumentFood{},
+		Days:       make([]models.DocumentDay, 0, len(days)),
+	}
+	exercises := map[int]string{}
+	food := map[int]string{}
+	for _, d := range days {
+		exKey, ok := exercises[d.Exercise.ID]
+		if !ok {
+			exKey = "exercise-" + strconv.Itoa(d.Exercise.ID)
+			exercises[d.Exercise.ID] = exKey
+			doc.Exercises = append(doc.Exercises, models.DocumentExercise{Key: exKey, Name: d.Exercise.Name,
+				Description: d.Exercise.Description, MediaURL: d.Exercise.MediaURL, Sets: d.Exercise.Sets, Repetitions: d.Exercise.Repetitions})
+		}
+		foodKey, ok := food[d.Food.ID]
+		if !ok {
+			foodKey = "food-" + strconv.Itoa(d.Food.ID)
+			food[d.Food.ID] = foodKey
+			doc.Food = append(doc.Food, models.DocumentFood{Key: foodKey, Name: d.Food.Name, Description: d.Food.Description,
+				Calories: d.Food.Calories, Protein: d.Food.Protein, Fats: d.Food.Fats, Carbohydrates: d.Food.Carbohydrates})
+		}
+		doc.Days = append(doc.Days, models.DocumentDay{Day: d.Day.DayNumber, Exercise: exKey, Food: foodKey, Note: d.Day.Note})
+	}
+	return doc, nil
+}
+
+// Import validates the document and creates the program for the actor as an
+// unpublished draft. Library items no day refers to are skipped.
+func (s *ProgramDocumentService) Import(ctx context.Context, actor models.Actor, doc models.ProgramDocument) (models.WorkOutProgram, error) {
+	if err := normalizeDocument(&doc); err != nil {
+		return models.WorkOutProgram{}, err
+	}
+	return s.Repo.ImportProgram(ctx, actor.UserID, doc)
+}
+
+// normalizeDocument trims the document, checks it is complete and
+// consistent and drops unreferenced exercises and food.
+func normalizeDocument(doc *models.ProgramDocument) error {
+	invalid := func(format string, args ...interface{}) error {
+		return fmt.Errorf("%w: %s", models.ErrInvalidProgramDocument, fmt.Sprintf(format, args...))
+	}
+
+	if doc.Format != models.ProgramDocumentFormat {
+		return invalid("format must be %q", models.ProgramDocumentFormat)
+	}
+	if doc.Version < 1 || doc.Version > models.ProgramDocumentVersion {
+		return invalid("unsupported version %d", doc.Version)
+	}
+	doc.Program.Name = strings.TrimSpace(doc.Program.Name)
+	if doc.Program.Name == "" || len(doc.Program.Name) > 255 {
+		return invalid("program name is required and must be at most 255 characters")
+	}
+	if doc.Program.Days < 1 || doc.Program.Days > maxDocumentDays {
+		return invalid("program days must be between 1 and %d", maxDocumentDays)
+	}
+
+	referenced := map[string]bool{}
+	seenDays := map[int]bool{}
+	for i, d := range doc.Days {
+		if d.Day < 1 || d.Day > doc.Program.Days {
+			return invalid("day %d is outside the program's %d days", d.Day, doc.Program.Days)
+		}
+		if seenDays[d.Day] {
+			return invalid("day %d is listed twice", d.Day)
+		}
+		seenDays[d.Day] = true
+		doc.Days[i].Exercise = strings.TrimSpace(d.Exercise)
+		doc.Days[i].Food = strings.TrimSpace(d.Food)
+		referenced["exercise:"+doc.Days[i].Exercise] = true
+		referenced["food:"+doc.Days[i].Food] = true
+	}
+
+	exercises := doc.Exercises[:0]
+	keys := map[string]bool{}
+	for _, e := range doc.Exercises {
+		e.Key, e.Name = strings.TrimSpace(e.Key), strings.TrimSpace(e.Name)
+		if e.Key == "" || e.Name == "" {
+			return invalid("exercises need a key and a name")
+		}
+		if keys[e.Key] {
+			return invalid("exercise key %q is used twice", e.Key)
+		}
+		keys[e.Key] = true
+		if referenced["exercise:"+e.Key] {
+			exercises = append(exercises, e)
+		}
+	}
+	doc.Exercises = exercises
+
+	food := doc.Food[:0]
+	keys = map[string]bool{}
+	for _, f := range doc.Food {
+		f.Key, f.Name = strings.TrimSpace(f.Key), strings.TrimSpace(f.Name)
+		if f.Key == "" || f.Name == "" {
+			return invalid("food items need a key and a name")
+		}
+		if keys[f.Key] {
+			return invalid("food key %q is used twice", f.Key)
+		}
+		if f.Calories < 0 || f.Protein < 0 || f.Fats < 0 || f.Carbohydrates < 0 {
+			return invalid("food %q has negative nutrition values", f.Key)
+		}
+		keys[f.Key] = true
+		if referenced["food:"+f.Key] {
+			food = append(food, f)
+		}
+	}
+	doc.Food = food
+
+	defined := map[string]bool{}
+	for _, e := range doc.Exercises {
+		defined["exercise:"+e.Key] = true
+	}
+	for _, f := range doc.Food {
+		defined["food:"+f.Key] = true
+	}
+	for _, d := range doc.Days {
+		if !defined["exercise:"+d.Exercise] {
+			return invalid("day %d references unknown exercise %q", d.Day, d.Exercise)
+		}
+		if !defined["food:"+d.Food] {
+			return invalid("day %d references unknown food %q", d.Day, d.Food)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+
+	"workout/internal/models"
+)
+
+func validDocument() models.ProgramDocument {
+	return models.ProgramDocument{
+		Format:  models.ProgramDocumentFormat,
+		Version: models.ProgramDocumentVersion,
+		Program: models.DocumentProgram{Name: "Beginner strength", Days: 3},
+		Exercises: []models.DocumentExercise{
+			{Key: "squat", Name: "Back squat", Sets: "3", Repetitions: "8"},
+			{Key: "press", Name: "Bench press"},
+		},
+		Food: []models.DocumentFood{{Key: "oats", Name: "Oatmeal", Calories: 350}},
+		Days: []models.DocumentDay{
+			{Day: 1, Exercise: "squat", Food: "oats"},
+			{Day: 3, Exercise: "press", Food: "oats", Note: "Warm up first"},
+		},
+	}
+}
+
+func TestNormalizeDocument(t *testing.T) {
+	tests := []struct {
+		name    string
+		edit    func(*models.ProgramDocument)
+		wantErr string
+		check   func(*testing.T, models.ProgramDocument)
+	}{
+		{name: "valid", edit: func(*models.ProgramDocument) {}},
+		{name: "trims names and keys", edit: func(d *models.ProgramDocument) {
+			d.Program.Name = "  Beginner strength "
+			d.Exercises[0].Key, d.Exercises[0].Name = " squat ", " Back squat "
+			d.Days[0].Exercise = "squat "
+		}, check: func(t *testing.T, d models.ProgramDocument) {
+			if d.Program.Name != "Beginner strength" || d.Exercises[0].Key != "squat" || d.Exercises[0].Name != "Back squat" || d.Days[0].Exercise != "squat" {
+				t.Errorf("not trimmed: %+v", d)
+			}
+		}},
+		{name: "drops unreferenced items", edit: func(d *models.ProgramDocument) {
+			d.Exercises = append(d.Exercises, models.DocumentExercise{Key: "unused", Name: "Unused"})
+			d.Food = append(d.Food, models.DocumentFood{Key: "unused", Name: "Unused"})
+		}, check: func(t *testing.T, d models.ProgramDocument) {
+			if len(d.Exercises) != 2 || len(d.Food) != 1 {
+				t.Errorf("got %d exercises and %d food items, want 2 and 1", len(d.Exercises), len(d.Food))
+			}
+		}},
+		{name: "older version", edit: func(d *models.ProgramDocument) { d.Version = 1 }},
+		{name: "wrong format", edit: func(d *models.ProgramDocument) { d.Format = "other" }, wantErr: "format must be"},
+		{name: "newer version", edit: func(d *models.ProgramDocument) { d.Version = models.ProgramDocumentVersion + 1 }, wantErr: "unsupported version"},
+		{name: "no version", edit: func(d *models.ProgramDocument) { d.Version = 0 }, wantErr: "unsupported version"},
+		{name: "blank name", edit: func(d *models.ProgramDocument) { d.Program.Name = "  " }, wantErr: "program name"},
+		{name: "long name", edit: func(d *models.ProgramDocument) { d.Program.Name = strings.Repeat("a", 256) }, wantErr: "program name"},
+		{name: "no days", edit: func(d *models.ProgramDocument) { d.Program.Days = 0 }, wantErr: "program days"},
+		{name: "too many days", edit: func(d *models.ProgramDocument) { d.Program.Days = maxDocumentDays + 1 }, wantErr: "program days"},
+		{name: "day out of range", edit: func(d *models.ProgramDocument) { d.Days[1].Day = 4 }, wantErr: "outside the program"},
+		{name: "day zero", edit: func(d *models.ProgramDocument) { d.Days[0].Day = 0 }, wantErr: "outside the program"},
+		{name: "day listed twice", edit: func(d *models.ProgramDocument) { d.Days[1].Day = 1 }, wantErr: "listed twice"},
+		{name: "exercise without key", edit: func(d *models.ProgramDocument) { d.Exercises[1].Key = " " }, wantErr: "exercises need"},
+		{name: "duplicate exercise key", edit: func(d *models.ProgramDocument) { d.Exercises[1].Key = "squat" }, wantErr: "used twice"},
+		{name: "food without name", edit: func(d *models.ProgramDocument) { d.Food[0].Name = "" }, wantErr: "food items need"},
+		{name: "duplicate food key", edit: func(d *models.ProgramDocument) {
+			d.Food = append(d.Food, models.DocumentFood{Key: "oats", Name: "More oats"})
+		}, wantErr: "used twice"},
+		{name: "negative nutrition", edit: func(d *models.ProgramDocument) { d.Food[0].Fats = -1 }, wantErr: "negative"},
+		{name: "unknown exercise", edit: func(d *models.ProgramDocument) { d.Days[0].Exercise = "deadlift" }, wantErr: "unknown exercise"},
+		{name: "unknown food", edit: func(d *models.ProgramDocument) { d.Days[0].Food = "rice" }, wantErr: "unknown food"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := validDocument()
+			tt.edit(&doc)
+			err := normalizeDocument(&doc)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if tt.check != nil {
+					tt.check(t, doc)
+				}
+				return
+			}
+			if !errors.Is(err, models.ErrInvalidProgramDocument) || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestDocumentRoundTrip exports a program, serializes the document and
+// checks that it reads back as a valid document describing the same program.
+func TestDocumentRoundTrip(t *testing.T) {
+	codecs := []struct {
+		name      string
+		marshal   func(interface{}) ([]byte, error)
+		unmarshal func([]byte, interface{}) error
+	}{
+		{"json", json.Marshal, json.Unmarshal},
+		{"yaml", yaml.Marshal, yaml.Unmarshal},
+	}
+	for _, c := range codecs {
+		t.Run(c.name, func(t *testing.T) {
+			a, mock := newTestAuthorizer(t)
+			s := &ProgramDocumentService{Repo: a.ProgramRepo, DayRepo: a.DayRepo, Auth: a}
+			squat := testDay(1, 1, 1, "Warm up first")
+			squat.Exercise = models.Exercises{ID: 1, Name: "Back squat", Sets: "3", Repetitions: "8", MediaURL: "https://example.com/squat.png"}
+			squat.Food = models.Food{ID: 1, Name: "Oatmeal", Calories: 350, Protein: 12.5}
+			again := squat
+			again.Day = models.Days{ID: 103, DayNumber: 3}
+			expectProgram(mock, 10, trainer.UserID)
+			expectDays(mock, 10, nil, squat, again)
+
+			exported, err := s.Export(context.Background(), trainer, 10, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(exported.Exercises) != 1 || len(exported.Food) != 1 || len(exported.Days) != 2 {
+				t.Fatalf("shared items were not listed once: %+v", exported)
+			}
+
+			data, err := c.marshal(exported)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var imported models.ProgramDocument
+			if err := c.unmarshal(data, &imported); err != nil {
+				t.Fatal(err)
+			}
+			if err := normalizeDocument(&imported); err != nil {
+				t.Fatalf("exported document does not import: %v", err)
+			}
+			if imported.ExportedAt == nil || !imported.ExportedAt.Equal(*exported.ExportedAt) {
+				t.Errorf("exported_at = %v, want %v", imported.ExportedAt, exported.ExportedAt)
+			}
+			imported.ExportedAt, exported.ExportedAt = nil, nil
+			if !reflect.DeepEqual(imported, exported) {
+				t.Fatalf("round trip changed the document:\n got %+v\nwant %+v", imported, exported)
+			}
+		})
+	}
+}
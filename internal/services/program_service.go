@@ -2,6 +2,10 @@ package services
 
 import (
 	"context"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
 
 	"workout/internal/models"
 	"workout/internal/repositories"
@@ -10,24 +14,185 @@ import (
 // ProgramService handles business logic for workout programs.
 type ProgramService struct {
 	Repo *repositories.ProgramRepository
+	Auth *Authorizer
 }
 
 func (s *ProgramService) CreateProgram(ctx context.Context, p models.WorkOutProgram) (models.WorkOutProgram, error) {
 	return s.Repo.CreateProgram(ctx, p)
 }
 
-func (s *ProgramService) ProgramsByTrainer(ctx context.Context, trainerID int) ([]models.WorkOutProgram, error) {
+// ProgramsByTrainer lists the programs of a trainer. Only admins may list
+// programs of other trainers.
+func (s *ProgramService) ProgramsByTrainer(ctx context.Context, actor models.Actor, trainerID int) ([]models.WorkOutProgram, error) {
+	if !actor.IsAdmin() && trainerID != actor.UserID {
+		return nil, models.ErrForbidden
+	}
 	return s.Repo.GetProgramsByTrainer(ctx, trainerID)
 }
 
-func (s *ProgramService) ProgramByID(ctx context.Context, id int) (models.WorkOutProgram, error) {
-	return s.Repo.GetProgramByID(ctx, id)
+// ProgramByID returns a program to its trainer or to an enrolled client
+// with active access. Clients see the version they are pinned to.
+func (s *ProgramService) ProgramByID(ctx context.Context, actor models.Actor, id int) (models.WorkOutProgram, error) {
+	p, err := s.Auth.ReadProgram(ctx, actor, id)
+	if err != nil {
+		return models.WorkOutProgram{}, err
+	}
+	versionID, err := s.Auth.ViewVersion(ctx, actor, p, 0)
+	if err != nil || versionID == nil {
+		return p, err
+	}
+	v, err := s.Auth.VersionRepo.GetVersionByID(ctx, *versionID)
+	if err != nil {
+		return models.WorkOutProgram{}, err
+	}
+	p.Name, p.Description, p.Days, p.Version = v.Name, v.Description, v.Days, v.Version
+	return p, nil
 }
 
-func (s *ProgramService) UpdateProgram(ctx context.Context, p models.WorkOutProgram) (models.WorkOutProgram, error) {
+func (s *ProgramService) UpdateProgram(ctx context.Context, actor models.Actor, p models.WorkOutProgram) (models.WorkOutProgram, error) {
+	existing, err := s.Auth.Program(ctx, actor, p.ID)
+	if err != nil {
+		return models.WorkOutProgram{}, err
+	}
+	p.TrainerID = existing.TrainerID
 	return s.Repo.UpdateProgram(ctx, p)
 }
 
-func (s *ProgramService) DeleteProgram(ctx context.Context, id int) error {
+func (s *ProgramService) DeleteProgram(ctx context.Context, actor models.Actor, id int) error {
+	if _, err := s.Auth.Program(ctx, actor, id); err != nil {
+		return err
+	}
 	return s.Repo.DeleteProgram(ctx, id)
 }
+
+// CloneProgram deep-copies a program the actor manages. The copy belongs to
+// the source program's trainer and starts as an unpublished draft.
+func (s *ProgramService) CloneProgram(ctx context.Context, actor models.Actor, id int, req models.CloneProgramRequest) (models.WorkOutProgram, error) {
+	src, err := s.Auth.Program(ctx, actor, id)
+	if err != nil {
+		return models.WorkOutProgram{}, err
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		name = src.Name + " (copy)"
+	}
+	p := models.WorkOutProgram{TrainerID: src.TrainerID, Name: name, Days: src.Days, Description: src.Description}
+	return s.Repo.CloneProgram(ctx, src.ID, p, req.CopyResources)
+}
+
+const (
+	defaultCatalogPerPage = 20
+	maxCatalogPerPage     = 100
+)
+
+var (
+	slugPattern  = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+	slugSeparate = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// ListInCatalog publishes the program to the public catalog, or updates its
+// listing. Only programs with a published version can be listed; the catalog
+// always shows the latest version.
+func (s *ProgramService) ListInCatalog(ctx context.Context, actor models.Actor, id int, req models.CatalogRequest) (models.CatalogProgram, error) {
+	p, err := s.Auth.Program(ctx, actor, id)
+	if err != nil {
+		return models.CatalogProgram{}, err
+	}
+	published, err := s.Auth.VersionRepo.HasVersions(ctx, id)
+	if err != nil {
+		return models.CatalogProgram{}, err
+	}
+	if !published {
+		return models.CatalogProgram{}, models.ErrProgramNotPublished
+	}
+
+	req.Slug = strings.TrimSpace(req.Slug)
+	if req.Slug == "" {
+		req.Slug = slugify(p.Name, p.ID)
+	}
+	req.CoverImageURL = strings.TrimSpace(req.CoverImageURL)
+	req.Summary = strings.TrimSpace(req.Summary)
+	if len(req.Slug) < 3 || len(req.Slug) > 100 || !slugPattern.MatchString(req.Slug) || !validCoverURL(req.CoverImageURL) {
+		return models.CatalogProgram{}, models.ErrInvalidCatalog
+	}
+	if req.PreviewDays < 0 {
+		req.PreviewDays = 0
+	}
+	if req.PreviewDays > models.MaxPreviewDays {
+		req.PreviewDays = models.MaxPreviewDays
+	}
+
+	if err := s.Repo.SetCatalogListing(ctx, id, req); err != nil {
+		return models.CatalogProgram{}, err
+	}
+	return s.CatalogProgram(ctx, req.Slug)
+}
+
+// RemoveFromCatalog makes the program private again.
+func (s *ProgramService) RemoveFromCatalog(ctx context.Context, actor models.Actor, id int) error {
+	if _, err := s.Auth.Program(ctx, actor, id); err != nil {
+		return err
+	}
+	return s.Repo.RemoveCatalogListing(ctx, id)
+}
+
+// Catalog returns a page of public programs. It needs no authentication.
+func (s *ProgramService) Catalog(ctx context.Context, f models.CatalogFilter) (models.CatalogList, error) {
+	if f.Page < 1 {
+		f.Page = 1
+	}
+	if f.PerPage < 1 {
+		f.PerPage = defaultCatalogPerPage
+	}
+	if f.PerPage > maxCatalogPerPage {
+		f.PerPage = maxCatalogPerPage
+	}
+	f.Query = strings.TrimSpace(f.Query)
+	programs, total, err := s.Repo.GetCatalog(ctx, f)
+	if err != nil {
+		return models.CatalogList{}, err
+	}
+	return models.CatalogList{Programs: programs, Total: total, Page: f.Page, PerPage: f.PerPage}, nil
+}
+
+// CatalogProgram returns a public program with its preview days.
+func (s *ProgramService) CatalogProgram(ctx context.Context, slug string) (models.CatalogProgram, error) {
+	c, versionID, err := s.Repo.GetCatalogProgram(ctx, slug)
+	if err != nil {
+		return models.CatalogProgram{}, err
+	}
+	if c.PreviewDays == 0 {
+		return c, nil
+	}
+	days, err := s.Auth.DayRepo.DaysByProgram(ctx, c.ID, &versionID)
+	if err != nil {
+		return models.CatalogProgram{}, err
+	}
+	for _, d := range days {
+		if d.Day.DayNumber <= c.PreviewDays {
+			c.Preview = append(c.Preview, d)
+		}
+	}
+	return c, nil
+}
+
+// slugify derives a catalog slug from the program name, falling back to the
+// program id for names without latin letters or digits.
+func slugify(name string, id int) string {
+	slug := strings.Trim(slugSeparate.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	if len(slug) > 90 {
+		slug = strings.TrimRight(slug[:90], "-")
+	}
+	if len(slug) < 3 {
+		return "program-" + strconv.Itoa(id)
+	}
+	return slug
+}
+
+func validCoverURL(raw string) bool {
+	if raw == "" {
+		return true
+	}
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
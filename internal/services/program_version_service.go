@@ -0,0 +1,153 @@
+package services
+
+import (
+	"context"
+	"sort"
+
+	"workout/internal/models"
+	"workout/internal/repositories"
+)
+
+// ProgramVersionService publishes program versions and moves clients
+// between them. Trainers edit the draft; clients only see the version their
+// enrollment is pinned to, so edits reach them after an explicit migration.
+type ProgramVersionService struct {
+	Repo    *repositories.ProgramVersionRepository
+	DayRepo *repositories.DayRepository
+	Auth    *Authorizer
+}
+
+// Publish snapshots the draft of the program as a new version.
+func (s *ProgramVersionService) Publish(ctx context.Context, actor models.Actor, programID int) (models.ProgramVersion, error) {
+	if _, err := s.Auth.Program(ctx, actor, programID); err != nil {
+		return models.ProgramVersion{}, err
+	}
+	return s.Repo.Publish(ctx, programID, actor.UserID)
+}
+
+// Versions lists the published versions of a program of the actor.
+func (s *ProgramVersionService) Versions(ctx context.Context, actor models.Actor, programID int) ([]models.ProgramVersion, error) {
+	if _, err := s.Auth.Program(ctx, actor, programID); err != nil {
+		return nil, err
+	}
+	return s.Repo.GetVersions(ctx, programID)
+}
+
+// Version returns a published version with its days.
+func (s *ProgramVersionService) Version(ctx context.Context, actor models.Actor, programID, version int) (models.ProgramVersion, error) {
+	if _, err := s.Auth.Program(ctx, actor, programID); err != nil {
+		return models.ProgramVersion{}, err
+	}
+	v, err := s.Repo.GetVersion(ctx, programID, version)
+	if err != nil {
+		return models.ProgramVersion{}, err
+	}
+	v.DayDetails, err = s.DayRepo.DaysByProgram(ctx, programID, &v.ID)
+	if err != nil {
+		return models.ProgramVersion{}, err
+	}
+	return v, nil
+}
+
+// MigrateClients pins clients of the program to the version. Progress is
+// carried over to days with the same number.
+func (s *ProgramVersionService) MigrateClients(ctx context.Context, actor models.Actor, programID, version int, clientIDs []int) (models.MigrateClientsResult, error) {
+	if _, err := s.Auth.Program(ctx, actor, programID); err != nil {
+		return models.MigrateClientsResult{}, err
+	}
+	v, err := s.Repo.GetVersion(ctx, programID, version)
+	if err != nil {
+		return models.MigrateClientsResult{}, err
+	}
+	migrated, err := s.Repo.MigrateClients(ctx, programID, v.ID, clientIDs)
+	if err != nil {
+		return models.MigrateClientsResult{}, err
+	}
+	return models.MigrateClientsResult{Version: v.Version, Migrated: migrated}, nil
+}
+
+// versionSnapshot is the comparable content of a version or the draft.
+type versionSnapshot struct {
+	program models.WorkOutProgram
+	days    map[int]models.Days
+}
+
+// Diff compares two versions of a program. A zero to compares against the
+// current draft.
+func (s *ProgramVersionService) Diff(ctx context.Context, actor models.Actor, programID, from, to int) (models.ProgramVersionDiff, error) {
+	p, err := s.Auth.Program(ctx, actor, programID)
+	if err != nil {
+		return models.ProgramVersionDiff{}, err
+	}
+	a, err := s.snapshot(ctx, p, from)
+	if err != nil {
+		return models.ProgramVersionDiff{}, err
+	}
+	b, err := s.snapshot(ctx, p, to)
+	if err != nil {
+		return models.ProgramVersionDiff{}, err
+	}
+
+	diff := models.ProgramVersionDiff{ProgramID: programID, From: from, To: to, Fields: []models.FieldChange{}, Days: []models.DayChange{}}
+	diff.Fields = appendChange(diff.Fields, "name", a.program.Name, b.program.Name)
+	diff.Fields = appendChange(diff.Fields, "description", a.program.Description, b.program.Description)
+	diff.Fields = appendChange(diff.Fields, "days", a.program.Days, b.program.Days)
+
+	numbers := []int{}
+	for n := range a.days {
+		numbers = append(numbers, n)
+	}
+	for n := range b.days {
+		if _, ok := a.days[n]; !ok {
+			numbers = append(numbers, n)
+		}
+	}
+	sort.Ints(numbers)
+	for _, n := range numbers {
+		before, inA := a.days[n]
+		after, inB := b.days[n]
+		switch {
+		case !inB:
+			diff.Days = append(diff.Days, models.DayChange{DayNumber: n, Change: models.DayRemoved})
+		case !inA:
+			diff.Days = append(diff.Days, models.DayChange{DayNumber: n, Change: models.DayAdded})
+		default:
+			var fields []models.FieldChange
+			fields = appendChange(fields, "exercises_id", before.ExercisesID, after.ExercisesID)
+			fields = appendChange(fields, "food_id", before.FoodID, after.FoodID)
+			fields = appendChange(fields, "note", before.Note, after.Note)
+			if len(fields) > 0 {
+				diff.Days = append(diff.Days, models.DayChange{DayNumber: n, Change: models.DayChanged, Fields: fields})
+			}
+		}
+	}
+	return diff, nil
+}
+
+func (s *ProgramVersionService) snapshot(ctx context.Context, p models.WorkOutProgram, version int) (versionSnapshot, error) {
+	snap := versionSnapshot{program: p, days: map[int]models.Days{}}
+	var versionID *int
+	if version != 0 {
+		v, err := s.Repo.GetVersion(ctx, p.ID, version)
+		if err != nil {
+			return versionSnapshot{}, err
+		}
+		snap.program.Name, snap.program.Description, snap.program.Days = v.Name, v.Description, v.Days
+		versionID = &v.ID
+	}
+	days, err := s.DayRepo.DaysByProgram(ctx, p.ID, versionID)
+	if err != nil {
+		return versionSnapshot{}, err
+	}
+	for _, d := range days {
+		snap.days[d.Day.DayNumber] = d.Day
+	}
+	return snap, nil
+}
+
+func appendChange(changes []models.FieldChange, field string, from, to interface{}) []models.FieldChange {
+	if from == to {
+		return changes
+	}
+	return append(changes, models.FieldChange{Field: field, From: from, To: to})
+}
@@ -0,0 +1,199 @@
+package services
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"workout/internal/models"
+)
+
+var dayColumnNames = []string{"d.id", "d.work_out_program_id", "d.program_version_id", "d.day_number", "d.exercises_id", "d.food_id", "d.note",
+	"d.created_at", "d.updated_at",
+	"e.id", "e.name", "e.description", "e.media_url", "e.sets", "e.repetitions", "e.created_at", "e.updated_at",
+	"f.id", "f.name", "f.description", "f.calories", "f.protein", "f.fats", "f.carbohydrates", "f.created_at", "f.updated_at"}
+
+// expectDays expects DaysByProgram for the draft (nil versionID) or a
+// version and returns the days with their exercise and food.
+func expectDays(mock sqlmock.Sqlmock, programID int, versionID interface{}, days ...models.DayDetails) {
+	rows := sqlmock.NewRows(dayColumnNames)
+	for _, d := range days {
+		rows.AddRow(d.Day.ID, programID, versionID, d.Day.DayNumber, d.Exercise.ID, d.Food.ID, d.Day.Note,
+			time.Now(), nil,
+			d.Exercise.ID, d.Exercise.Name, d.Exercise.Description, d.Exercise.MediaURL, d.Exercise.Sets, d.Exercise.Repetitions, time.Now(), nil,
+			d.Food.ID, d.Food.Name, d.Food.Description, d.Food.Calories, d.Food.Protein, d.Food.Fats, d.Food.Carbohydrates, time.Now(), nil)
+	}
+	mock.ExpectQuery(query("WHERE d.work_out_program_id = ? AND d.program_version_id <=> ?")).WithArgs(programID, versionID).WillReturnRows(rows)
+}
+
+func testDay(number, exerciseID, foodID int, note string) models.DayDetails {
+	return models.DayDetails{
+		Day:      models.Days{ID: 100 + number, DayNumber: number, Note: note},
+		Exercise: models.Exercises{ID: exerciseID, Name: "Exercise"},
+		Food:     models.Food{ID: foodID, Name: "Food"},
+	}
+}
+
+func newTestVersionService(t *testing.T) (*ProgramVersionService, sqlmock.Sqlmock) {
+	a, mock := newTestAuthorizer(t)
+	return &ProgramVersionService{Repo: a.VersionRepo, DayRepo: a.DayRepo, Auth: a}, mock
+}
+
+func TestProgramVersionDiff(t *testing.T) {
+	base := []models.DayDetails{testDay(1, 1, 1, ""), testDay(2, 2, 2, "rest well"), testDay(3, 3, 3, "")}
+	tests := []struct {
+		name       string
+		to         []models.DayDetails
+		wantFields []models.FieldChange
+		wantDays   []models.DayChange
+	}{
+		{"unchanged", base, []models.FieldChange{}, []models.DayChange{}},
+		{"day edited", []models.DayDetails{base[0], testDay(2, 5, 2, "stretch"), base[2]},
+			[]models.FieldChange{},
+			[]models.DayChange{{DayNumber: 2, Change: models.DayChanged, Fields: []models.FieldChange{
+				{Field: "exercises_id", From: 2, To: 5},
+				{Field: "note", From: "rest well", To: "stretch"},
+			}}}},
+		{"days added and removed", []models.DayDetails{base[0], base[2], testDay(4, 4, 4, "")},
+			[]models.FieldChange{},
+			[]models.DayChange{{DayNumber: 2, Change: models.DayRemoved}, {DayNumber: 4, Change: models.DayAdded}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, mock := newTestVersionService(t)
+			expectProgram(mock, 10, trainer.UserID)
+			expectVersion(mock, 10, 1, 70)
+			expectDays(mock, 10, 70, base...)
+			expectDays(mock, 10, nil, tt.to...)
+
+			diff, err := s.Diff(context.Background(), trainer, 10, 1, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// the version is named "Strength v1", the draft "Strength"
+			wantFields := append([]models.FieldChange{{Field: "name", From: "Strength v1", To: "Strength"}}, tt.wantFields...)
+			if !reflect.DeepEqual(diff.Fields, wantFields) {
+				t.Errorf("fields = %+v, want %+v", diff.Fields, wantFields)
+			}
+			if !reflect.DeepEqual(diff.Days, tt.wantDays) {
+				t.Errorf("days = %+v, want %+v", diff.Days, tt.wantDays)
+			}
+			if diff.ProgramID != 10 || diff.From != 1 || diff.To != 0 {
+				t.Errorf("diff header = %d %d %d", diff.ProgramID, diff.From, diff.To)
+			}
+		})
+	}
+}
+
+func TestProgramVersionDiffBetweenVersions(t *testing.T) {
+	s, mock := newTestVersionService(t)
+	expectProgram(mock, 10, trainer.UserID)
+	expectVersion(mock, 10, 1, 70)
+	expectDays(mock, 10, 70, testDay(1, 1, 1, ""))
+	mock.ExpectQuery(query("FROM program_versions v WHERE v.program_id = ? AND v.version = ?")).WithArgs(10, 2).
+		WillReturnRows(sqlmock.NewRows(versionColumnNames).AddRow(71, 10, 2, "Strength v1", "Harder", 4, trainer.UserID, time.Now(), 0))
+	expectDays(mock, 10, 71, testDay(1, 1, 9, ""))
+
+	diff, err := s.Diff(context.Background(), trainer, 10, 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantFields := []models.FieldChange{{Field: "description", From: "", To: "Harder"}, {Field: "days", From: 3, To: 4}}
+	if !reflect.DeepEqual(diff.Fields, wantFields) {
+		t.Errorf("fields = %+v, want %+v", diff.Fields, wantFields)
+	}
+	wantDays := []models.DayChange{{DayNumber: 1, Change: models.DayChanged, Fields: []models.FieldChange{{Field: "food_id", From: 1, To: 9}}}}
+	if !reflect.DeepEqual(diff.Days, wantDays) {
+		t.Errorf("days = %+v, want %+v", diff.Days, wantDays)
+	}
+}
+
+func TestProgramVersionDiffErrors(t *testing.T) {
+	t.Run("other trainer", func(t *testing.T) {
+		s, mock := newTestVersionService(t)
+		expectProgram(mock, 10, trainer.UserID)
+		if _, err := s.Diff(context.Background(), otherTrainer, 10, 1, 0); !errors.Is(err, models.ErrForbidden) {
+			t.Fatalf("error = %v, want %v", err, models.ErrForbidden)
+		}
+	})
+	t.Run("missing version", func(t *testing.T) {
+		s, mock := newTestVersionService(t)
+		expectProgram(mock, 10, trainer.UserID)
+		mock.ExpectQuery(query("FROM program_versions v")).WithArgs(10, 5).WillReturnRows(sqlmock.NewRows(versionColumnNames))
+		if _, err := s.Diff(context.Background(), trainer, 10, 5, 0); !errors.Is(err, models.ErrVersionNotFound) {
+			t.Fatalf("error = %v, want %v", err, models.ErrVersionNotFound)
+		}
+	})
+}
+
+func TestMigrateClients(t *testing.T) {
+	tests := []struct {
+		name      string
+		clientIDs []int
+		clause    string
+		args      []driver.Value
+		migrated  int64
+	}{
+		{"all clients", nil, "NOT (pi.version_id <=> ?)", []driver.Value{70, 10, 70}, 3},
+		{"chosen clients", []int{4, 5}, "AND pi.client_id IN (?, ?)", []driver.Value{70, 10, 70, 4, 5}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, mock := newTestVersionService(t)
+			expectProgram(mock, 10, trainer.UserID)
+			expectVersion(mock, 10, 2, 70)
+			mock.ExpectBegin()
+			// progress moves to the matching days first, then missing rows
+			// are created, then the enrollments are repinned
+			mock.ExpectExec(query("UPDATE IGNORE progress p")).WithArgs(tt.args...).WillReturnResult(sqlmock.NewResult(0, 5))
+			mock.ExpectExec(query("INSERT IGNORE INTO progress")).WithArgs(tt.args...).WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectExec(query("UPDATE program_invites pi SET pi.version_id = ?") + ".*" + query(tt.clause)).WithArgs(tt.args...).
+				WillReturnResult(sqlmock.NewResult(0, tt.migrated))
+			mock.ExpectCommit()
+
+			res, err := s.MigrateClients(context.Background(), trainer, 10, 2, tt.clientIDs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Version != 2 || res.Migrated != int(tt.migrated) {
+				t.Fatalf("result = %+v", res)
+			}
+		})
+	}
+}
+
+func TestMigrateClientsRollsBack(t *testing.T) {
+	s, mock := newTestVersionService(t)
+	expectProgram(mock, 10, trainer.UserID)
+	expectVersion(mock, 10, 2, 70)
+	mock.ExpectBegin()
+	mock.ExpectExec(query("UPDATE IGNORE progress p")).WillReturnError(errors.New("deadlock"))
+	mock.ExpectRollback()
+
+	if _, err := s.MigrateClients(context.Background(), trainer, 10, 2, nil); err == nil {
+		t.Fatal("migration succeeded")
+	}
+}
+
+func TestMigrateClientsErrors(t *testing.T) {
+	t.Run("other trainer", func(t *testing.T) {
+		s, mock := newTestVersionService(t)
+		expectProgram(mock, 10, trainer.UserID)
+		if _, err := s.MigrateClients(context.Background(), otherTrainer, 10, 2, nil); !errors.Is(err, models.ErrForbidden) {
+			t.Fatalf("error = %v, want %v", err, models.ErrForbidden)
+		}
+	})
+	t.Run("missing version", func(t *testing.T) {
+		s, mock := newTestVersionService(t)
+		expectProgram(mock, 10, trainer.UserID)
+		mock.ExpectQuery(query("FROM program_versions v")).WithArgs(10, 9).WillReturnRows(sqlmock.NewRows(versionColumnNames))
+		if _, err := s.MigrateClients(context.Background(), trainer, 10, 9, nil); !errors.Is(err, models.ErrVersionNotFound) {
+			t.Fatalf("error = %v, want %v", err, models.ErrVersionNotFound)
+		}
+	})
+}
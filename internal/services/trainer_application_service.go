@@ -0,0 +1,225 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/uuid"
+	"workout/internal/mailer"
+	"workout/internal/models"
+	"workout/internal/repositories"
+)
+
+const (
+	maxCertificateSize  = 10 << 20
+	maxCertificateFiles = 5
+	applicationsPerPage = 20
+)
+
+// certificateTypes maps accepted certificate content types to file extensions.
+var certificateTypes = map[string]string{
+	"application/pdf": ".pdf",
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+}
+
+// TrainerApplicationService handles applications of clients who want to
+// become trainers. The role only changes when an admin approves.
+type TrainerApplicationService struct {
+	Repo     *repositories.TrainerApplicationRepository
+	UserRepo *repositories.UserRepository
+	Mailer   mailer.Mailer
+	Audit    *AuditService
+	// Dir is the private directory certificates are stored in.
+	Dir string
+}
+
+// Submit files an application for the user. Only clients without a pending
+// application may apply.
+func (s *TrainerApplicationService) Submit(ctx context.Context, userID int, credentials, bio string, files []*multipart.FileHeader) (models.TrainerApplication, error) {
+	credentials, bio = strings.TrimSpace(credentials), strings.TrimSpace(bio)
+	if credentials == "" || bio == "" {
+		return models.TrainerApplication{}, models.ErrInvalidApplication
+	}
+	if len(files) > maxCertificateFiles {
+		return models.TrainerApplication{}, models.ErrInvalidUpload
+	}
+
+	user, err := s.UserRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return models.TrainerApplication{}, err
+	}
+	if user.Role != models.RoleClient {
+		return models.TrainerApplication{}, models.ErrAlreadyTrainer
+	}
+	pending, err := s.Repo.HasPendingApplication(ctx, userID)
+	if err != nil {
+		return models.TrainerApplication{}, err
+	}
+	if pending {
+		return models.TrainerApplication{}, models.ErrApplicationPending
+	}
+
+	app := models.TrainerApplication{UserID: userID, Credentials: credentials, Bio: bio}
+	for _, fh := range files {
+		f, err := s.storeCertificate(fh)
+		if err != nil {
+			s.removeFiles(app.Files)
+			return models.TrainerApplication{}, err
+		}
+		app.Files = append(app.Files, f)
+	}
+
+	created, err := s.Repo.CreateApplication(ctx, app)
+	if err != nil {
+		s.removeFiles(app.Files)
+		return models.TrainerApplication{}, err
+	}
+	created.UserName, created.UserEmail = user.Name, user.Email
+	return created, nil
+}
+
+// MyApplications lists the applications of the user.
+func (s *TrainerApplicationService) MyApplications(ctx context.Context, userID int) ([]models.TrainerApplication, error) {
+	return s.Repo.GetApplicationsByUser(ctx, userID)
+}
+
+// Queue lists applications with the given status, pending by default.
+func (s *TrainerApplicationService) Queue(ctx context.Context, status string, page int) ([]models.TrainerApplication, int, error) {
+	if status == "" {
+		status = models.ApplicationPending
+	}
+	if page < 1 {
+		page = 1
+	}
+	return s.Repo.ListApplications(ctx, status, applicationsPerPage, (page-1)*applicationsPerPage)
+}
+
+// Application returns an application with its files.
+func (s *TrainerApplicationService) Application(ctx context.Context, id int) (models.TrainerApplication, error) {
+	return s.Repo.GetApplication(ctx, id)
+}
+
+// OpenFile opens a certificate of an application for download.
+func (s *TrainerApplicationService) OpenFile(ctx context.Context, applicationID, fileID int) (models.ApplicationFile, *os.File, error) {
+	f, err := s.Repo.GetFile(ctx, applicationID, fileID)
+	if err != nil {
+		return models.ApplicationFile{}, nil, err
+	}
+	file, err := os.Open(filepath.Join(s.Dir, f.Path))
+	if err != nil {
+		return models.ApplicationFile{}, nil, err
+	}
+	return f, file, nil
+}
+
+// Approve makes the applicant a trainer and notifies them.
+func (s *TrainerApplicationService) Approve(ctx context.Context, actor models.Actor, id int) (models.TrainerApplication, error) {
+	if err := s.Repo.ApproveApplication(ctx, id, actor.UserID); err != nil {
+		return models.TrainerApplication{}, err
+	}
+	app, err := s.Repo.GetApplication(ctx, id)
+	if err != nil {
+		return models.TrainerApplication{}, err
+	}
+	s.Audit.RecordActor(ctx, actor.UserID, models.AuditRoleChanged, models.AuditTargetUser, app.UserID,
+		map[string]interface{}{"from": models.RoleClient, "to": models.RoleTrainer, "application_id": app.ID})
+	s.notify(ctx, app, "Your trainer application was approved",
+		"Hi %s,\n\nYour application to become a trainer was approved. Sign in again to start creating programs.\n", app.UserName)
+	return app, nil
+}
+
+// Reject declines the application with a reason and notifies the applicant.
+func (s *TrainerApplicationService) Reject(ctx context.Context, actor models.Actor, id int, reason string) (models.TrainerApplication, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return models.TrainerApplication{}, models.ErrRejectionReasonRequired
+	}
+	if err := s.Repo.RejectApplication(ctx, id, actor.UserID, reason); err != nil {
+		return models.TrainerApplication{}, err
+	}
+	app, err := s.Repo.GetApplication(ctx, id)
+	if err != nil {
+		return models.TrainerApplication{}, err
+	}
+	s.notify(ctx, app, "Your trainer application was rejected",
+		"Hi %s,\n\nYour application to become a trainer was rejected:\n\n%s\n\nYou may apply again.\n", app.UserName, reason)
+	return app, nil
+}
+
+// notify emails the applicant. The review is already stored, so failures
+// are only logged.
+func (s *TrainerApplicationService) notify(ctx context.Context, app models.TrainerApplication, subject, format string, args ...interface{}) {
+	err := s.Mailer.Send(ctx, mailer.Message{To: app.UserEmail, Subject: subject, Body: fmt.Sprintf(format, args...)})
+	if err != nil {
+		log.Printf("Failed to notify applicant of application %d: %v", app.ID, err)
+	}
+}
+
+// storeCertificate validates an uploaded certificate by size and sniffed
+// content type and copies it into the private directory under a random name.
+func (s *TrainerApplicationService) storeCertificate(fh *multipart.FileHeader) (models.ApplicationFile, error) {
+	if fh.Size <= 0 || fh.Size > maxCertificateSize {
+		return models.ApplicationFile{}, models.ErrInvalidUpload
+	}
+	src, err := fh.Open()
+	if err != nil {
+		return models.ApplicationFile{}, err
+	}
+	defer src.Close()
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(src, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return models.ApplicationFile{}, err
+	}
+	contentType := http.DetectContentType(head[:n])
+	ext, ok := certificateTypes[contentType]
+	if !ok {
+		return models.ApplicationFile{}, models.ErrInvalidUpload
+	}
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return models.ApplicationFile{}, err
+	}
+
+	dir := filepath.Join(s.Dir, "applications")
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return models.ApplicationFile{}, err
+	}
+	rel := filepath.Join("applications", uuid.New().String()+ext)
+	dst, err := os.OpenFile(filepath.Join(s.Dir, rel), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o640)
+	if err != nil {
+		return models.ApplicationFile{}, err
+	}
+	written, err := io.Copy(dst, io.LimitReader(src, maxCertificateSize+1))
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil && written > maxCertificateSize {
+		err = models.ErrInvalidUpload
+	}
+	if err != nil {
+		os.Remove(filepath.Join(s.Dir, rel))
+		return models.ApplicationFile{}, err
+	}
+
+	return models.ApplicationFile{
+		FileName:    filepath.Base(fh.Filename),
+		ContentType: contentType,
+		Size:        written,
+		Path:        rel,
+	}, nil
+}
+
+func (s *TrainerApplicationService) removeFiles(files []models.ApplicationFile) {
+	for _, f := range files {
+		os.Remove(filepath.Join(s.Dir, f.Path))
+	}
+}
@@ -0,0 +1,135 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"workout/internal/models"
+	"workout/internal/repositories"
+	"workout/utils"
+)
+
+const (
+	totpIssuer        = "Workout"
+	recoveryCodeCount = 10
+)
+
+// TwoFactorService manages TOTP enrollment and verification.
+type TwoFactorService struct {
+	Repo     *repositories.TwoFactorRepository
+	UserRepo *repositories.UserRepository
+}
+
+// Enroll creates a new pending secret. It becomes active only after Confirm.
+func (s *TwoFactorService) Enroll(ctx context.Context, userID int) (models.TOTPEnrollment, error) {
+	if tf, err := s.Repo.GetTwoFactor(ctx, userID); err == nil && tf.EnabledAt != nil {
+		return models.TOTPEnrollment{}, models.ErrTwoFactorAlreadyEnabled
+	} else if err != nil && !errors.Is(err, models.ErrTwoFactorNotEnabled) {
+		return models.TOTPEnrollment{}, err
+	}
+
+	user, err := s.UserRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return models.TOTPEnrollment{}, err
+	}
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return models.TOTPEnrollment{}, err
+	}
+	if err := s.Repo.SavePendingSecret(ctx, userID, secret); err != nil {
+		return models.TOTPEnrollment{}, err
+	}
+	return models.TOTPEnrollment{Secret: secret, OTPAuthURI: utils.TOTPURI(totpIssuer, user.Email, secret)}, nil
+}
+
+// Confirm activates a pending secret with a first valid code and returns
+// freshly generated recovery codes.
+func (s *TwoFactorService) Confirm(ctx context.Context, userID int, code string) (models.RecoveryCodes, error) {
+	tf, err := s.Repo.GetTwoFactor(ctx, userID)
+	if err != nil {
+		return models.RecoveryCodes{}, err
+	}
+	if tf.EnabledAt != nil {
+		return models.RecoveryCodes{}, models.ErrTwoFactorAlreadyEnabled
+	}
+	if err := s.verifyTOTP(ctx, tf, code); err != nil {
+		return models.RecoveryCodes{}, err
+	}
+	if err := s.Repo.Enable(ctx, userID); err != nil {
+		return models.RecoveryCodes{}, err
+	}
+	return s.newRecoveryCodes(ctx, userID)
+}
+
+// Disable turns two-factor authentication off. A valid code is required.
+func (s *TwoFactorService) Disable(ctx context.Context, userID int, code string) error {
+	if err := s.Verify(ctx, userID, code); err != nil {
+		return err
+	}
+	return s.Repo.Disable(ctx, userID)
+}
+
+// RegenerateRecoveryCodes invalidates the old recovery codes and issues new ones.
+func (s *TwoFactorService) RegenerateRecoveryCodes(ctx context.Context, userID int, code string) (models.RecoveryCodes, error) {
+	if err := s.Verify(ctx, userID, code); err != nil {
+		return models.RecoveryCodes{}, err
+	}
+	return s.newRecoveryCodes(ctx, userID)
+}
+
+// Enabled reports whether the user has confirmed two-factor authentication.
+func (s *TwoFactorService) Enabled(ctx context.Context, userID int) (bool, error) {
+	tf, err := s.Repo.GetTwoFactor(ctx, userID)
+	if errors.Is(err, models.ErrTwoFactorNotEnabled) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return tf.EnabledAt != nil, nil
+}
+
+// Verify accepts either a current TOTP code or an unused recovery code.
+func (s *TwoFactorService) Verify(ctx context.Context, userID int, code string) error {
+	tf, err := s.Repo.GetTwoFactor(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if tf.EnabledAt == nil {
+		return models.ErrTwoFactorNotEnabled
+	}
+
+	code = strings.ToLower(strings.TrimSpace(code))
+	if strings.Contains(code, "-") {
+		return s.Repo.UseRecoveryCode(ctx, userID, code)
+	}
+	return s.verifyTOTP(ctx, tf, code)
+}
+
+func (s *TwoFactorService) verifyTOTP(ctx context.Context, tf models.TwoFactor, code string) error {
+	step, ok := utils.ValidateTOTP(tf.Secret, code, time.Now())
+	if !ok || step <= tf.LastUsedStep {
+		return models.ErrInvalidTOTPCode
+	}
+	return s.Repo.ConsumeStep(ctx, tf.UserID, step)
+}
+
+func (s *TwoFactorService) newRecoveryCodes(ctx context.Context, userID int) (models.RecoveryCodes, error) {
+	codes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			return models.RecoveryCodes{}, err
+		}
+		h := hex.EncodeToString(b)
+		codes[i] = h[:5] + "-" + h[5:]
+	}
+	if err := s.Repo.ReplaceRecoveryCodes(ctx, userID, codes); err != nil {
+		return models.RecoveryCodes{}, err
+	}
+	return models.RecoveryCodes{Codes: codes}, nil
+}
@@ -3,10 +3,11 @@ package services
 import (
 	_ "bytes"
 	"context"
+	"crypto/subtle"
 	_ "encoding/json"
 	"errors"
 	"fmt"
-	"github.com/dgrijalva/jwt-go"
+	"github.com/golang-jwt/jwt"
 	"github.com/google/uuid"
 	_ "github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
@@ -15,11 +16,12 @@ import (
 	"log"
 	_ "math/rand"
 	_ "net/http"
+	"net/mail"
 	_ "net/url"
 	_ "os"
-	"strconv"
-	_ "strings"
+	"strings"
 	"time"
+	"workout/internal/mailer"
 	"workout/internal/models"
 	"workout/internal/repositories"
 	"workout/utils"
@@ -27,106 +29,593 @@ import (
 
 type tokenClaims struct {
 	jwt.StandardClaims
-	UserID int    `json:"user_id"`
-	Role   string `json:"role"`
+	UserID    int    `json:"user_id"`
+	Role      string `json:"role"`
+	SessionID int    `json:"sid,omitempty"`
+	Purpose   string `json:"purpose,omitempty"`
+	// ImpersonatorID is the admin acting as the user, if any
+	ImpersonatorID int `json:"imp,omitempty"`
+	// InviteToken is accepted once a two-factor challenge is completed
+	InviteToken string `json:"invite,omitempty"`
 }
 type UserService struct {
 	UserRepo     *repositories.UserRepository
+	SessionRepo  *repositories.SessionRepository
+	RevokedRepo  *repositories.RevokedTokenRepository
+	ResetRepo    *repositories.PasswordResetRepository
+	EmailRepo    *repositories.EmailChangeRepository
+	APITokenRepo *repositories.APITokenRepository
+	Mailer       mailer.Mailer
+	TwoFactor    *TwoFactorService
+	Lockout      *LockoutService
 	TokenManager *utils.Manager
+	Auth         *Authorizer
+	Audit        *AuditService
+	Invites      *InviteService
 }
 
-func (s *UserService) SignIn(ctx context.Context, email, password string) (models.Tokens, error) {
+// SignIn checks the credentials and opens a new session for the device
+// described by userAgent and ip. Accounts with two-factor authentication get
+// a short-lived challenge token instead, to be completed with CompleteSignIn.
+//
+// Unknown emails and wrong passwords both yield ErrInvalidCredentials, and
+// repeated failures lock the account and the IP address for a while.
+func (s *UserService) SignIn(ctx context.Context, email, password, userAgent, ip string) (models.SignInResult, error) {
+	if err := s.Lockout.Attempt(ctx, email, ip); err != nil {
+		if errors.Is(err, models.ErrTooManyAttempts) {
+			s.auditSignInFailed(ctx, 0, email, "locked")
+		}
+		return models.SignInResult{}, err
+	}
+
 	user, err := s.UserRepo.GetUserByEmail(ctx, email)
-	if err != nil {
-		log.Printf("User not found: %s", email)
-		return models.Tokens{}, errors.New("user not found")
+	if err != nil && !errors.Is(err, repositories.ErrUserNotFound) {
+		return models.SignInResult{}, err
+	}
+	found := err == nil
+	hash := user.Password
+	if !found {
+		// compare against a dummy hash so unknown emails take as long as known ones
+		hash = string(dummyPasswordHash)
 	}
 
 	// Compare the provided password with the hashed password
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
-		log.Printf("Invalid password for user: %s", email)
-		return models.Tokens{}, errors.New("invalid password")
+	if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) != nil || !found {
+		log.Printf("Failed sign in for %s from %s", email, ip)
+		s.auditSignInFailed(ctx, user.ID, email, "invalid_credentials")
+		// the failure was already counted by Attempt
+		return models.SignInResult{}, models.ErrInvalidCredentials
+	}
+	if err := s.Lockout.Release(ctx, email, ip); err != nil {
+		return models.SignInResult{}, err
+	}
+	if err := checkAccount(user); err != nil {
+		s.auditSignInFailed(ctx, user.ID, email, err.Error())
+		return models.SignInResult{}, err
+	}
+
+	challenge, err := s.twoFactorChallenge(ctx, user, "")
+	if err != nil {
+		return models.SignInResult{}, err
+	}
+	if challenge != "" {
+		return models.SignInResult{MFARequired: true, ChallengeToken: challenge}, nil
+	}
+
+	if err := s.Lockout.Succeed(ctx, email); err != nil {
+		return models.SignInResult{}, err
+	}
+
+	tokens, err := s.CreateSession(ctx, user, userAgent, ip)
+	if err != nil {
+		log.Printf("Error creating session: %v", err)
+		return models.SignInResult{}, err
 	}
+	s.auditSignIn(ctx, user, "password", userAgent)
+
+	return models.SignInResult{Tokens: &tokens}, nil
+}
+
+// SignInExternal signs in a user who was already authenticated elsewhere,
+// e.g. by an OpenID Connect provider. Two-factor authentication still applies.
+// An optional invite token is accepted only once the sign in succeeds.
+func (s *UserService) SignInExternal(ctx context.Context, user models.User, inviteToken, userAgent, ip string) (models.SignInResult, error) {
+	if err := checkAccount(user); err != nil {
+		s.auditSignInFailed(ctx, user.ID, user.Email, err.Error())
+		return models.SignInResult{}, err
+	}
+	challenge, err := s.twoFactorChallenge(ctx, user, inviteToken)
+	if err != nil {
+		return models.SignInResult{}, err
+	}
+	if challenge != "" {
+		return models.SignInResult{MFARequired: true, ChallengeToken: challenge}, nil
+	}
+
+	tokens, err := s.CreateSession(ctx, user, userAgent, ip)
+	if err != nil {
+		return models.SignInResult{}, err
+	}
+	s.auditSignIn(ctx, user, "external", userAgent)
+	s.acceptInvite(ctx, user, inviteToken)
+	return models.SignInResult{Tokens: &tokens}, nil
+}
+
+// acceptInvite accepts an invite the user brought along to sign in. It runs
+// only once the sign in has succeeded, and a stale or foreign invite does
+// not fail the sign in.
+func (s *UserService) acceptInvite(ctx context.Context, user models.User, inviteToken string) {
+	if inviteToken == "" || s.Invites == nil {
+		return
+	}
+	if _, err := s.Invites.AcceptInvite(ctx, inviteToken, user.ID); err != nil {
+		log.Printf("Error accepting invite after sign in of user %d: %v", user.ID, err)
+	}
+}
+
+func (s *UserService) auditSignIn(ctx context.Context, user models.User, method, userAgent string) {
+	s.Audit.RecordActor(ctx, user.ID, models.AuditSignIn, models.AuditTargetUser, user.ID,
+		map[string]interface{}{"method": method, "user_agent": userAgent})
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, &tokenClaims{
+// auditSignInFailed records a failed sign in. userID is zero for unknown emails.
+func (s *UserService) auditSignInFailed(ctx context.Context, userID int, email, reason string) {
+	s.Audit.RecordActor(ctx, 0, models.AuditSignInFailed, models.AuditTargetUser, userID,
+		map[string]interface{}{"email": email, "reason": reason})
+}
+
+// twoFactorChallenge returns a challenge token when the user has two-factor
+// authentication enabled, or an empty string otherwise.
+func (s *UserService) twoFactorChallenge(ctx context.Context, user models.User, inviteToken string) (string, error) {
+	if s.TwoFactor == nil {
+		return "", nil
+	}
+	enabled, err := s.TwoFactor.Enabled(ctx, user.ID)
+	if err != nil || !enabled {
+		return "", err
+	}
+	return s.TokenManager.Sign(&tokenClaims{
 		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: time.Now().Add(tokenTTL).Unix(),
+			ExpiresAt: time.Now().Add(mfaChallengeTTL).Unix(),
 			IssuedAt:  time.Now().Unix(),
 		},
-		UserID: user.ID,
-		Role:   user.Role,
+		UserID:      user.ID,
+		Purpose:     mfaChallengePurpose,
+		InviteToken: inviteToken,
 	})
+}
 
-	accessToken, err := token.SignedString([]byte(signingKey))
+// checkAccount rejects suspended accounts and accounts that must reset
+// their password before signing in.
+func checkAccount(user models.User) error {
+	if user.SuspendedAt != nil {
+		return models.ErrAccountSuspended
+	}
+	if user.PasswordResetRequired {
+		return models.ErrPasswordResetRequired
+	}
+	return nil
+}
+
+// UnlockUser lifts a sign-in lockout of the user's account.
+func (s *UserService) UnlockUser(ctx context.Context, userID int) error {
+	user, err := s.UserRepo.GetUserByID(ctx, userID)
 	if err != nil {
-		log.Printf("Error signing token: %v", err)
-		return models.Tokens{}, err
+		return err
 	}
-	fmt.Println("login token:", accessToken)
-	tokens, err := s.CreateSession(ctx, user, accessToken)
+	return s.Lockout.Unlock(ctx, user.Email)
+}
+
+// CompleteSignIn finishes a two-step sign in with a TOTP or recovery code.
+func (s *UserService) CompleteSignIn(ctx context.Context, challengeToken, code, userAgent, ip string) (models.Tokens, error) {
+	claims := &tokenClaims{}
+	if _, err := s.TokenManager.ParseWithClaims(challengeToken, claims); err != nil || claims.Purpose != mfaChallengePurpose {
+		return models.Tokens{}, models.ErrInvalidMFAChallenge
+	}
+
+	user, err := s.UserRepo.GetUserByID(ctx, claims.UserID)
 	if err != nil {
-		log.Printf("Error creating session: %v", err)
+		return models.Tokens{}, err
+	}
+	if err := checkAccount(user); err != nil {
+		s.auditSignInFailed(ctx, user.ID, user.Email, err.Error())
+		return models.Tokens{}, err
+	}
+	if err := s.Lockout.Attempt(ctx, user.Email, ip); err != nil {
+		return models.Tokens{}, err
+	}
+
+	if err := s.TwoFactor.Verify(ctx, user.ID, code); err != nil {
+		if errors.Is(err, models.ErrInvalidTOTPCode) {
+			s.auditSignInFailed(ctx, user.ID, user.Email, "invalid_two_factor_code")
+			return models.Tokens{}, err
+		}
+		if releaseErr := s.Lockout.Release(ctx, user.Email, ip); releaseErr != nil {
+			return models.Tokens{}, releaseErr
+		}
+		return models.Tokens{}, err
+	}
+	if err := s.Lockout.Release(ctx, user.Email, ip); err != nil {
+		return models.Tokens{}, err
+	}
+	if err := s.Lockout.Succeed(ctx, user.Email); err != nil {
 		return models.Tokens{}, err
 	}
 
+	tokens, err := s.CreateSession(ctx, user, userAgent, ip)
+	if err != nil {
+		return models.Tokens{}, err
+	}
+	s.auditSignIn(ctx, user, "two_factor", userAgent)
+	s.acceptInvite(ctx, user, claims.InviteToken)
 	return tokens, nil
 }
 
+// dummyPasswordHash is compared against when the email is unknown.
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("dummy-password"), bcrypt.DefaultCost)
+
 const (
 	salt       = "sadasdnsadna"
 	tokenTTL   = 120 * time.Minute
-	signingKey = "asdadsadadaadsasd"
+	sessionTTL = 24 * 30 * 2 * time.Hour
+
+	// impersonation tokens are short-lived and have no session to refresh
+	impersonationTTL = 15 * time.Minute
+
+	mfaChallengeTTL     = 5 * time.Minute
+	mfaChallengePurpose = "mfa"
+
+	verificationCodeLength   = 6
+	verificationCodeTTL      = 10 * time.Minute
+	verificationCodeCooldown = time.Minute
+	verificationMaxAttempts  = 5
+
+	passwordResetTTL = time.Hour
+
+	emailChangeTTL       = 24 * time.Hour
+	emailChangeRevertTTL = 7 * 24 * time.Hour
 )
 
-func (s *UserService) CreateSession(ctx context.Context, user models.User, accessToken string) (models.Tokens, error) {
+// CreateSession stores a new device session for the user and issues
+// an access token bound to it.
+func (s *UserService) CreateSession(ctx context.Context, user models.User, userAgent, ip string) (models.Tokens, error) {
 	var (
 		res models.Tokens
 		err error
 	)
 
-	userIDStr := strconv.Itoa(user.ID)
+	res.RefreshToken, err = s.newRefreshToken()
+	if err != nil {
+		return res, err
+	}
 
-	res.AccessToken = accessToken
+	session, err := s.SessionRepo.CreateSession(ctx, models.Session{
+		UserID:       user.ID,
+		RefreshToken: res.RefreshToken,
+		UserAgent:    userAgent,
+		IPAddress:    ip,
+		ExpiresAt:    time.Now().Add(sessionTTL),
+	})
+	if err != nil {
+		return res, err
+	}
 
-	// Generate RefreshToken using UUID as a fallback
-	res.RefreshToken = uuid.New().String() // Fallback if TokenManager is unavailable
-	if s.TokenManager != nil {
-		res.RefreshToken, err = s.TokenManager.NewRefreshToken()
-		if err != nil {
-			return res, err
+	res.AccessToken, err = s.issueAccessToken(ctx, user, session)
+	if err != nil {
+		log.Printf("Error signing token: %v", err)
+		return res, err
+	}
+
+	return res, nil
+}
+
+// Refresh exchanges a refresh token for a new token pair. The presented
+// refresh token is rotated out; replaying it later revokes the whole session.
+func (s *UserService) Refresh(ctx context.Context, refreshToken string) (models.Tokens, error) {
+	session, err := s.SessionRepo.GetSessionByRefreshToken(ctx, refreshToken)
+	if errors.Is(err, models.ErrSessionNotFound) {
+		sessionID, usedErr := s.SessionRepo.GetSessionIDByUsedRefreshToken(ctx, refreshToken)
+		if usedErr != nil {
+			if errors.Is(usedErr, models.ErrSessionNotFound) {
+				return models.Tokens{}, models.ErrInvalidRefreshToken
+			}
+			return models.Tokens{}, usedErr
 		}
+		log.Printf("Refresh token reuse detected, revoking session %d", sessionID)
+		reused, err := s.SessionRepo.GetSessionByID(ctx, sessionID)
+		if err != nil && !errors.Is(err, models.ErrSessionNotFound) {
+			return models.Tokens{}, err
+		}
+		if err == nil {
+			s.Audit.RecordActor(ctx, 0, models.AuditRefreshTokenReuse, models.AuditTargetUser, reused.UserID,
+				map[string]interface{}{"session_id": sessionID})
+			// the access token of the family would otherwise live until it expires
+			if err := s.RevokedRepo.RevokeToken(ctx, reused.AccessJTI, reused.UserID, time.Now().Add(tokenTTL)); err != nil {
+				return models.Tokens{}, err
+			}
+		}
+		if err := s.SessionRepo.DeleteSessionByID(ctx, sessionID); err != nil {
+			return models.Tokens{}, err
+		}
+		return models.Tokens{}, models.ErrRefreshTokenReused
+	}
+	if err != nil {
+		return models.Tokens{}, err
+	}
+	if session.ExpiresAt.Before(time.Now()) {
+		return models.Tokens{}, models.ErrInvalidRefreshToken
 	}
 
-	// Создание и сохранение сессии с RefreshToken
-	session := models.Session{
-		RefreshToken: res.RefreshToken,
-		ExpiresAt:    time.Now().Add(24 * 30 * 2 * time.Hour),
+	user, err := s.UserRepo.GetUserByID(ctx, session.UserID)
+	if err != nil {
+		return models.Tokens{}, err
+	}
+	if err := checkAccount(user); err != nil {
+		return models.Tokens{}, err
 	}
 
-	err = s.UserRepo.SetSession(ctx, userIDStr, session)
+	var res models.Tokens
+	res.RefreshToken, err = s.newRefreshToken()
 	if err != nil {
-		return res, err
+		return models.Tokens{}, err
+	}
+	if err := s.SessionRepo.RotateRefreshToken(ctx, session.ID, refreshToken, res.RefreshToken); err != nil {
+		return models.Tokens{}, err
 	}
 
+	res.AccessToken, err = s.issueAccessToken(ctx, user, session)
+	if err != nil {
+		return models.Tokens{}, err
+	}
+	s.Audit.RecordActor(ctx, user.ID, models.AuditTokenRefresh, models.AuditTargetUser, user.ID,
+		map[string]interface{}{"session_id": session.ID})
 	return res, nil
 }
 
+func (s *UserService) newRefreshToken() (string, error) {
+	// Fallback to UUID if TokenManager is unavailable
+	if s.TokenManager == nil {
+		return uuid.New().String(), nil
+	}
+	return s.TokenManager.NewRefreshToken()
+}
+
+// issueAccessToken signs a new access token for the session. Only the latest
+// access token of a session stays valid, the previous one is revoked.
+func (s *UserService) issueAccessToken(ctx context.Context, user models.User, session models.Session) (string, error) {
+	jti := uuid.New().String()
+	signed, err := s.TokenManager.Sign(&tokenClaims{
+		StandardClaims: jwt.StandardClaims{
+			Id:        jti,
+			ExpiresAt: time.Now().Add(tokenTTL).Unix(),
+			IssuedAt:  time.Now().Unix(),
+		},
+		UserID:    user.ID,
+		Role:      user.Role,
+		SessionID: session.ID,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.SessionRepo.SetAccessJTI(ctx, session.ID, jti); err != nil {
+		return "", err
+	}
+	if err := s.RevokedRepo.RevokeToken(ctx, session.AccessJTI, user.ID, time.Now().Add(tokenTTL)); err != nil {
+		return "", err
+	}
+	return signed, nil
+}
+
+// IssueImpersonationToken signs an access token that lets the admin act as
+// the user. It is not bound to a session, so it cannot be refreshed and
+// ends with Logout or when it expires.
+func (s *UserService) IssueImpersonationToken(user models.User, adminID int) (string, time.Time, error) {
+	expiresAt := time.Now().Add(impersonationTTL)
+	signed, err := s.TokenManager.Sign(&tokenClaims{
+		StandardClaims: jwt.StandardClaims{
+			Id:        uuid.New().String(),
+			ExpiresAt: expiresAt.Unix(),
+			IssuedAt:  time.Now().Unix(),
+		},
+		UserID:         user.ID,
+		Role:           user.Role,
+		ImpersonatorID: adminID,
+	})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return signed, expiresAt, nil
+}
+
+// Sessions lists the active sessions of a user, flagging the one the
+// request was made with.
+func (s *UserService) Sessions(ctx context.Context, userID, currentSessionID int) ([]models.Session, error) {
+	sessions, err := s.SessionRepo.GetSessionsByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == currentSessionID
+	}
+	return sessions, nil
+}
+
+// RevokeSession signs a user out of one of their devices.
+func (s *UserService) RevokeSession(ctx context.Context, userID, sessionID int) error {
+	session, err := s.SessionRepo.GetSessionByID(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	if session.UserID != userID {
+		return models.ErrSessionNotFound
+	}
+	if err := s.RevokedRepo.RevokeToken(ctx, session.AccessJTI, userID, time.Now().Add(tokenTTL)); err != nil {
+		return err
+	}
+	return s.SessionRepo.DeleteSession(ctx, userID, sessionID)
+}
+
+// Logout ends the session the request was made with and revokes its access token.
+func (s *UserService) Logout(ctx context.Context, userID, sessionID int, jti string, expiresAt time.Time) error {
+	if err := s.RevokedRepo.RevokeToken(ctx, jti, userID, expiresAt); err != nil {
+		return err
+	}
+	if sessionID != 0 {
+		if err := s.SessionRepo.DeleteSession(ctx, userID, sessionID); err != nil && !errors.Is(err, models.ErrSessionNotFound) {
+			return err
+		}
+	}
+	if err := s.RevokedRepo.DeleteExpired(ctx); err != nil {
+		log.Printf("Failed to prune revoked tokens: %v", err)
+	}
+	return nil
+}
+
+// LogoutEverywhere ends all sessions of the user and revokes their access tokens.
+func (s *UserService) LogoutEverywhere(ctx context.Context, userID int, jti string, expiresAt time.Time) error {
+	sessions, err := s.SessionRepo.GetSessionsByUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, session := range sessions {
+		if err := s.RevokedRepo.RevokeToken(ctx, session.AccessJTI, userID, time.Now().Add(tokenTTL)); err != nil {
+			return err
+		}
+	}
+	if err := s.RevokedRepo.RevokeToken(ctx, jti, userID, expiresAt); err != nil {
+		return err
+	}
+	return s.SessionRepo.DeleteSessionsByUser(ctx, userID)
+}
+
+// RevokeAllAccess ends every session of the user and revokes their personal
+// API tokens, for when the account may be in someone else's hands.
+func (s *UserService) RevokeAllAccess(ctx context.Context, userID int) error {
+	if err := s.LogoutEverywhere(ctx, userID, "", time.Time{}); err != nil {
+		return err
+	}
+	return s.APITokenRepo.RevokeTokensByUser(ctx, userID)
+}
+
+// SendVerificationCode generates a new short-lived code for the email and
+// delivers it through the configured mailer.
+func (s *UserService) SendVerificationCode(ctx context.Context, email string) error {
+	if addr, err := mail.ParseAddress(email); err != nil || addr.Address != email {
+		return models.ErrInvalidEmail
+	}
+	if prev, err := s.UserRepo.GetVerificationCode(ctx, email); err == nil {
+		if time.Since(prev.CreatedAt) < verificationCodeCooldown {
+			return models.ErrVerificationCodeTooSoon
+		}
+	} else if !errors.Is(err, models.ErrInvalidVerificationCode) {
+		return err
+	}
+
+	code, err := utils.GenerateNumericCode(verificationCodeLength)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	entry := models.VerificationCodeEntry{
+		Email:     email,
+		Code:      code,
+		ExpiresAt: now.Add(verificationCodeTTL),
+		CreatedAt: now,
+	}
+	if err := s.UserRepo.SaveVerificationCode(ctx, entry); err != nil {
+		return err
+	}
+
+	return s.Mailer.Send(ctx, mailer.Message{
+		To:      email,
+		Subject: "Your verification code",
+		Body:    fmt.Sprintf("Your verification code is %s. It expires in %d minutes.\n", code, int(verificationCodeTTL.Minutes())),
+	})
+}
+
+// checkVerificationCode validates a code against the latest one issued for the
+// email. Expired codes and codes with too many failed attempts are rejected.
+func (s *UserService) checkVerificationCode(ctx context.Context, email, code string) error {
+	entry, err := s.UserRepo.GetVerificationCode(ctx, email)
+	if err != nil {
+		return err
+	}
+	if entry.Attempts >= verificationMaxAttempts || (!entry.ExpiresAt.IsZero() && entry.ExpiresAt.Before(time.Now())) {
+		return models.ErrInvalidVerificationCode
+	}
+	if subtle.ConstantTimeCompare([]byte(entry.Code), []byte(code)) != 1 {
+		if err := s.UserRepo.IncrementVerificationAttempts(ctx, entry.ID); err != nil {
+			log.Printf("Failed to record verification attempt: %v", err)
+		}
+		return models.ErrInvalidVerificationCode
+	}
+	return nil
+}
+
+// RequestPasswordReset emails a single-use reset token to the user. Unknown
+// emails are silently ignored so the endpoint does not reveal which accounts exist.
+func (s *UserService) RequestPasswordReset(ctx context.Context, email string) error {
+	user, err := s.UserRepo.GetUserByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, repositories.ErrUserNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	reset, err := s.ResetRepo.CreateReset(ctx, models.PasswordReset{
+		UserID:    user.ID,
+		Token:     uuid.New().String(),
+		ExpiresAt: time.Now().Add(passwordResetTTL),
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.Mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Password reset",
+		Body: fmt.Sprintf("Someone requested a password reset for your account.\n\nYour reset token is %s. It expires in %d minutes.\n\nIf it was not you, ignore this email.\n",
+			reset.Token, int(passwordResetTTL.Minutes())),
+	})
+}
+
+// ResetPassword sets a new password using a reset token, signs the user out
+// of all devices and revokes their API tokens.
+func (s *UserService) ResetPassword(ctx context.Context, token, newPassword string) error {
+	reset, err := s.ResetRepo.GetResetByToken(ctx, token)
+	if err != nil {
+		return err
+	}
+	if reset.UsedAt != nil || reset.ExpiresAt.Before(time.Now()) {
+		return models.ErrInvalidResetToken
+	}
+	if err := s.ResetRepo.MarkResetUsed(ctx, reset.ID); err != nil {
+		return err
+	}
+
+	hashed, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	if err := s.UserRepo.UpdatePassword(ctx, reset.UserID, string(hashed)); err != nil {
+		return err
+	}
+
+	return s.RevokeAllAccess(ctx, reset.UserID)
+}
+
 func (s *UserService) CreateUser(ctx context.Context, user models.User) (models.User, error) {
 	return s.UserRepo.CreateUser(ctx, user)
 }
 
 func (s *UserService) SignUp(ctx context.Context, user models.User, inputCode string) (models.SignUpResponse, error) {
-	// 1. Получаем ожидаемый код из базы
-	codeFromDB, err := s.UserRepo.GetVerificationCodeByEmail(ctx, user.Email)
-	if err != nil {
+	// 1-2. Проверяем код из базы (срок действия и число попыток)
+	if err := s.checkVerificationCode(ctx, user.Email, inputCode); err != nil {
 		return models.SignUpResponse{}, err
 	}
 
-	// 2. Сравниваем коды
-	if inputCode != codeFromDB {
-		return models.SignUpResponse{}, models.ErrInvalidVerificationCode
-	}
-
 	// 3. Хешируем пароль
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
 	if err != nil {
@@ -147,32 +636,69 @@ func (s *UserService) SignUp(ctx context.Context, user models.User, inputCode st
 	return models.SignUpResponse{User: newUser}, nil
 }
 
-func (s *UserService) UpgradeToTrainer(ctx context.Context, userID int) error {
-	return s.UserRepo.UpdateUserRole(ctx, userID, "trainer")
-}
-
 func (s *UserService) GetAllClients(ctx context.Context) ([]models.User, error) {
 	return s.UserRepo.GetAllClients(ctx)
 }
 
-func (s *UserService) GetClientsByProgramID(ctx context.Context, programID int) ([]models.User, error) {
+func (s *UserService) GetClientsByProgramID(ctx context.Context, actor models.Actor, programID int) ([]models.User, error) {
+	if _, err := s.Auth.Program(ctx, actor, programID); err != nil {
+		return nil, err
+	}
 	return s.UserRepo.GetClientsByProgramID(ctx, programID)
 }
 
-func (s *UserService) DeleteClientFromProgram(ctx context.Context, programID, clientID int) error {
-	return s.UserRepo.DeleteClientFromProgram(ctx, programID, clientID)
+func (s *UserService) DeleteClientFromProgram(ctx context.Context, actor models.Actor, programID, clientID int) error {
+	if _, err := s.Auth.Program(ctx, actor, programID); err != nil {
+		return err
+	}
+	if err := s.UserRepo.DeleteClientFromProgram(ctx, programID, clientID); err != nil {
+		return err
+	}
+	s.Audit.RecordActor(ctx, actor.UserID, models.AuditClientRemoved, models.AuditTargetProgram, programID,
+		map[string]interface{}{"client_id": clientID})
+	return nil
 }
-func (s *UserService) GetProgramsByClientID(ctx context.Context, clientID int) ([]models.WorkOutProgram, error) {
-	return s.UserRepo.GetProgramsByClientID(ctx, clientID)
+
+// GetProgramsByClientID lists the programs of a client. Clients only see
+// programs they still have access to; trainers only see their own programs
+// the client is enrolled in.
+func (s *UserService) GetProgramsByClientID(ctx context.Context, actor models.Actor, clientID int) ([]models.WorkOutProgram, error) {
+	if err := s.Auth.Client(ctx, actor, clientID); err != nil {
+		return nil, err
+	}
+	self := actor.UserID == clientID && !actor.IsAdmin()
+	programs, err := s.UserRepo.GetProgramsByClientID(ctx, clientID, self)
+	if err != nil || actor.IsAdmin() || actor.UserID == clientID {
+		return programs, err
+	}
+	own := []models.WorkOutProgram{}
+	for _, p := range programs {
+		if p.TrainerID == actor.UserID {
+			own = append(own, p)
+		}
+	}
+	return own, nil
 }
 
-// UpdateProfile updates user's profile. Changing email or password requires verification.
+// UpdateProfile updates user's profile. Changing the password requires a
+// verification code sent to the current email. A new email is not applied
+// directly: it becomes a pending change that the new address has to confirm.
 func (s *UserService) UpdateProfile(ctx context.Context, userID int, req models.UserUpdateRequest) (models.User, error) {
 	user, err := s.UserRepo.GetUserByID(ctx, userID)
 	if err != nil {
 		return models.User{}, err
 	}
 
+	newEmail := strings.TrimSpace(req.Email)
+	if newEmail == user.Email {
+		newEmail = ""
+	}
+	if newEmail != "" {
+		if err := s.checkEmailAvailable(ctx, newEmail); err != nil {
+			return models.User{}, err
+		}
+	}
+
 	if req.Name != "" {
 		user.Name = req.Name
 	}
@@ -180,33 +706,132 @@ func (s *UserService) UpdateProfile(ctx context.Context, userID int, req models.
 		user.Phone = req.Phone
 	}
 
-	if req.Email != "" || req.Password != "" {
+	if req.Password != "" {
 		if req.VerificationCode == "" {
 			return models.User{}, models.ErrInvalidVerificationCode
 		}
-		emailToCheck := req.Email
-		if emailToCheck == "" {
-			emailToCheck = user.Email
+		if err := s.checkVerificationCode(ctx, user.Email, req.VerificationCode); err != nil {
+			return models.User{}, err
 		}
-		code, err := s.UserRepo.GetVerificationCodeByEmail(ctx, emailToCheck)
+		_ = s.UserRepo.ClearVerificationCode(ctx, user.Email)
+		hashed, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 		if err != nil {
 			return models.User{}, err
 		}
-		if code != req.VerificationCode {
-			return models.User{}, models.ErrInvalidVerificationCode
-		}
-		_ = s.UserRepo.ClearVerificationCode(ctx, emailToCheck)
-		if req.Email != "" {
-			user.Email = req.Email
-		}
-		if req.Password != "" {
-			hashed, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
-			if err != nil {
-				return models.User{}, err
-			}
-			user.Password = string(hashed)
+		user.Password = string(hashed)
+	}
+
+	updated, err := s.UserRepo.UpdateUser(ctx, user)
+	if err != nil {
+		return models.User{}, err
+	}
+	if newEmail != "" {
+		if err := s.startEmailChange(ctx, updated, newEmail); err != nil {
+			return models.User{}, err
 		}
+		updated.PendingEmail = newEmail
 	}
+	return updated, nil
+}
 
-	return s.UserRepo.UpdateUser(ctx, user)
+// checkEmailAvailable validates the address and makes sure no account uses it.
+func (s *UserService) checkEmailAvailable(ctx context.Context, email string) error {
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return models.ErrInvalidEmail
+	}
+	_, err = s.UserRepo.GetUserByEmail(ctx, email)
+	if err == nil {
+		return models.ErrEmailTaken
+	}
+	if errors.Is(err, repositories.ErrUserNotFound) {
+		return nil
+	}
+	return err
+}
+
+// startEmailChange stores a pending email change, sends the confirm token to
+// the new address and tells the old address how to undo the change.
+func (s *UserService) startEmailChange(ctx context.Context, user models.User, newEmail string) error {
+	now := time.Now()
+	change, err := s.EmailRepo.CreateChange(ctx, models.EmailChange{
+		UserID:          user.ID,
+		OldEmail:        user.Email,
+		NewEmail:        newEmail,
+		ConfirmToken:    uuid.New().String(),
+		RevertToken:     uuid.New().String(),
+		ExpiresAt:       now.Add(emailChangeTTL),
+		RevertExpiresAt: now.Add(emailChangeRevertTTL),
+	})
+	if err != nil {
+		return err
+	}
+
+	err = s.Mailer.Send(ctx, mailer.Message{
+		To:      change.NewEmail,
+		Subject: "Confirm your new email",
+		Body: fmt.Sprintf("Hi %s,\n\nConfirm that this is your new email address with the token %s. It expires in %d hours.\n\nIf it was not you, ignore this email.\n",
+			user.Name, change.ConfirmToken, int(emailChangeTTL.Hours())),
+	})
+	if err != nil {
+		return err
+	}
+	err = s.Mailer.Send(ctx, mailer.Message{
+		To:      change.OldEmail,
+		Subject: "Your email is being changed",
+		Body: fmt.Sprintf("Hi %s,\n\nSomeone asked to change the email of your account to %s.\n\nIf it was not you, undo the change with the token %s within %d days. This also signs out all devices and asks for a new password.\n",
+			user.Name, change.NewEmail, change.RevertToken, int(emailChangeRevertTTL.Hours()/24)),
+	})
+	if err != nil {
+		log.Printf("Failed to notify old address of email change %d: %v", change.ID, err)
+	}
+	return nil
+}
+
+// ConfirmEmailChange applies a pending email change using the token sent to
+// the new address.
+func (s *UserService) ConfirmEmailChange(ctx context.Context, token string) error {
+	change, err := s.EmailRepo.GetByConfirmToken(ctx, token)
+	if err != nil {
+		return err
+	}
+	if change.ConfirmedAt != nil || change.CancelledAt != nil || change.RevertedAt != nil || change.ExpiresAt.Before(time.Now()) {
+		return models.ErrInvalidEmailChangeToken
+	}
+	if err := s.EmailRepo.ConfirmChange(ctx, change); err != nil {
+		return err
+	}
+	s.Audit.RecordActor(ctx, change.UserID, models.AuditEmailChanged, models.AuditTargetUser, change.UserID,
+		map[string]interface{}{"from": change.OldEmail, "to": change.NewEmail})
+	return nil
+}
+
+// RevertEmailChange undoes an email change using the token sent to the old
+// address. A change that was already confirmed is treated as a possible
+// account takeover: the old email is restored, all sessions end and the
+// owner has to reset the password.
+func (s *UserService) RevertEmailChange(ctx context.Context, token string) error {
+	change, err := s.EmailRepo.GetByRevertToken(ctx, token)
+	if err != nil {
+		return err
+	}
+	if change.CancelledAt != nil || change.RevertedAt != nil || change.RevertExpiresAt.Before(time.Now()) {
+		return models.ErrInvalidEmailChangeToken
+	}
+	if err := s.EmailRepo.RevertChange(ctx, change); err != nil {
+		return err
+	}
+	s.Audit.RecordActor(ctx, 0, models.AuditEmailReverted, models.AuditTargetUser, change.UserID,
+		map[string]interface{}{"from": change.NewEmail, "to": change.OldEmail, "confirmed": change.ConfirmedAt != nil})
+	if change.ConfirmedAt == nil {
+		return nil
+	}
+
+	if err := s.UserRepo.SetPasswordResetRequired(ctx, change.UserID, true); err != nil {
+		return err
+	}
+	if err := s.RevokeAllAccess(ctx, change.UserID); err != nil {
+		return err
+	}
+	return s.RequestPasswordReset(ctx, change.OldEmail)
 }
@@ -0,0 +1,186 @@
+package utils
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+func rsaPEM(t *testing.T) (private, public []byte, key *rsa.PrivateKey) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}),
+		pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pub}), key
+}
+
+func ed25519PEM(t *testing.T) (private, public []byte, key ed25519.PrivateKey) {
+	t.Helper()
+	pubKey, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	priv, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, err := x509.MarshalPKIXPublicKey(pubKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: priv}),
+		pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pub}), key
+}
+
+func TestAsymmetricSigning(t *testing.T) {
+	rsaPriv, rsaPub, _ := rsaPEM(t)
+	edPriv, edPub, _ := ed25519PEM(t)
+
+	tests := []struct {
+		name      string
+		algorithm string
+		private   []byte
+		public    []byte
+	}{
+		{"RS256", "RS256", rsaPriv, rsaPub},
+		{"EdDSA", "EdDSA", edPriv, edPub},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signer := mustManager(t, "a", KeyConfig{ID: "a", Algorithm: tt.algorithm, PrivateKeyPEM: tt.private})
+			token, err := signer.NewJWT("42", time.Minute)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// a verifier that only has the public key, as after a rotation
+			verifier := mustManager(t, "b",
+				KeyConfig{ID: "a", Algorithm: tt.algorithm, PublicKeyPEM: tt.public},
+				KeyConfig{ID: "b", Secret: testSecret1})
+			if subject, err := verifier.Parse(token); err != nil || subject != "42" {
+				t.Fatalf("got %q, %v; want 42", subject, err)
+			}
+
+			if _, err := NewKeyRingManager("a", []KeyConfig{{ID: "a", Algorithm: tt.algorithm, PublicKeyPEM: tt.public}}); err == nil {
+				t.Fatal("a public key was accepted as the active key")
+			}
+		})
+	}
+}
+
+func TestParseRejectsAlgorithmConfusion(t *testing.T) {
+	rsaPriv, rsaPub, _ := rsaPEM(t)
+	m := mustManager(t, "rs", KeyConfig{ID: "rs", Algorithm: "RS256", PrivateKeyPEM: rsaPriv})
+
+	// an HS256 token keyed with the published RSA public key
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.StandardClaims{Subject: "42"})
+	forged.Header["kid"] = "rs"
+	token, err := forged.SignedString(rsaPub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Parse(token); err == nil {
+		t.Fatal("HS256 token was accepted for an RS256 key")
+	}
+
+	unsigned := jwt.NewWithClaims(jwt.SigningMethodNone, jwt.StandardClaims{Subject: "42"})
+	unsigned.Header["kid"] = "rs"
+	token, err = unsigned.SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Parse(token); err == nil {
+		t.Fatal("unsigned token was accepted")
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	rsaPriv, _, rsaKey := rsaPEM(t)
+	edPriv, _, edKey := ed25519PEM(t)
+	m := mustManager(t, "rs",
+		KeyConfig{ID: "rs", Algorithm: "RS256", PrivateKeyPEM: rsaPriv},
+		KeyConfig{ID: "ed", Algorithm: "EdDSA", PrivateKeyPEM: edPriv},
+		KeyConfig{ID: "hs", Secret: testSecret1})
+
+	set := m.JWKS()
+	want := []JWK{
+		{Kty: "OKP", Kid: "ed", Use: "sig", Alg: "EdDSA", Crv: "Ed25519",
+			X: base64.RawURLEncoding.EncodeToString(edKey.Public().(ed25519.PublicKey))},
+		{Kty: "RSA", Kid: "rs", Use: "sig", Alg: "RS256",
+			N: base64.RawURLEncoding.EncodeToString(rsaKey.N.Bytes()), E: "AQAB"},
+	}
+	if !reflect.DeepEqual(set.Keys, want) {
+		t.Fatalf("JWKS() = %+v, want %+v", set.Keys, want)
+	}
+
+	for _, k := range set.Keys {
+		pub, err := k.PublicKey()
+		if err != nil {
+			t.Fatalf("%s: %v", k.Kid, err)
+		}
+		var original interface{} = &rsaKey.PublicKey
+		if k.Kid == "ed" {
+			original = edKey.Public()
+		}
+		if !reflect.DeepEqual(pub, original) {
+			t.Errorf("%s: key does not round trip", k.Kid)
+		}
+	}
+}
+
+func TestJWKSOmitsSecrets(t *testing.T) {
+	m := mustManager(t, "hs", KeyConfig{ID: "hs", Secret: testSecret1})
+	if keys := m.JWKS().Keys; keys == nil || len(keys) != 0 {
+		t.Fatalf("JWKS() = %+v, want an empty list", keys)
+	}
+}
+
+func TestJWKPublicKey(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ec := JWK{Kty: "EC", Crv: "P-256",
+		X: base64.RawURLEncoding.EncodeToString(ecKey.X.Bytes()),
+		Y: base64.RawURLEncoding.EncodeToString(ecKey.Y.Bytes())}
+
+	tests := []struct {
+		name    string
+		jwk     JWK
+		want    interface{}
+		wantErr bool
+	}{
+		{"EC P-256", ec, &ecKey.PublicKey, false},
+		{"unsupported curve", JWK{Kty: "EC", Crv: "P-521"}, nil, true},
+		{"unsupported OKP curve", JWK{Kty: "OKP", Crv: "X25519"}, nil, true},
+		{"unsupported type", JWK{Kty: "oct"}, nil, true},
+		{"bad encoding", JWK{Kty: "RSA", N: "!!", E: "AQAB"}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.jwk.PublicKey()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("PublicKey() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
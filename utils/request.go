@@ -0,0 +1,82 @@
+package utils
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// IPResolver finds the address of the caller. X-Forwarded-For is honoured
+// only for requests that come from one of the trusted proxies; otherwise
+// the header is controlled by the client and ignored.
+type IPResolver struct {
+	trusted []*net.IPNet
+}
+
+// NewIPResolver parses trusted proxies given as IP addresses or CIDR ranges.
+func NewIPResolver(proxies []string) (*IPResolver, error) {
+	r := &IPResolver{}
+	for _, p := range proxies {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		if !strings.Contains(p, "/") {
+			ip := net.ParseIP(p)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q", p)
+			}
+			bits := 32
+			if ip.To4() == nil {
+				bits = 128
+			}
+			p = fmt.Sprintf("%s/%d", p, bits)
+		}
+		_, network, err := net.ParseCIDR(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", p, err)
+		}
+		r.trusted = append(r.trusted, network)
+	}
+	return r, nil
+}
+
+func (r *IPResolver) isTrusted(ip net.IP) bool {
+	for _, n := range r.trusted {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP returns the address of the caller. Behind trusted proxies it is
+// the rightmost X-Forwarded-For entry that is not a trusted proxy itself.
+func (r *IPResolver) ClientIP(req *http.Request) string {
+	remote, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		remote = req.RemoteAddr
+	}
+	ip := net.ParseIP(remote)
+	if ip == nil || !r.isTrusted(ip) {
+		return remote
+	}
+
+	var hops []string
+	for _, h := range req.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(h, ",")...)
+	}
+	client := remote
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := net.ParseIP(strings.TrimSpace(hops[i]))
+		if hop == nil {
+			break
+		}
+		client = hop.String()
+		if !r.isTrusted(hop) {
+			break
+		}
+	}
+	return client
+}
@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewIPResolver(t *testing.T) {
+	tests := []struct {
+		proxies []string
+		wantErr bool
+	}{
+		{nil, false},
+		{[]string{"10.0.0.1", " 10.0.0.0/8 ", "", "::1", "fd00::/8"}, false},
+		{[]string{"proxy.internal"}, true},
+		{[]string{"10.0.0.0/33"}, true},
+	}
+	for _, tt := range tests {
+		if _, err := NewIPResolver(tt.proxies); (err != nil) != tt.wantErr {
+			t.Errorf("NewIPResolver(%q) error = %v, wantErr %v", tt.proxies, err, tt.wantErr)
+		}
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	r, err := NewIPResolver([]string{"10.0.0.0/8", "2001:db8::1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		remote string
+		xff    []string
+		want   string
+	}{
+		{"direct", "203.0.113.7:5000", nil, "203.0.113.7"},
+		{"untrusted peer spoofing the header", "203.0.113.7:5000", []string{"198.51.100.1"}, "203.0.113.7"},
+		{"trusted proxy", "10.0.0.2:5000", []string{"198.51.100.1"}, "198.51.100.1"},
+		{"trusted proxy without header", "10.0.0.2:5000", nil, "10.0.0.2"},
+		{"client prepends a fake hop", "10.0.0.2:5000", []string{"1.2.3.4, 198.51.100.1"}, "198.51.100.1"},
+		{"chain of trusted proxies", "10.0.0.2:5000", []string{"198.51.100.1, 10.0.0.5", "10.0.0.9"}, "198.51.100.1"},
+		{"only trusted hops", "10.0.0.2:5000", []string{"10.0.0.5"}, "10.0.0.5"},
+		{"garbage hop", "10.0.0.2:5000", []string{"198.51.100.1, unknown"}, "10.0.0.2"},
+		{"ipv6 proxy", "[2001:db8::1]:5000", []string{"2001:db8::42"}, "2001:db8::42"},
+		{"remote without port", "203.0.113.7", nil, "203.0.113.7"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = tt.remote
+			for _, h := range tt.xff {
+				req.Header.Add("X-Forwarded-For", h)
+			}
+			if got := r.ClientIP(req); got != tt.want {
+				t.Fatalf("ClientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,61 +1,180 @@
 package utils
 
 import (
+	"crypto/ed25519"
+	"crypto/rand"
 	"errors"
 	"fmt"
+	"math/big"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt"
-	"golang.org/x/exp/rand"
 )
 
+// Manager signs and verifies JWTs. Tokens are signed with the active key and
+// carry its id in the "kid" header; any configured key can verify, which lets
+// secrets be rotated without invalidating tokens that are still in use.
 type Manager struct {
-	signingKey string
+	activeKeyID string
+	keys        map[string]signingKey
+}
+
+// KeyConfig describes one signing key. HS256 keys use Secret, RS256 and EdDSA
+// keys use a PEM encoded private key, or only a public key for keys that are
+// kept around just to verify tokens issued before a rotation.
+type KeyConfig struct {
+	ID            string
+	Algorithm     string
+	Secret        string
+	PrivateKeyPEM []byte
+	PublicKeyPEM  []byte
+}
+
+type signingKey struct {
+	method    jwt.SigningMethod
+	signKey   interface{}
+	verifyKey interface{}
 }
 
 func NewManager(signingKey string) (*Manager, error) {
-	if signingKey == "" {
-		return nil, errors.New("empty signing key")
+	return NewKeyRingManager("default", []KeyConfig{{ID: "default", Secret: signingKey}})
+}
+
+// NewKeyRingManager creates a Manager with several verification keys,
+// signing new tokens with activeKeyID.
+func NewKeyRingManager(activeKeyID string, keys []KeyConfig) (*Manager, error) {
+	m := &Manager{activeKeyID: activeKeyID, keys: make(map[string]signingKey, len(keys))}
+	for _, kc := range keys {
+		key, err := parseKey(kc)
+		if err != nil {
+			return nil, fmt.Errorf("signing key %q: %v", kc.ID, err)
+		}
+		m.keys[kc.ID] = key
+	}
+	active, ok := m.keys[activeKeyID]
+	if !ok {
+		return nil, errors.New("active signing key is not configured")
+	}
+	if active.signKey == nil {
+		return nil, errors.New("active signing key has no private key")
 	}
+	return m, nil
+}
+
+func parseKey(kc KeyConfig) (signingKey, error) {
+	switch kc.Algorithm {
+	case "", "HS256":
+		if kc.Secret == "" {
+			return signingKey{}, errors.New("empty secret")
+		}
+		return signingKey{method: jwt.SigningMethodHS256, signKey: []byte(kc.Secret), verifyKey: []byte(kc.Secret)}, nil
+	case "RS256":
+		key := signingKey{method: jwt.SigningMethodRS256}
+		if len(kc.PrivateKeyPEM) > 0 {
+			private, err := jwt.ParseRSAPrivateKeyFromPEM(kc.PrivateKeyPEM)
+			if err != nil {
+				return signingKey{}, err
+			}
+			key.signKey, key.verifyKey = private, &private.PublicKey
+			return key, nil
+		}
+		public, err := jwt.ParseRSAPublicKeyFromPEM(kc.PublicKeyPEM)
+		if err != nil {
+			return signingKey{}, err
+		}
+		key.verifyKey = public
+		return key, nil
+	case "EdDSA":
+		key := signingKey{method: jwt.SigningMethodEdDSA}
+		if len(kc.PrivateKeyPEM) > 0 {
+			private, err := jwt.ParseEdPrivateKeyFromPEM(kc.PrivateKeyPEM)
+			if err != nil {
+				return signingKey{}, err
+			}
+			edKey, ok := private.(ed25519.PrivateKey)
+			if !ok {
+				return signingKey{}, errors.New("not an Ed25519 private key")
+			}
+			key.signKey, key.verifyKey = edKey, edKey.Public()
+			return key, nil
+		}
+		public, err := jwt.ParseEdPublicKeyFromPEM(kc.PublicKeyPEM)
+		if err != nil {
+			return signingKey{}, err
+		}
+		key.verifyKey = public
+		return key, nil
+	default:
+		return signingKey{}, fmt.Errorf("unsupported algorithm %q", kc.Algorithm)
+	}
+}
 
-	return &Manager{signingKey: signingKey}, nil
+// Sign issues a token for the claims using the active key.
+func (m *Manager) Sign(claims jwt.Claims) (string, error) {
+	key := m.keys[m.activeKeyID]
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = m.activeKeyID
+	return token.SignedString(key.signKey)
+}
+
+// ParseWithClaims verifies a token and decodes it into claims. Tokens issued
+// before key ids were introduced have no "kid" and are checked with the active key.
+func (m *Manager) ParseWithClaims(tokenString string, claims jwt.Claims) (*jwt.Token, error) {
+	return jwt.ParseWithClaims(tokenString, claims, m.keyFunc)
+}
+
+func (m *Manager) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		kid = m.activeKeyID
+	}
+	key, ok := m.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	// the algorithm is bound to the key, never taken from the token alone
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("неожиданный метод подписи: %v", token.Header["alg"])
+	}
+	return key.verifyKey, nil
 }
 
 func (m *Manager) NewJWT(userId string, ttl time.Duration) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.StandardClaims{
+	return m.Sign(jwt.StandardClaims{
 		ExpiresAt: time.Now().Add(ttl).Unix(),
 		Subject:   userId,
 	})
-
-	return token.SignedString([]byte(m.signingKey))
 }
 
 func (m *Manager) Parse(accessToken string) (string, error) {
-	token, err := jwt.Parse(accessToken, func(token *jwt.Token) (i interface{}, err error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("неожиданный метод подписи: %v", token.Header["alg"])
-		}
-		return []byte(m.signingKey), nil
-	})
-	if err != nil {
+	claims := &jwt.StandardClaims{}
+	if _, err := m.ParseWithClaims(accessToken, claims); err != nil {
 		return "", err
 	}
-
-	claims, ok := token.Claims.(jwt.MapClaims)
-	if !ok {
-		return "", fmt.Errorf("ошибка при получении пользовательских данных из токена")
-	}
-
-	return claims["user_id"].(string), nil // Изменяем на "user_id"
+	return claims.Subject, nil
 }
 
 func (m *Manager) NewRefreshToken() (string, error) {
 	b := make([]byte, 32)
 
-	// Используем rand.Read для генерации случайных байтов
 	if _, err := rand.Read(b); err != nil {
 		return "", err
 	}
 
 	return fmt.Sprintf("%x", b), nil
 }
+
+// GenerateNumericCode returns a random code of n decimal digits suitable
+// for one-time verification codes.
+func GenerateNumericCode(n int) (string, error) {
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		d, err := rand.Int(rand.Reader, big.NewInt(10))
+		if err != nil {
+			return "", err
+		}
+		b.WriteByte(byte('0' + d.Int64()))
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,130 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+const (
+	testSecret1 = "0123456789abcdef0123456789abcdef"
+	testSecret2 = "fedcba9876543210fedcba9876543210"
+)
+
+func TestNewKeyRingManager(t *testing.T) {
+	tests := []struct {
+		name    string
+		active  string
+		keys    []KeyConfig
+		wantErr string
+	}{
+		{"single key", "k1", []KeyConfig{{ID: "k1", Secret: testSecret1}}, ""},
+		{"explicit algorithm", "k1", []KeyConfig{{ID: "k1", Algorithm: "HS256", Secret: testSecret1}}, ""},
+		{"rotation", "k2", []KeyConfig{{ID: "k1", Secret: testSecret1}, {ID: "k2", Secret: testSecret2}}, ""},
+		{"empty secret", "k1", []KeyConfig{{ID: "k1"}}, "empty secret"},
+		{"unknown algorithm", "k1", []KeyConfig{{ID: "k1", Algorithm: "none", Secret: testSecret1}}, "unsupported algorithm"},
+		{"active key missing", "k2", []KeyConfig{{ID: "k1", Secret: testSecret1}}, "active signing key is not configured"},
+		{"no keys", "k1", nil, "active signing key is not configured"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewKeyRingManager(tt.active, tt.keys)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("got error %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func mustManager(t *testing.T, active string, keys ...KeyConfig) *Manager {
+	t.Helper()
+	m, err := NewKeyRingManager(active, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestSignSetsKid(t *testing.T) {
+	m := mustManager(t, "k2", KeyConfig{ID: "k1", Secret: testSecret1}, KeyConfig{ID: "k2", Secret: testSecret2})
+	token, err := m.NewJWT("42", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, _, err := new(jwt.Parser).ParseUnverified(token, &jwt.StandardClaims{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kid := parsed.Header["kid"]; kid != "k2" {
+		t.Errorf("kid = %v, want k2", kid)
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	old := mustManager(t, "k1", KeyConfig{ID: "k1", Secret: testSecret1})
+	token, err := old.NewJWT("42", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacy, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.StandardClaims{Subject: "42"}).SignedString([]byte(testSecret2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		manager *Manager
+		token   string
+		wantOK  bool
+	}{
+		{"same key", old, token, true},
+		{"rotated, old key kept", mustManager(t, "k2", KeyConfig{ID: "k1", Secret: testSecret1}, KeyConfig{ID: "k2", Secret: testSecret2}), token, true},
+		{"rotated, old key dropped", mustManager(t, "k2", KeyConfig{ID: "k2", Secret: testSecret2}), token, false},
+		{"same id, new secret", mustManager(t, "k1", KeyConfig{ID: "k1", Secret: testSecret2}), token, false},
+		{"no kid, active key", mustManager(t, "k2", KeyConfig{ID: "k1", Secret: testSecret1}, KeyConfig{ID: "k2", Secret: testSecret2}), legacy, true},
+		{"no kid, other key", mustManager(t, "k1", KeyConfig{ID: "k1", Secret: testSecret1}, KeyConfig{ID: "k2", Secret: testSecret2}), legacy, false},
+		{"tampered", old, token[:len(token)-2] + "xx", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			subject, err := tt.manager.Parse(tt.token)
+			if tt.wantOK && (err != nil || subject != "42") {
+				t.Fatalf("got %q, %v; want 42", subject, err)
+			}
+			if !tt.wantOK && err == nil {
+				t.Fatal("token was accepted")
+			}
+		})
+	}
+}
+
+func TestParseRejectsExpired(t *testing.T) {
+	m := mustManager(t, "k1", KeyConfig{ID: "k1", Secret: testSecret1})
+	token, err := m.NewJWT("42", -time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Parse(token); err == nil {
+		t.Fatal("expired token was accepted")
+	}
+}
+
+func TestGenerateNumericCode(t *testing.T) {
+	for _, n := range []int{1, 6, 8} {
+		code, err := GenerateNumericCode(n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(code) != n || strings.Trim(code, "0123456789") != "" {
+			t.Errorf("GenerateNumericCode(%d) = %q", n, code)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package utils
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA1 test key of RFC 6238, "12345678901234567890".
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestValidateTOTP(t *testing.T) {
+	tests := []struct {
+		name     string
+		secret   string
+		code     string
+		unix     int64
+		wantStep int64
+		wantOK   bool
+	}{
+		// codes of RFC 6238 appendix B truncated to six digits
+		{"rfc 59", rfc6238Secret, "287082", 59, 1, true},
+		{"rfc 1111111109", rfc6238Secret, "081804", 1111111109, 37037036, true},
+		{"rfc 1111111111", rfc6238Secret, "050471", 1111111111, 37037037, true},
+		{"rfc 1234567890", rfc6238Secret, "005924", 1234567890, 41152263, true},
+		{"rfc 2000000000", rfc6238Secret, "279037", 2000000000, 66666666, true},
+		{"lowercase secret with spaces", " gezdgnbvgy3tqojqgezdgnbvgy3tqojq ", "287082", 59, 1, true},
+		{"previous step", rfc6238Secret, "081804", 1111111109 + 30, 37037036, true},
+		{"next step", rfc6238Secret, "081804", 1111111109 - 30, 37037036, true},
+		{"two steps late", rfc6238Secret, "081804", 1111111109 + 60, 0, false},
+		{"wrong code", rfc6238Secret, "123456", 59, 0, false},
+		{"eight digits", rfc6238Secret, "94287082", 59, 0, false},
+		{"empty code", rfc6238Secret, "", 59, 0, false},
+		{"invalid secret", "not base32!", "287082", 59, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := ValidateTOTP(tt.secret, tt.code, time.Unix(tt.unix, 0))
+			if ok != tt.wantOK || step != tt.wantStep {
+				t.Fatalf("ValidateTOTP() = %d, %v; want %d, %v", step, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestGenerateTOTPSecret(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := totpEncoding.DecodeString(secret)
+	if err != nil || len(key) != 20 {
+		t.Fatalf("secret %q decodes to %d bytes, %v", secret, len(key), err)
+	}
+	now := time.Now()
+	if _, ok := ValidateTOTP(secret, totpCode(key, now.Unix()/totpPeriod), now); !ok {
+		t.Fatal("current code of a new secret was rejected")
+	}
+}
+
+func TestTOTPURI(t *testing.T) {
+	u, err := url.Parse(TOTPURI("Work Out", "trainer@example.com", rfc6238Secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Scheme != "otpauth" || u.Host != "totp" || u.Path != "/Work Out:trainer@example.com" {
+		t.Errorf("unexpected URI %s", u)
+	}
+	want := map[string]string{"secret": rfc6238Secret, "issuer": "Work Out", "algorithm": "SHA1", "digits": "6", "period": "30"}
+	for k, v := range want {
+		if got := u.Query().Get(k); got != v {
+			t.Errorf("%s = %q, want %q", k, got, v)
+		}
+	}
+}